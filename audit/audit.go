@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// 审计日志中的操作类型
+const (
+	ActionSchemaCreate  = "schema.create"
+	ActionSchemaUpdate  = "schema.update"
+	ActionSchemaDelete  = "schema.delete"
+	ActionPurge         = "purge"
+	ActionConfigUpdate  = "config.update"
+	ActionHookCreate    = "hook.create"
+	ActionHookUpdate    = "hook.update"
+	ActionHookDelete    = "hook.delete"
+	ActionRoleCreate    = "role.create"
+	ActionRoleUpdate    = "role.update"
+	ActionRoleDelete    = "role.delete"
+	ActionLogin         = "login"
+	ActionLoginFailed   = "login.failed"
+	ActionAccountLocked = "account.locked"
+)
+
+var adapter auditAdapter
+
+func init() {
+	a := config.TConfig.AuditAdapter
+	if a == "Database" {
+		adapter = newDatabaseAuditAdapter()
+	} else if a == "File" {
+		adapter = newFileAuditAdapter(config.TConfig.AuditLogFile)
+	} else if a == "Syslog" {
+		adapter = newSyslogAuditAdapter(config.TConfig.AppName)
+	} else {
+		adapter = &nullAuditAdapter{}
+	}
+}
+
+// Entry 一条审计日志
+// Actor 操作者，取值： master、readOnlyMaster、apiKey:<id>、user:<id>、anonymous
+// Changes 字段变化，格式为 {"field":{"old":...,"new":...}}
+type Entry struct {
+	Action    string
+	Actor     string
+	IP        string
+	Method    string
+	Route     string
+	ClassName string
+	TargetID  string
+	Changes   types.M
+}
+
+// Log 写入一条审计日志，写入失败时仅打印错误，不影响正常请求
+func Log(entry *Entry) {
+	if entry == nil {
+		return
+	}
+	err := adapter.log(entry.toObject(time.Now().UTC()))
+	if err != nil {
+		log.Println("audit log error:", err)
+	}
+}
+
+// Find 查询审计日志，仅 AuditAdapter=Database 时支持
+// where 支持的字段： action、actor、className、targetId、createdAt
+// options 支持 limit 与 skip
+func Find(where, options types.M) (types.S, error) {
+	results, err := adapter.find(where, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		object := utils.M(r)
+		if object == nil {
+			continue
+		}
+		// changes 以 JSON 字符串格式存储，返回时转换为对象
+		if s, ok := object["changes"].(string); ok {
+			var changes types.M
+			if json.Unmarshal([]byte(s), &changes) == nil {
+				object["changes"] = changes
+			}
+		}
+	}
+	return results, nil
+}
+
+// Diff 对比修改前后的数据，返回发生变化的字段
+func Diff(before, after types.M) types.M {
+	changes := types.M{}
+	for k, v := range after {
+		old, ok := before[k]
+		if ok && reflect.DeepEqual(old, v) {
+			continue
+		}
+		changes[k] = types.M{"old": old, "new": v}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; ok == false {
+			changes[k] = types.M{"old": v, "new": nil}
+		}
+	}
+	return changes
+}
+
+// Flatten 将对象中的第一层子对象展开，如 {"fields":{"a":1}} 转换为 {"fields.a":1} ，用于对比 schema 等嵌套数据
+func Flatten(object types.M) types.M {
+	result := types.M{}
+	for k, v := range object {
+		if m := utils.M(v); m != nil {
+			for subKey, subValue := range m {
+				result[k+"."+subKey] = subValue
+			}
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func (e *Entry) toObject(now time.Time) types.M {
+	object := types.M{
+		"action":    e.Action,
+		"actor":     e.Actor,
+		"ip":        e.IP,
+		"method":    e.Method,
+		"route":     e.Route,
+		"className": e.ClassName,
+		"targetId":  e.TargetID,
+		"createdAt": utils.TimetoString(now),
+	}
+	if len(e.Changes) > 0 {
+		changes, err := json.Marshal(e.Changes)
+		if err == nil {
+			object["changes"] = string(changes)
+		}
+	}
+	return object
+}
+
+type auditAdapter interface {
+	log(object types.M) error
+	find(where, options types.M) (types.S, error)
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_Diff(t *testing.T) {
+	var before, after, result, expect types.M
+	/************************************************************/
+	before = nil
+	after = types.M{"name": "admin"}
+	result = Diff(before, after)
+	expect = types.M{"name": types.M{"old": nil, "new": "admin"}}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	before = types.M{"name": "admin", "key": "v1", "same": 1}
+	after = types.M{"key": "v2", "same": 1}
+	result = Diff(before, after)
+	expect = types.M{
+		"name": types.M{"old": "admin", "new": nil},
+		"key":  types.M{"old": "v1", "new": "v2"},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_Flatten(t *testing.T) {
+	var object, result, expect types.M
+	/************************************************************/
+	object = types.M{
+		"className": "post",
+		"fields": types.M{
+			"title": types.M{"type": "String"},
+		},
+	}
+	result = Flatten(object)
+	expect = types.M{
+		"className":    "post",
+		"fields.title": types.M{"type": "String"},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_fileAuditAdapter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	a := newFileAuditAdapter(path)
+	entry := &Entry{
+		Action:    ActionPurge,
+		Actor:     "master",
+		IP:        "127.0.0.1",
+		Method:    "DELETE",
+		Route:     "/v1/purge/post",
+		ClassName: "post",
+		Changes:   types.M{"key": types.M{"old": "v1", "new": "v2"}},
+	}
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.log(entry.toObject(now))
+	a.log(entry.toObject(now))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Error("expect:", 2, "result:", len(lines))
+	}
+	var result types.M
+	json.Unmarshal([]byte(lines[0]), &result)
+	expect := types.M{
+		"action":    "purge",
+		"actor":     "master",
+		"ip":        "127.0.0.1",
+		"method":    "DELETE",
+		"route":     "/v1/purge/post",
+		"className": "post",
+		"targetId":  "",
+		"createdAt": "2017-01-01T00:00:00.000Z",
+		"changes":   `{"key":{"new":"v2","old":"v1"}}`,
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const auditLogCollection = "_AuditLog"
+
+// databaseAuditAdapter 将审计日志写入 _AuditLog 表
+type databaseAuditAdapter struct{}
+
+func newDatabaseAuditAdapter() *databaseAuditAdapter {
+	return &databaseAuditAdapter{}
+}
+
+func (d *databaseAuditAdapter) log(object types.M) error {
+	object["objectId"] = utils.CreateObjectID()
+	// 仅允许 Master 读取
+	object["ACL"] = types.M{}
+	return orm.TomatoDBController.Create(auditLogCollection, object, types.M{})
+}
+
+func (d *databaseAuditAdapter) find(where, options types.M) (types.S, error) {
+	findOptions := types.M{
+		"sort":  map[string]interface{}{"createdAt": -1},
+		"limit": 100,
+	}
+	if options != nil {
+		if v, ok := options["limit"]; ok {
+			findOptions["limit"] = v
+		}
+		if v, ok := options["skip"]; ok {
+			findOptions["skip"] = v
+		}
+	}
+	return orm.TomatoDBController.Find(auditLogCollection, where, findOptions)
+}
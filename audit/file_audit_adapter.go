@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// fileAuditAdapter 将审计日志以 JSON 行的格式追加到文件中
+type fileAuditAdapter struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileAuditAdapter(path string) *fileAuditAdapter {
+	return &fileAuditAdapter{path: path}
+}
+
+func (f *fileAuditAdapter) log(object types.M) error {
+	line, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *fileAuditAdapter) find(where, options types.M) (types.S, error) {
+	return nil, errs.E(errs.OperationForbidden, "Querying audit log requires AuditAdapter=Database.")
+}
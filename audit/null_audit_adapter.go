@@ -0,0 +1,16 @@
+package audit
+
+import (
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+type nullAuditAdapter struct{}
+
+func (n *nullAuditAdapter) log(object types.M) error {
+	return nil
+}
+
+func (n *nullAuditAdapter) find(where, options types.M) (types.S, error) {
+	return nil, errs.E(errs.OperationForbidden, "Audit log is disabled.")
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+	"sync"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// syslogAuditAdapter 将审计日志写入本机 syslog
+type syslogAuditAdapter struct {
+	mu  sync.Mutex
+	tag string
+	w   *syslog.Writer
+}
+
+func newSyslogAuditAdapter(tag string) *syslogAuditAdapter {
+	return &syslogAuditAdapter{tag: tag}
+}
+
+func (s *syslogAuditAdapter) log(object types.M) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, s.tag)
+		if err != nil {
+			return err
+		}
+		s.w = w
+	}
+	line, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return s.w.Info(string(line))
+}
+
+func (s *syslogAuditAdapter) find(where, options types.M) (types.S, error) {
+	return nil, errs.E(errs.OperationForbidden, "Querying audit log requires AuditAdapter=Database.")
+}
@@ -11,6 +11,15 @@ var Role *SubCache
 // User ...
 var User *SubCache
 
+// Revocation 已吊销的 JWT sessionToken 列表
+var Revocation *SubCache
+
+// RateLimit 限流令牌桶
+var RateLimit *SubCache
+
+// APIKey 受限 API Key 的权限信息，以 keyHash 为 key
+var APIKey *SubCache
+
 var adapter Adapter
 
 func init() {
@@ -30,6 +39,15 @@ func init() {
 	User = &SubCache{
 		prefix: "user",
 	}
+	Revocation = &SubCache{
+		prefix: "revocation",
+	}
+	RateLimit = &SubCache{
+		prefix: "ratelimit",
+	}
+	APIKey = &SubCache{
+		prefix: "apiKey",
+	}
 }
 
 var keySeparatorChar = ":"
@@ -91,6 +109,7 @@ type Adapter interface {
 	put(key string, value interface{}, ttl int64)
 	del(key string)
 	clear()
+	takeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool)
 }
 
 // InitCache 仅用于测试
@@ -102,4 +121,13 @@ func InitCache() {
 	User = &SubCache{
 		prefix: "user",
 	}
+	Revocation = &SubCache{
+		prefix: "revocation",
+	}
+	RateLimit = &SubCache{
+		prefix: "ratelimit",
+	}
+	APIKey = &SubCache{
+		prefix: "apiKey",
+	}
 }
@@ -73,6 +73,21 @@ func (m *inMemoryCacheAdapter) clear() {
 	m.cache = map[string]*recordCache{}
 }
 
+func (m *inMemoryCacheAdapter) takeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var state interface{}
+	if record, ok := m.cache[key]; ok && (record.expire == -1 || record.expire >= time.Now().UnixNano()) {
+		state = record.value
+	}
+	value, allowed := takeFromBucket(state, cost, capacity, rate, now)
+	m.cache[key] = &recordCache{
+		value:  value,
+		expire: ttl*10e9 + time.Now().UnixNano(),
+	}
+	return value["tokens"].(float64), allowed
+}
+
 type recordCache struct {
 	expire int64
 	value  interface{}
@@ -1,11 +1,14 @@
 package cache
 
 import (
+	"sync"
+
 	"github.com/JuShangEnergy/framework/config"
 	"github.com/JuShangEnergy/framework/dependencies/lru"
 )
 
 type lruCacheAdapter struct {
+	mu    sync.Mutex
 	cache *lru.Cache
 }
 
@@ -39,3 +42,11 @@ func (lru *lruCacheAdapter) del(key string) {
 func (lru *lruCacheAdapter) clear() {
 	lru.cache.Clear()
 }
+
+func (lru *lruCacheAdapter) takeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	value, allowed := takeFromBucket(lru.get(key), cost, capacity, rate, now)
+	lru.cache.Add(key, value)
+	return value["tokens"].(float64), allowed
+}
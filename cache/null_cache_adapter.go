@@ -20,3 +20,8 @@ func (m *nullCacheAdapter) del(key string) {
 
 func (m *nullCacheAdapter) clear() {
 }
+
+func (m *nullCacheAdapter) takeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool) {
+	value, allowed := takeFromBucket(nil, cost, capacity, rate, now)
+	return value["tokens"].(float64), allowed
+}
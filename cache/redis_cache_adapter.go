@@ -97,3 +97,40 @@ func (m *redisCacheAdapter) del(key string) {
 func (m *redisCacheAdapter) clear() {
 	m.do("FLUSHDB")
 }
+
+// takeTokenScript 在 Redis 中原子地读取并更新令牌桶，桶状态保存在 hash 中
+var takeTokenScript = redis.NewScript(1, `
+local cost = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local rate = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local tokens = capacity
+local state = redis.call("HMGET", KEYS[1], "tokens", "updatedAt")
+if state[1] and state[2] then
+	tokens = math.min(capacity, tonumber(state[1]) + (now - tonumber(state[2])) * rate)
+end
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updatedAt", tostring(now))
+redis.call("EXPIRE", KEYS[1], ARGV[5])
+return {tostring(tokens), allowed}
+`)
+
+// takeToken Redis 出错时不限流，避免缓存故障导致所有请求被拒绝
+func (m *redisCacheAdapter) takeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool) {
+	c := m.p.Get()
+	defer c.Close()
+	values, err := redis.Values(takeTokenScript.Do(c, key, cost, capacity, rate, now, ttl))
+	if err != nil || len(values) != 2 {
+		return capacity - cost, true
+	}
+	tokens, err := redis.Float64(values[0], nil)
+	if err != nil {
+		return capacity - cost, true
+	}
+	allowed, _ := redis.Int(values[1], nil)
+	return tokens, allowed == 1
+}
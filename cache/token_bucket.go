@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"math"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// TakeToken 从令牌桶中取出 cost 个令牌， capacity 为桶容量， rate 为每秒补充的令牌数， now 为当前时间，单位为秒
+// 返回取出后剩余的令牌数，以及是否取到令牌，令牌不足时不取出， ttl 为桶状态的保存时长
+// 读取与更新在缓存中原子执行，使用 Redis 时多个实例共享计数
+func (c *SubCache) TakeToken(key string, cost, capacity, rate, now float64, ttl int64) (float64, bool) {
+	cacheKey := joinKeys(config.TConfig.AppID, c.prefix, key)
+	return adapter.takeToken(cacheKey, cost, capacity, rate, now, ttl)
+}
+
+// takeFromBucket 由令牌桶的当前状态计算取出 cost 个令牌后的状态
+func takeFromBucket(state interface{}, cost, capacity, rate, now float64) (types.M, bool) {
+	tokens := capacity
+	if s := utils.M(state); s != nil {
+		t, ok1 := s["tokens"].(float64)
+		updatedAt, ok2 := s["updatedAt"].(float64)
+		if ok1 && ok2 {
+			tokens = math.Min(capacity, t+(now-updatedAt)*rate)
+		}
+	}
+	allowed := false
+	if tokens >= cost {
+		tokens -= cost
+		allowed = true
+	}
+	return types.M{"tokens": tokens, "updatedAt": now}, allowed
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_takeToken(t *testing.T) {
+	var tokens float64
+	var allowed bool
+	/*******************************************************************/
+	adapters := []Adapter{newInMemoryCacheAdapter(5), newLRUCacheAdapter(10)}
+	for _, a := range adapters {
+		tokens, allowed = a.takeToken("k", 1, 2, 0.2, 100, 10)
+		if tokens != 1 || allowed == false {
+			t.Error("expect:", 1, "result:", tokens, allowed)
+		}
+		tokens, allowed = a.takeToken("k", 1, 2, 0.2, 100, 10)
+		if tokens != 0 || allowed == false {
+			t.Error("expect:", 0, "result:", tokens, allowed)
+		}
+		tokens, allowed = a.takeToken("k", 1, 2, 0.2, 102.5, 10)
+		if tokens != 0.5 || allowed {
+			t.Error("expect:", 0.5, "result:", tokens, allowed)
+		}
+		// 5 秒后补充一个令牌
+		tokens, allowed = a.takeToken("k", 1, 2, 0.2, 105, 10)
+		if tokens != 0 || allowed == false {
+			t.Error("expect:", 0, "result:", tokens, allowed)
+		}
+	}
+}
+
+func Test_takeTokenCost(t *testing.T) {
+	var tokens float64
+	var allowed bool
+	/*******************************************************************/
+	adapters := []Adapter{newInMemoryCacheAdapter(5), newLRUCacheAdapter(10)}
+	for _, a := range adapters {
+		tokens, allowed = a.takeToken("k", 3, 5, 1, 100, 10)
+		if tokens != 2 || allowed == false {
+			t.Error("expect:", 2, "result:", tokens, allowed)
+		}
+		// 令牌不足时不取出
+		tokens, allowed = a.takeToken("k", 3, 5, 1, 100, 10)
+		if tokens != 2 || allowed {
+			t.Error("expect:", 2, "result:", tokens, allowed)
+		}
+		tokens, allowed = a.takeToken("k", 3, 5, 1, 101, 10)
+		if tokens != 0 || allowed == false {
+			t.Error("expect:", 0, "result:", tokens, allowed)
+		}
+	}
+}
+
+func Test_takeTokenConcurrent(t *testing.T) {
+	a := newInMemoryCacheAdapter(5)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	count := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, allowed := a.takeToken("k", 1, 10, 0.001, 100, 10); allowed {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 10 {
+		t.Error("expect:", 10, "result:", count)
+	}
+}
@@ -1,6 +1,10 @@
 package cloud
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/JuShangEnergy/framework/headers"
+)
 
 var restrictedClassNames = []string{"_Session"}
 
@@ -23,6 +27,16 @@ func Job(functionName string, handler JobHandler) {
 	AddJob(functionName, handler)
 }
 
+// AllowHeaders 添加额外允许的跨域请求头
+func AllowHeaders(names ...string) {
+	headers.AllowHeaders(names...)
+}
+
+// ExposeHeaders 添加允许客户端读取的响应头
+func ExposeHeaders(names ...string) {
+	headers.ExposeHeaders(names...)
+}
+
 // BeforeSave ...
 func BeforeSave(className string, handler TriggerHandler) error {
 	err := validateClassNameForTriggers(className)
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"strconv"
 	"time"
 
 	"log"
@@ -11,6 +13,7 @@ import (
 
 	"strings"
 
+	"github.com/JuShangEnergy/framework/livequery/pubsub"
 	"github.com/beego/beego"
 )
 
@@ -51,16 +54,36 @@ type Config struct {
 	TencentAppID                     string   // 腾讯云存储 AppID ，仅在 FileAdapter=Tencent 时需要配置
 	TencentSecretID                  string   // 腾讯云存储 SecretID ，仅在 FileAdapter=Tencent 时需要配置
 	TencentSecretKey                 string   // 腾讯云存储 SecretKey ，仅在 FileAdapter=Tencent 时需要配置
-	PushAdapter                      string   // 推送模块，可选：FCM, UMeng，默认为 tomato
+	PushAdapter                      string   // 推送模块，可选：FCM, UMeng, APNs, HMS, Mi, OPPO, vivo, WebPush，多个模块使用 | 隔开，如： APNs|FCM|HMS ，默认为 tomato
 	PushChannel                      string   // 推送通道
 	PushBatchSize                    int      // 批量推送的大小
 	ScheduledPush                    bool     // 是否有推送调度器
-	LiveQueryClasses                 string   // LiveQuery 支持的 classe ，多个 class 使用 | 隔开，如： classeA|classeB|classeC
-	PublisherType                    string   // 发布者类型，可选：Redis ，默认使用自带的 EventEmitter
-	PublisherURL                     string   // 发布者地址， PublisherType=Redis 时必填
-	PublisherConfig                  string   // 发布者配置信息， PublisherType=Redis 时为 Redis 密码，选填
+	PushQueueType                    string   // 推送队列类型，可选：RedisStreams、NATS、Database 或其他通过 pubsub.Register 注册的类型，默认使用进程内的 EventEmitter ，多个节点分摊推送任务时使用 RedisStreams 、 NATS 或 Database ，其中 RedisStreams 与 Database 为持久化队列， NATS 的任务最多投递一次，不支持 Redis 与 Embedded
+	PushQueueURL                     string   // 推送队列地址， PushQueueType 为 RedisStreams、NATS 时必填
+	PushQueueConfig                  string   // 推送队列配置信息， RedisStreams 与 NATS 的格式同 PublisherConfig ， group 默认为 PushChannel
+	PushWorker                       bool     // 是否在当前进程中处理推送任务，默认为 true ，设置为 false 时需要在单独的进程中调用 RunPushWorker
+	PushMaxAttempts                  int      // 推送任务的最大尝试次数，超过后移入死信存储，默认为 5
+	PushRetryInterval                int      // 推送任务首次重试的间隔，之后每次翻倍，单位为秒，默认为 10
+	PushRateLimits                   string   // 各推送模块每秒发送的消息数，格式为 APNs=500&FCM=1000 ，未配置的模块不限速，令牌桶保存在 CacheAdapter 中，使用 Redis 时各进程共享速率
+	PushQuietHours                   string   // 免打扰时段，格式为 22:00-08:00 ，按设备的 timeZone 计算，处于该时段的设备延迟到时段结束后发送，为空时不启用
+	PushDeliveryLog                  bool     // 是否在 _PushDelivery 中记录每个设备的推送结果，用于查询设备是否收到推送与统计打开率，默认为 false
+	LiveQueryClasses                 string   // LiveQuery 支持的 classe ，多个 class 使用 | 隔开，如： classeA|classeB|classeC ，也可在类定义中设置 liveQuery 开启
+	PublisherType                    string   // 发布者类型，可选：Redis、RedisStreams、NATS、Embedded 或其他通过 pubsub.Register 注册的类型，默认使用自带的 EventEmitter ， Embedded 表示 LiveQuery 与 API 运行在同一进程中
+	PublisherURL                     string   // 发布者地址， PublisherType 为 Redis、RedisStreams、NATS 时必填
+	PublisherConfig                  string   // 发布者配置信息， PublisherType=Redis 时为 Redis 密码，选填； RedisStreams 格式为 password=abc&group=g&consumer=c&maxLen=10000 ； NATS 格式为 user=abc&pass=123&token=xyz&group=g
+	LiveQuerySendQueueSize           int      // LiveQuery 每个客户端发送队列的长度，默认为 100
+	LiveQueryWriteTimeout            int      // LiveQuery 单条消息的写入超时时间，单位为秒，默认为 10
+	LiveQueryOverflowPolicy          string   // LiveQuery 发送队列已满时的处理方式，可选：dropOldest、disconnect ，默认为 dropOldest
+	LiveQueryPingInterval            int      // LiveQuery 发送 ping 帧的间隔，单位为秒，为 0 时不发送，默认为 30
+	LiveQueryIdleTimeout             int      // LiveQuery 连接空闲超时时间，单位为秒，超时未收到客户端数据（包括 pong 帧）时断开连接，为 0 时不检测，默认为 90
+	LiveQuerySessionRevokedPolicy    string   // LiveQuery 中 sessionToken 被删除或过期后的处理方式，可选：downgrade、close ， downgrade 表示按未登录用户校验权限， close 表示关闭相关订阅，默认为 downgrade
+	LiveQuerySSEPattern              string   // LiveQuery SSE 接口路径，为空时不开启 SSE ，默认为 /live
+	LiveQuerySSEReplaySize           int      // LiveQuery SSE 重连时可补发的最近事件数，默认为 100
 	SessionLength                    int      // Session 有效期，单位为秒，取值大于 0 ，默认为 31536000 秒，即 1 年
 	RevokeSessionOnPasswordReset     bool     // 密码重置后是否清除 Session ，默认为 true 清除 Session
+	SessionTokenType                 string   // Session Token 类型，可选：Random、JWT，默认为 Random 随机字符串，使用 JWT 时吊销列表保存在缓存中， CacheAdapter 必须为 Redis
+	JWTSigningKeys                   []string // JWT 签名密钥，格式为 kid:secret ，多个密钥使用 | 隔开，如： k2:secret2|k1:secret1 ，SessionTokenType=JWT 时必填
+	JWTActiveKeyID                   string   // 当前用于签发 JWT 的密钥 kid ，默认为 JWTSigningKeys 中的第一个，其余密钥仅用于验签
 	PreventLoginWithUnverifiedEmail  bool     // 是否阻止未验证邮箱的用户登录，默认为 false 不阻止
 	CacheAdapter                     string   // 缓存模块，可选： LRU、InMemory、Redis、Null， 默认为 LRU 使用内存做缓存模块
 	RedisAddress                     string   // Redis 地址， CacheAdapter=Redis 时必填
@@ -80,6 +103,16 @@ type Config struct {
 	MaxPasswordHistory               int      // 最大密码历史个数，修改的密码不能与密码历史重复，取值范围： 0-20 ，默认为 0 表示不设置密码历史
 	UserSensitiveFields              []string // 用户敏感字段，按需删除，多个字段使用 | 删除，如： email|password
 	AnalyticsAdapter                 string   // 分析模块，可选：InfluxDB，默认使用空的分析模块
+	AuditAdapter                     string   // 审计日志模块，可选：Database、File、Syslog，默认为空不记录审计日志
+	AuditLogFile                     string   // 审计日志文件路径，仅在 AuditAdapter=File 时需要配置
+	RateLimitRules                   string   // 限流规则，JSON 数组格式，如：[{"path":"/v1/login","method":"POST","key":"ip","limit":10,"period":60}] ，默认为空不限流
+	CORSAllowOrigins                 []string // 允许跨域访问的来源，多个来源使用 | 隔开，支持通配子域名，如： https://app.example.com|https://*.example.com ，默认为 * 允许所有来源
+	CORSAllowHeaders                 []string // 额外允许的跨域请求头，多个请求头使用 | 隔开，默认为空
+	CORSExposeHeaders                []string // 允许客户端读取的响应头，多个响应头使用 | 隔开，默认为空
+	CORSMaxAge                       int      // 预检请求结果的缓存时间，单位为秒，取值大于等于 0 ，默认为 0 表示不设置
+	HSTSMaxAge                       int      // Strict-Transport-Security 的有效期，单位为秒，取值大于等于 0 ，默认为 0 表示不发送该响应头
+	ContentTypeNosniff               bool     // 是否发送 X-Content-Type-Options: nosniff 响应头，默认为 false 不发送
+	FrameOptions                     string   // publichtml 页面的 X-Frame-Options 响应头，可选：DENY、SAMEORIGIN ，默认为空不发送
 	InfluxDBURL                      string   // InfluxDB 地址，仅在 AnalyticsAdapter=InfluxDB 时需要配置
 	InfluxDBUsername                 string   // InfluxDB 用户名，仅在 AnalyticsAdapter=InfluxDB 时需要配置
 	InfluxDBPassword                 string   // InfluxDB 密码，仅在 AnalyticsAdapter=InfluxDB 时需要配置
@@ -97,6 +130,23 @@ type Config struct {
 	UMengIOSAppKey                   string   // 友盟推送 ios app key
 	UMengAndroidAppMasterSecret      string   // 友盟推送 android app master secret
 	UMengIOSAppMasterSecret          string   // 友盟推送 ios app master secret
+	APNSKeyFile                      string   // APNs 推送 .p8 私钥文件路径，仅在 PushAdapter 包含 APNs 时需要配置
+	APNSKeyID                        string   // APNs 推送私钥的 Key ID
+	APNSTeamID                       string   // APNs 推送的 Team ID
+	APNSTopic                        string   // APNs 推送的 topic ，即 App 的 Bundle ID ，设备信息中有 appIdentifier 时优先使用 appIdentifier
+	APNSProduction                   bool     // APNs 是否使用生产环境，默认使用 sandbox 环境
+	HMSAppID                         string   // 华为推送 App ID ，仅在 PushAdapter 包含 HMS 时需要配置，设备的 pushType 为 hms
+	HMSAppSecret                     string   // 华为推送 App Secret
+	MiAppSecret                      string   // 小米推送 App Secret ，仅在 PushAdapter 包含 Mi 时需要配置，设备的 pushType 为 mi
+	MiPackageName                    string   // 小米推送的应用包名
+	OPPOAppKey                       string   // OPPO 推送 App Key ，仅在 PushAdapter 包含 OPPO 时需要配置，设备的 pushType 为 oppo
+	OPPOMasterSecret                 string   // OPPO 推送 Master Secret
+	VivoAppID                        string   // vivo 推送 App ID ，仅在 PushAdapter 包含 vivo 时需要配置，设备的 pushType 为 vivo
+	VivoAppKey                       string   // vivo 推送 App Key
+	VivoAppSecret                    string   // vivo 推送 App Secret
+	WebPushPublicKey                 string   // Web Push 的 VAPID 公钥， base64url 编码，仅在 PushAdapter 包含 WebPush 时需要配置，设备的 deviceType 为 web
+	WebPushPrivateKey                string   // Web Push 的 VAPID 私钥， base64url 编码
+	WebPushSubject                   string   // Web Push 的联系方式，如 mailto:admin@example.com
 	HDFSNameNode                     string   // HDFS Name Node 地址
 	HDFSUser                         string   // HDFS 用户名
 	HDFSRoot                         string   // HDFS 存储根目录
@@ -142,9 +192,24 @@ func parseConfig() {
 	TConfig.PublisherType = beego.AppConfig.String("PublisherType")
 	TConfig.PublisherURL = beego.AppConfig.String("PublisherURL")
 	TConfig.PublisherConfig = beego.AppConfig.String("PublisherConfig")
+	TConfig.LiveQuerySendQueueSize = beego.AppConfig.DefaultInt("LiveQuerySendQueueSize", 100)
+	TConfig.LiveQueryWriteTimeout = beego.AppConfig.DefaultInt("LiveQueryWriteTimeout", 10)
+	TConfig.LiveQueryOverflowPolicy = beego.AppConfig.DefaultString("LiveQueryOverflowPolicy", "dropOldest")
+	TConfig.LiveQueryPingInterval = beego.AppConfig.DefaultInt("LiveQueryPingInterval", 30)
+	TConfig.LiveQueryIdleTimeout = beego.AppConfig.DefaultInt("LiveQueryIdleTimeout", 90)
+	TConfig.LiveQuerySessionRevokedPolicy = beego.AppConfig.DefaultString("LiveQuerySessionRevokedPolicy", "downgrade")
+	TConfig.LiveQuerySSEPattern = beego.AppConfig.DefaultString("LiveQuerySSEPattern", "/live")
+	TConfig.LiveQuerySSEReplaySize = beego.AppConfig.DefaultInt("LiveQuerySSEReplaySize", 100)
 
 	TConfig.SessionLength = beego.AppConfig.DefaultInt("SessionLength", 31536000)
 	TConfig.RevokeSessionOnPasswordReset = beego.AppConfig.DefaultBool("RevokeSessionOnPasswordReset", true)
+	TConfig.SessionTokenType = beego.AppConfig.DefaultString("SessionTokenType", "Random")
+	// JWTSigningKeys 签名密钥列表，格式： kid1:secret1|kid2:secret2
+	TConfig.JWTSigningKeys = []string{}
+	if keys := beego.AppConfig.String("JWTSigningKeys"); keys != "" {
+		TConfig.JWTSigningKeys = strings.Split(keys, "|")
+	}
+	TConfig.JWTActiveKeyID = beego.AppConfig.String("JWTActiveKeyID")
 	TConfig.PreventLoginWithUnverifiedEmail = beego.AppConfig.DefaultBool("PreventLoginWithUnverifiedEmail", false)
 	TConfig.EmailVerifyTokenValidityDuration = beego.AppConfig.DefaultInt("EmailVerifyTokenValidityDuration", 0)
 	TConfig.SchemaCacheTTL = beego.AppConfig.DefaultInt("SchemaCacheTTL", 5)
@@ -194,6 +259,16 @@ func parseConfig() {
 	}
 
 	TConfig.AnalyticsAdapter = beego.AppConfig.String("AnalyticsAdapter")
+	TConfig.AuditAdapter = beego.AppConfig.String("AuditAdapter")
+	TConfig.AuditLogFile = beego.AppConfig.String("AuditLogFile")
+	TConfig.RateLimitRules = beego.AppConfig.String("RateLimitRules")
+	TConfig.CORSAllowOrigins = splitList(beego.AppConfig.DefaultString("CORSAllowOrigins", "*"))
+	TConfig.CORSAllowHeaders = splitList(beego.AppConfig.String("CORSAllowHeaders"))
+	TConfig.CORSExposeHeaders = splitList(beego.AppConfig.String("CORSExposeHeaders"))
+	TConfig.CORSMaxAge = beego.AppConfig.DefaultInt("CORSMaxAge", 0)
+	TConfig.HSTSMaxAge = beego.AppConfig.DefaultInt("HSTSMaxAge", 0)
+	TConfig.ContentTypeNosniff = beego.AppConfig.DefaultBool("ContentTypeNosniff", false)
+	TConfig.FrameOptions = strings.ToUpper(beego.AppConfig.String("FrameOptions"))
 	TConfig.InfluxDBURL = beego.AppConfig.String("InfluxDBURL")
 	TConfig.InfluxDBUsername = beego.AppConfig.String("InfluxDBUsername")
 	TConfig.InfluxDBPassword = beego.AppConfig.String("InfluxDBPassword")
@@ -208,6 +283,15 @@ func parseConfig() {
 	TConfig.PushChannel = beego.AppConfig.String("PushChannel")
 	TConfig.PushBatchSize = beego.AppConfig.DefaultInt("PushBatchSize", 0)
 	TConfig.ScheduledPush = beego.AppConfig.DefaultBool("ScheduledPush", false)
+	TConfig.PushQueueType = beego.AppConfig.String("PushQueueType")
+	TConfig.PushQueueURL = beego.AppConfig.String("PushQueueURL")
+	TConfig.PushQueueConfig = beego.AppConfig.String("PushQueueConfig")
+	TConfig.PushWorker = beego.AppConfig.DefaultBool("PushWorker", true)
+	TConfig.PushMaxAttempts = beego.AppConfig.DefaultInt("PushMaxAttempts", 5)
+	TConfig.PushRetryInterval = beego.AppConfig.DefaultInt("PushRetryInterval", 10)
+	TConfig.PushRateLimits = beego.AppConfig.String("PushRateLimits")
+	TConfig.PushQuietHours = beego.AppConfig.String("PushQuietHours")
+	TConfig.PushDeliveryLog = beego.AppConfig.DefaultBool("PushDeliveryLog", false)
 
 	TConfig.FCMServerKey = beego.AppConfig.String("FCMServerKey")
 	TConfig.HDFSNameNode = beego.AppConfig.String("HDFSNameNode")
@@ -219,6 +303,25 @@ func parseConfig() {
 	TConfig.UMengIOSAppKey = beego.AppConfig.String("UMengIOSAppKey")
 	TConfig.UMengIOSAppMasterSecret = beego.AppConfig.String("UMengIOSAppMasterSecret")
 
+	TConfig.APNSKeyFile = beego.AppConfig.String("APNSKeyFile")
+	TConfig.APNSKeyID = beego.AppConfig.String("APNSKeyID")
+	TConfig.APNSTeamID = beego.AppConfig.String("APNSTeamID")
+	TConfig.APNSTopic = beego.AppConfig.String("APNSTopic")
+	TConfig.APNSProduction = beego.AppConfig.DefaultBool("APNSProduction", false)
+
+	TConfig.HMSAppID = beego.AppConfig.String("HMSAppID")
+	TConfig.HMSAppSecret = beego.AppConfig.String("HMSAppSecret")
+	TConfig.MiAppSecret = beego.AppConfig.String("MiAppSecret")
+	TConfig.MiPackageName = beego.AppConfig.String("MiPackageName")
+	TConfig.OPPOAppKey = beego.AppConfig.String("OPPOAppKey")
+	TConfig.OPPOMasterSecret = beego.AppConfig.String("OPPOMasterSecret")
+	TConfig.VivoAppID = beego.AppConfig.String("VivoAppID")
+	TConfig.VivoAppKey = beego.AppConfig.String("VivoAppKey")
+	TConfig.VivoAppSecret = beego.AppConfig.String("VivoAppSecret")
+	TConfig.WebPushPublicKey = beego.AppConfig.String("WebPushPublicKey")
+	TConfig.WebPushPrivateKey = beego.AppConfig.String("WebPushPrivateKey")
+	TConfig.WebPushSubject = beego.AppConfig.String("WebPushSubject")
+
 	if TConfig.DatabaseType == "PostgreSQL" {
 		TConfig.PgMaxConnections = beego.AppConfig.DefaultInt("PgMaxConnections", 100)
 	}
@@ -236,6 +339,8 @@ func Validate() {
 	validatePasswordPolicy()
 	validateCacheConfiguration()
 	validateAnalyticsConfiguration()
+	validateAuditConfiguration()
+	validateSecurityHeadersConfiguration()
 	validateMasterKeyIps()
 }
 
@@ -293,7 +398,91 @@ func validateFileConfiguration() {
 
 // validatePushConfiguration 校验推送相关参数
 func validatePushConfiguration() {
-	// TODO
+	for _, adapter := range strings.Split(TConfig.PushAdapter, "|") {
+		switch strings.TrimSpace(adapter) {
+		case "APNs":
+			if TConfig.APNSKeyFile == "" {
+				log.Fatalln("APNSKeyFile is required")
+			}
+			if TConfig.APNSKeyID == "" {
+				log.Fatalln("APNSKeyID is required")
+			}
+			if TConfig.APNSTeamID == "" {
+				log.Fatalln("APNSTeamID is required")
+			}
+			if TConfig.APNSTopic == "" {
+				log.Fatalln("APNSTopic is required")
+			}
+		case "HMS":
+			if TConfig.HMSAppID == "" || TConfig.HMSAppSecret == "" {
+				log.Fatalln("HMSAppID and HMSAppSecret are required")
+			}
+		case "Mi":
+			if TConfig.MiAppSecret == "" || TConfig.MiPackageName == "" {
+				log.Fatalln("MiAppSecret and MiPackageName are required")
+			}
+		case "OPPO":
+			if TConfig.OPPOAppKey == "" || TConfig.OPPOMasterSecret == "" {
+				log.Fatalln("OPPOAppKey and OPPOMasterSecret are required")
+			}
+		case "vivo":
+			if TConfig.VivoAppID == "" || TConfig.VivoAppKey == "" || TConfig.VivoAppSecret == "" {
+				log.Fatalln("VivoAppID, VivoAppKey and VivoAppSecret are required")
+			}
+		case "WebPush":
+			if TConfig.WebPushPublicKey == "" || TConfig.WebPushPrivateKey == "" || TConfig.WebPushSubject == "" {
+				log.Fatalln("WebPushPublicKey, WebPushPrivateKey and WebPushSubject are required")
+			}
+		}
+	}
+	switch TConfig.PushQueueType {
+	case "", "EventEmitter": // 进程内的队列，默认为 EventEmitter
+		if TConfig.PushWorker == false {
+			log.Fatalln("PushWorker can not be disabled when PushQueueType is " + TConfig.PushQueueType)
+		}
+	case "Database":
+	case "Redis":
+		// Redis 发布订阅会把任务投递给每个节点，没有节点订阅时任务直接丢失，不能作为任务队列
+		log.Fatalln("PushQueueType Redis is not supported, use RedisStreams instead")
+	case "Embedded":
+		// Embedded 通道已满时会丢弃消息，不能作为任务队列
+		log.Fatalln("PushQueueType Embedded is not supported, use EventEmitter instead")
+	default:
+		if pubsub.Registered(TConfig.PushQueueType) == false {
+			log.Fatalln("Unsupported PushQueueType")
+		}
+		if (TConfig.PushQueueType == "RedisStreams" || TConfig.PushQueueType == "NATS") && TConfig.PushQueueURL == "" {
+			log.Fatalln(TConfig.PushQueueType + " PushQueueURL is required")
+		}
+	}
+	if TConfig.PushMaxAttempts < 1 {
+		log.Fatalln("PushMaxAttempts should be greater than 0")
+	}
+	if TConfig.PushRetryInterval < 1 {
+		log.Fatalln("PushRetryInterval should be greater than 0")
+	}
+	if TConfig.PushRateLimits != "" {
+		values, err := url.ParseQuery(TConfig.PushRateLimits)
+		if err != nil {
+			log.Fatalln("PushRateLimits format should be APNs=500&FCM=1000")
+		}
+		for name := range values {
+			if rate, err := strconv.ParseFloat(values.Get(name), 64); err != nil || rate <= 0 {
+				log.Fatalln("PushRateLimits " + name + " should be greater than 0")
+			}
+		}
+	}
+	if TConfig.PushQuietHours != "" {
+		pattern := regexp.MustCompile(`^([01]?\d|2[0-3]):[0-5]\d-([01]?\d|2[0-3]):[0-5]\d$`)
+		if pattern.MatchString(TConfig.PushQuietHours) == false {
+			log.Fatalln("PushQuietHours format should be 22:00-08:00")
+		}
+		var startHour, startMinute, endHour, endMinute int
+		fmt.Sscanf(TConfig.PushQuietHours, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+		if startHour == endHour && startMinute == endMinute {
+			log.Fatalln("PushQuietHours start and end should be different")
+		}
+	}
 }
 
 // validateMailConfiguration 校验发送邮箱相关参数
@@ -333,22 +522,63 @@ func validateMasterKeyIps() {
 // validateLiveQueryConfiguration 校验 LiveQuery 相关参数
 func validateLiveQueryConfiguration() {
 	t := TConfig.PublisherType
-	switch t {
-	case "": // 默认为 EventEmitter
-	case "Redis":
-		if TConfig.PublisherURL == "" {
-			log.Fatalln("Redis PublisherURL is required")
-		}
-	default:
+	if t != "" && pubsub.Registered(t) == false {
 		log.Fatalln("Unsupported LiveQuery PublisherType")
 	}
+	if (t == "Redis" || t == "RedisStreams" || t == "NATS") && TConfig.PublisherURL == "" {
+		log.Fatalln(t + " PublisherURL is required")
+	}
+	if TConfig.LiveQuerySendQueueSize <= 0 {
+		log.Fatalln("LiveQuerySendQueueSize should be greater than 0")
+	}
+	if TConfig.LiveQueryWriteTimeout <= 0 {
+		log.Fatalln("LiveQueryWriteTimeout should be greater than 0")
+	}
+	if TConfig.LiveQueryOverflowPolicy != "dropOldest" && TConfig.LiveQueryOverflowPolicy != "disconnect" {
+		log.Fatalln("Unsupported LiveQueryOverflowPolicy")
+	}
+	if TConfig.LiveQueryPingInterval < 0 || TConfig.LiveQueryIdleTimeout < 0 {
+		log.Fatalln("LiveQueryPingInterval and LiveQueryIdleTimeout should be greater than or equal to 0")
+	}
+	if TConfig.LiveQuerySessionRevokedPolicy != "downgrade" && TConfig.LiveQuerySessionRevokedPolicy != "close" {
+		log.Fatalln("Unsupported LiveQuerySessionRevokedPolicy")
+	}
+	if TConfig.LiveQuerySSEPattern != "" && strings.HasPrefix(TConfig.LiveQuerySSEPattern, "/") == false {
+		log.Fatalln("LiveQuerySSEPattern should start with /")
+	}
+	if TConfig.LiveQuerySSEReplaySize < 0 {
+		log.Fatalln("LiveQuerySSEReplaySize should be greater than or equal to 0")
+	}
+	// 空闲超时时间需要大于 ping 间隔，否则客户端来不及回复 pong 帧
+	if TConfig.LiveQueryIdleTimeout > 0 && TConfig.LiveQueryPingInterval > 0 && TConfig.LiveQueryIdleTimeout <= TConfig.LiveQueryPingInterval {
+		log.Fatalln("LiveQueryIdleTimeout should be greater than LiveQueryPingInterval")
+	}
 }
 
-// validateSessionConfiguration 校验 Session 有效期
+// validateSessionConfiguration 校验 Session 有效期与 Token 类型
 func validateSessionConfiguration() {
 	if TConfig.SessionLength <= 0 {
 		log.Fatalln("Session length must be a value greater than 0")
 	}
+	switch TConfig.SessionTokenType {
+	case "", "Random":
+	case "JWT":
+		keys := JWTKeys()
+		if len(keys) == 0 || len(keys) != len(TConfig.JWTSigningKeys) {
+			log.Fatalln("JWTSigningKeys should be in the format kid1:secret1|kid2:secret2")
+		}
+		// 吊销列表需要按有效期保存，且在各节点间共享、重启后不丢失，否则已吊销的 JWT 会重新生效
+		if TConfig.CacheAdapter != "Redis" {
+			log.Fatalln("JWT session token revocation requires CacheAdapter Redis")
+		}
+		if TConfig.JWTActiveKeyID != "" {
+			if _, ok := keys[TConfig.JWTActiveKeyID]; ok == false {
+				log.Fatalln("JWTActiveKeyID must be one of JWTSigningKeys")
+			}
+		}
+	default:
+		log.Fatalln("Unsupported SessionTokenType")
+	}
 }
 
 // validateAccountLockoutPolicy 校验账户锁定规则
@@ -431,6 +661,43 @@ func validateAnalyticsConfiguration() {
 	}
 }
 
+// validateAuditConfiguration 校验审计日志相关参数
+func validateAuditConfiguration() {
+	adapter := TConfig.AuditAdapter
+	switch adapter {
+	case "", "Database", "Syslog":
+	case "File":
+		if TConfig.AuditLogFile == "" {
+			log.Fatalln("AuditLogFile is required")
+		}
+	default:
+		log.Fatalln("Unsupported AuditAdapter")
+	}
+}
+
+// validateSecurityHeadersConfiguration 校验跨域与安全响应头相关参数
+func validateSecurityHeadersConfiguration() {
+	for _, origin := range TConfig.CORSAllowOrigins {
+		if origin != "*" && strings.Contains(origin, "://") == false {
+			log.Fatalln("CORSAllowOrigins should be * or contain the scheme, such as https://*.example.com")
+		}
+		if strings.Count(origin, "*") > 1 {
+			log.Fatalln("CORSAllowOrigins supports only one wildcard in each origin")
+		}
+	}
+	if TConfig.CORSMaxAge < 0 {
+		log.Fatalln("CORSMaxAge should be greater than or equal to 0")
+	}
+	if TConfig.HSTSMaxAge < 0 {
+		log.Fatalln("HSTSMaxAge should be greater than or equal to 0")
+	}
+	switch TConfig.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		log.Fatalln("Unsupported FrameOptions")
+	}
+}
+
 // GenerateSessionExpiresAt 获取 Session 过期时间
 func GenerateSessionExpiresAt() time.Time {
 	expiresAt := time.Now().UTC()
@@ -438,6 +705,29 @@ func GenerateSessionExpiresAt() time.Time {
 	return expiresAt
 }
 
+// JWTKeys 解析 JWTSigningKeys ，返回 kid 与密钥的对应关系
+func JWTKeys() map[string]string {
+	keys := map[string]string{}
+	for _, pair := range TConfig.JWTSigningKeys {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			continue
+		}
+		keys[kv[0]] = kv[1]
+	}
+	return keys
+}
+
+// JWTActiveKey 返回当前用于签发 JWT 的 kid 与密钥
+func JWTActiveKey() (string, string) {
+	keys := JWTKeys()
+	kid := TConfig.JWTActiveKeyID
+	if kid == "" && len(TConfig.JWTSigningKeys) > 0 {
+		kid = strings.SplitN(TConfig.JWTSigningKeys[0], ":", 2)[0]
+	}
+	return kid, keys[kid]
+}
+
 // GenerateEmailVerifyTokenExpiresAt 获取 Email 验证 Token 过期时间
 func GenerateEmailVerifyTokenExpiresAt() time.Time {
 	if TConfig.VerifyUserEmails == false || TConfig.EmailVerifyTokenValidityDuration <= 0 {
@@ -528,3 +818,14 @@ func ParseFrameURL() string {
 func VerifyEmailURL() string {
 	return TConfig.ServerURL + `/apps/verify_email`
 }
+
+// splitList 拆分使用 | 隔开的配置项，忽略空值
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"log"
+
 	"github.com/JuShangEnergy/framework/analytics"
+	"github.com/JuShangEnergy/framework/push"
 	"github.com/JuShangEnergy/framework/types"
 	"github.com/JuShangEnergy/framework/utils"
 )
@@ -12,6 +15,7 @@ type AnalyticsController struct {
 }
 
 // AppOpened ...
+// 带有 push_hash 时记录设备通过推送打开应用
 // @router /AppOpened [post]
 func (a *AnalyticsController) AppOpened() {
 	if a.JSONBody == nil {
@@ -19,6 +23,10 @@ func (a *AnalyticsController) AppOpened() {
 		a.ServeJSON()
 		return
 	}
+	// 记录推送打开失败不影响事件统计
+	if err := push.TrackAppOpened(a.JSONBody, a.Info.InstallationID); err != nil {
+		log.Println("track app opened failed:", err)
+	}
 	a.addTags(a.JSONBody)
 	response := analytics.AppOpened(a.JSONBody)
 	a.Data["json"] = response
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/rest"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// APIKeysController 处理 /apiKeys 接口的请求，管理受限的 API Key
+type APIKeysController struct {
+	ClassesController
+}
+
+// Prepare 访问 /apiKeys 接口需要 master key
+func (a *APIKeysController) Prepare() {
+	a.ClassesController.Prepare()
+	if a.Ctx.ResponseWriter.Started == false {
+		a.EnforceMasterKeyAccess()
+	}
+}
+
+// HandleFind 处理查找 API Key 请求
+// @router / [get]
+func (a *APIKeysController) HandleFind() {
+	a.ClassName = "_ApiKey"
+	a.ClassesController.HandleFind()
+}
+
+// HandleGet 处理获取指定 API Key 请求
+// @router /:objectId [get]
+func (a *APIKeysController) HandleGet() {
+	a.ClassName = "_ApiKey"
+	a.ObjectID = a.Ctx.Input.Param(":objectId")
+	a.ClassesController.HandleGet()
+}
+
+// HandleCreate 处理创建 API Key 请求，生成的 key 仅在创建时返回一次
+// 请求数据格式如下：
+//
+//	{
+//		"name":"billing-export",
+//		"classes":["Order","Invoice"],
+//		"operations":["find","get"],
+//		"cidrs":["10.0.0.0/8"],
+//		"expiresAt":{"__type":"Date","iso":"2027-01-01T00:00:00.000Z"}
+//	}
+//
+// @router / [post]
+func (a *APIKeysController) HandleCreate() {
+	if a.JSONBody == nil {
+		a.HandleError(errs.E(errs.InvalidJSON, "request body is empty"), 0)
+		return
+	}
+	delete(a.JSONBody, "key")
+	delete(a.JSONBody, "lastUsedAt")
+	err := rest.ValidateAPIKey(a.JSONBody)
+	if err != nil {
+		a.HandleError(err, 0)
+		return
+	}
+	key, keyHash := rest.GenerateAPIKey()
+	a.JSONBody["keyHash"] = keyHash
+
+	result, err := rest.Create(a.Auth, "_ApiKey", a.JSONBody, a.Info.ClientSDK)
+	if err != nil {
+		a.HandleError(err, 0)
+		return
+	}
+	response := utils.M(result["response"])
+	if response != nil {
+		response["key"] = key
+	}
+
+	a.Data["json"] = response
+	a.Ctx.Output.SetStatus(201)
+	a.ServeJSON()
+}
+
+// HandleUpdate 处理更新指定 API Key 请求， key 本身不可修改
+// @router /:objectId [put]
+func (a *APIKeysController) HandleUpdate() {
+	if a.JSONBody == nil {
+		a.HandleError(errs.E(errs.InvalidJSON, "request body is empty"), 0)
+		return
+	}
+	delete(a.JSONBody, "key")
+	delete(a.JSONBody, "keyHash")
+	delete(a.JSONBody, "lastUsedAt")
+	err := rest.ValidateAPIKey(a.JSONBody)
+	if err != nil {
+		a.HandleError(err, 0)
+		return
+	}
+	a.ClassName = "_ApiKey"
+	a.ObjectID = a.Ctx.Input.Param(":objectId")
+	a.ClassesController.HandleUpdate()
+}
+
+// HandleDelete 处理删除指定 API Key 请求
+// @router /:objectId [delete]
+func (a *APIKeysController) HandleDelete() {
+	a.ClassName = "_ApiKey"
+	a.ObjectID = a.Ctx.Input.Param(":objectId")
+	a.ClassesController.HandleDelete()
+}
@@ -1,5 +1,10 @@
 package controllers
 
+import (
+	"github.com/JuShangEnergy/framework/push"
+	"github.com/JuShangEnergy/framework/types"
+)
+
 // AudiencesController 处理 /push_audiences 接口的请求
 type AudiencesController struct {
 	ClassesController
@@ -41,3 +46,23 @@ func (s *AudiencesController) HandleDelete() {
 	s.ClassName = "_Audience"
 	s.ClassesController.HandleDelete()
 }
+
+// HandleCount 预览推送受众能推送到的设备数，返回数据格式为 {"count":100}
+// @router /:objectId/count [get]
+func (s *AudiencesController) HandleCount() {
+	if s.EnforceMasterKeyAccess() == false {
+		return
+	}
+	where, err := push.AudienceWhere(s.Ctx.Input.Param(":objectId"))
+	if err != nil {
+		s.HandleError(err, 0)
+		return
+	}
+	count, err := push.CountInstallations(s.Auth, where)
+	if err != nil {
+		s.HandleError(err, 0)
+		return
+	}
+	s.Data["json"] = types.M{"count": count}
+	s.ServeJSON()
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/JuShangEnergy/framework/audit"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// AuditLogsController 处理 /auditLogs 接口的请求
+type AuditLogsController struct {
+	ClassesController
+}
+
+// Prepare 访问 /auditLogs 接口需要 master key
+func (a *AuditLogsController) Prepare() {
+	a.ClassesController.Prepare()
+	if a.Ctx.ResponseWriter.Started == false {
+		a.EnforceMasterKeyAccess()
+	}
+}
+
+// HandleFind 查询审计日志，按时间倒序返回
+// 支持的查询参数： action、actor、className、targetId、from、to、limit、skip
+// from 与 to 为 ISO 格式的时间，如： 2017-01-01T00:00:00.000Z
+// @router / [get]
+func (a *AuditLogsController) HandleFind() {
+	where := types.M{}
+	for _, key := range []string{"action", "actor", "className", "targetId"} {
+		if v := a.Query[key]; v != "" {
+			where[key] = v
+		}
+	}
+	createdAt := types.M{}
+	if from := a.Query["from"]; from != "" {
+		createdAt["$gte"] = types.M{"__type": "Date", "iso": from}
+	}
+	if to := a.Query["to"]; to != "" {
+		createdAt["$lte"] = types.M{"__type": "Date", "iso": to}
+	}
+	if len(createdAt) > 0 {
+		where["createdAt"] = createdAt
+	}
+
+	options := types.M{}
+	for _, key := range []string{"limit", "skip"} {
+		if v := a.Query[key]; v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				a.HandleError(errs.E(errs.InvalidQuery, "Invalid parameter for query: "+key), 0)
+				return
+			}
+			options[key] = i
+		}
+	}
+
+	results, err := audit.Find(where, options)
+	if err != nil {
+		a.HandleError(err, 0)
+		return
+	}
+	if results == nil {
+		results = types.S{}
+	}
+	a.Data["json"] = types.M{"results": results}
+	a.ServeJSON()
+}
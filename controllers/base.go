@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/client"
 	"github.com/JuShangEnergy/framework/config"
 	"github.com/JuShangEnergy/framework/errs"
@@ -43,6 +44,7 @@ func (b *BaseController) Prepare() {
 	info.JavaScriptKey = b.Ctx.Input.Header("X-Parse-Javascript-Key")
 	info.DotNetKey = b.Ctx.Input.Header("X-Parse-Windows-Key")
 	info.RestAPIKey = b.Ctx.Input.Header("X-Parse-REST-API-Key")
+	info.APIKey = b.Ctx.Input.Header("X-Parse-Api-Key")
 	info.SessionToken = b.Ctx.Input.Header("X-Parse-Session-Token")
 	info.InstallationID = b.Ctx.Input.Header("X-Parse-Installation-Id")
 	info.ClientVersion = b.Ctx.Input.Header("X-Parse-Client-Version")
@@ -152,9 +154,10 @@ func (b *BaseController) Prepare() {
 		info.ClientSDK = client.FromString(info.ClientVersion)
 	}
 
+	ip := b.Ctx.Input.IP()
+	info.IP = ip
 	b.Info = info
 
-	ip := b.Ctx.Input.IP()
 	if info.MasterKey != "" && config.TConfig.MasterKey != "" && len(config.TConfig.MasterKeyIps) != 0 {
 		// 判断请求的ip是否在masterKeyIps中
 		ipInMasterKeyIps := false
@@ -184,6 +187,17 @@ func (b *BaseController) Prepare() {
 		return
 	}
 
+	// 使用受限的 API Key 访问
+	if info.APIKey != "" {
+		auth, err := rest.GetAuthForAPIKey(info.APIKey, ip, info.InstallationID, info)
+		if err != nil {
+			b.InvalidRequest()
+			return
+		}
+		b.Auth = auth
+		return
+	}
+
 	var allow = false
 	if (len(info.ClientKey) > 0 && info.ClientKey == config.TConfig.ClientKey) ||
 		(len(info.JavaScriptKey) > 0 && info.JavaScriptKey == config.TConfig.JavaScriptKey) ||
@@ -315,3 +329,42 @@ func (b *BaseController) EnforceMasterKeyAccess() bool {
 	}
 	return true
 }
+
+// EnforceAPIKeyOperation 使用 API Key 访问时，校验 API Key 是否具有指定操作的权限
+// 返回 true 表示允许访问
+func (b *BaseController) EnforceAPIKeyOperation(operation string) bool {
+	if b.Auth == nil || b.Auth.APIKey == nil || b.Auth.APIKey.Allow(operation, "") {
+		return true
+	}
+	b.Ctx.Output.SetStatus(403)
+	b.Data["json"] = types.M{"error": "unauthorized: api key isn't allowed to perform " + operation}
+	b.ServeJSON()
+	return false
+}
+
+// Audit 记录当前请求的审计日志
+func (b *BaseController) Audit(action, className, targetID string, changes types.M) {
+	b.AuditAs(b.auditActor(), action, className, targetID, changes)
+}
+
+// AuditAs 以指定的操作者记录审计日志，用于登录等请求中操作者尚未确定的情况
+func (b *BaseController) AuditAs(actor, action, className, targetID string, changes types.M) {
+	audit.Log(&audit.Entry{
+		Action:    action,
+		Actor:     actor,
+		IP:        b.Ctx.Input.IP(),
+		Method:    b.Ctx.Input.Method(),
+		Route:     b.Ctx.Input.URL(),
+		ClassName: className,
+		TargetID:  targetID,
+		Changes:   changes,
+	})
+}
+
+// auditActor 返回当前请求的操作者
+func (b *BaseController) auditActor() string {
+	if b.Auth == nil {
+		return "anonymous"
+	}
+	return b.Auth.AuditActor()
+}
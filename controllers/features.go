@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/livequery"
 	"github.com/JuShangEnergy/framework/types"
 )
 
@@ -60,6 +61,8 @@ func (f *FeaturesController) HandleGet() {
 	f.Data["json"] = types.M{
 		"features":           features,
 		"parseServerVersion": "1.0",
+		// LiveQuery 客户端发送队列的统计，用于监控
+		"liveQuery": livequery.QueueStats(),
 	}
 	f.ServeJSON()
 }
@@ -20,6 +20,9 @@ type FunctionsController struct {
 //
 // @router /:functionName [post]
 func (f *FunctionsController) HandleCloudFunction() {
+	if f.EnforceAPIKeyOperation("functions") == false {
+		return
+	}
 	functionName := f.Ctx.Input.Param(":functionName")
 	theFunction := cloud.GetFunction(functionName)
 	theValidator := cloud.GetValidator(functionName)
@@ -89,6 +92,9 @@ func (f *FunctionsController) HandleCloudFunction() {
 
 // @router /:functionName [get]
 func (f *FunctionsController) HandleCloudFunctionGet() {
+	if f.EnforceAPIKeyOperation("functions") == false {
+		return
+	}
 	functionName := f.Ctx.Input.Param(":functionName")
 	theFunction := cloud.GetFunction(functionName)
 	theValidator := cloud.GetValidator(functionName)
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/errs"
 	"strings"
 
@@ -63,11 +64,22 @@ func (g *GlobalConfigController) HandlePut() {
 	for k, v := range params {
 		update["params."+k] = v
 	}
+	before := types.M{}
+	if results, _ := orm.TomatoDBController.Find("_GlobalConfig", types.M{"objectId": "1"}, types.M{"limit": 1}); len(results) == 1 {
+		if oldParams := utils.M(utils.M(results[0])["params"]); oldParams != nil {
+			for k := range params {
+				if v, ok := oldParams[k]; ok {
+					before[k] = v
+				}
+			}
+		}
+	}
 	_, err := orm.TomatoDBController.Update("_GlobalConfig", types.M{"objectId": "1"}, update, types.M{"upsert": true}, false)
 	if err != nil {
 		g.HandleError(err, 0)
 		return
 	}
+	g.Audit(audit.ActionConfigUpdate, "_GlobalConfig", "1", audit.Diff(before, params))
 	g.Data["json"] = types.M{"result": true}
 	g.ServeJSON()
 }
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/hooks"
 	"github.com/JuShangEnergy/framework/types"
@@ -60,6 +61,7 @@ func (h *HooksController) HandleCreateFunction() {
 		h.HandleError(err, 0)
 		return
 	}
+	h.Audit(audit.ActionHookCreate, "_Hooks", utils.S(h.JSONBody["functionName"]), audit.Diff(nil, h.JSONBody))
 	h.Data["json"] = result
 	h.ServeJSON()
 }
@@ -89,6 +91,7 @@ func (h *HooksController) HandleUpdateFunction() {
 		h.HandleError(err, 0)
 		return
 	}
+	h.auditHook(functionName)
 	h.Data["json"] = result
 	h.ServeJSON()
 }
@@ -134,6 +137,7 @@ func (h *HooksController) HandleCreateTrigger() {
 		h.HandleError(err, 0)
 		return
 	}
+	h.Audit(audit.ActionHookCreate, "_Hooks", utils.S(h.JSONBody["className"])+"."+utils.S(h.JSONBody["triggerName"]), audit.Diff(nil, h.JSONBody))
 	h.Data["json"] = result
 	h.ServeJSON()
 }
@@ -165,10 +169,20 @@ func (h *HooksController) HandleUpdateTrigger() {
 		h.HandleError(err, 0)
 		return
 	}
+	h.auditHook(className + "." + triggerName)
 	h.Data["json"] = result
 	h.ServeJSON()
 }
 
+// auditHook 记录 hook 的更新与删除操作
+func (h *HooksController) auditHook(target string) {
+	if utils.S(h.JSONBody["__op"]) == "Delete" {
+		h.Audit(audit.ActionHookDelete, "_Hooks", target, nil)
+		return
+	}
+	h.Audit(audit.ActionHookUpdate, "_Hooks", target, audit.Diff(nil, types.M{"url": h.JSONBody["url"]}))
+}
+
 // Get ...
 // @router / [get]
 func (h *HooksController) Get() {
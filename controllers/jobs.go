@@ -16,7 +16,7 @@ type JobsController struct {
 // HandleCloudJob 执行后台任务
 // @router /:jobName [post]
 func (j *JobsController) HandleCloudJob() {
-	if j.EnforceMasterKeyAccess() == false {
+	if j.enforceJobAccess() == false {
 		return
 	}
 	jobName := j.Ctx.Input.Param(":jobName")
@@ -26,13 +26,21 @@ func (j *JobsController) HandleCloudJob() {
 // HandlePost ...
 // @router / [post]
 func (j *JobsController) HandlePost() {
-	if j.EnforceMasterKeyAccess() == false {
+	if j.enforceJobAccess() == false {
 		return
 	}
 	jobName := utils.S(j.JSONBody["jobName"])
 	j.runJob(jobName)
 }
 
+// enforceJobAccess 执行后台任务需要 Master 权限，或者具有 jobs 权限的 API Key
+func (j *JobsController) enforceJobAccess() bool {
+	if j.Auth.APIKey != nil {
+		return j.EnforceAPIKeyOperation("jobs")
+	}
+	return j.EnforceMasterKeyAccess()
+}
+
 func (j *JobsController) runJob(jobName string) {
 	jobFunction := cloud.GetJob(jobName)
 	if jobFunction == nil {
@@ -3,6 +3,7 @@ package controllers
 import (
 	"time"
 
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/config"
 	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/files"
@@ -72,10 +73,12 @@ func (l *LoginController) HandleLogIn() {
 	accountLockoutPolicy := rest.NewAccountLockout(utils.S(user["username"]))
 	err = accountLockoutPolicy.HandleLoginAttempt(correct)
 	if err != nil {
+		l.Audit(audit.ActionLoginFailed, "_User", utils.S(user["objectId"]), nil)
 		l.HandleError(err, 0)
 		return
 	}
 	if correct == false {
+		l.Audit(audit.ActionLoginFailed, "_User", utils.S(user["objectId"]), nil)
 		l.HandleError(errs.E(errs.ObjectNotFound, "Invalid username/password."), 0)
 		return
 	}
@@ -97,7 +100,12 @@ func (l *LoginController) HandleLogIn() {
 		}
 	}
 
-	token := "r:" + utils.CreateToken()
+	expiresAt := config.GenerateSessionExpiresAt()
+	token, err := rest.NewSessionToken(utils.S(user["objectId"]), expiresAt, l.Info)
+	if err != nil {
+		l.HandleError(err, 0)
+		return
+	}
 	user["sessionToken"] = token
 	delete(user, "password")
 
@@ -116,7 +124,6 @@ func (l *LoginController) HandleLogIn() {
 	// 展开文件信息
 	files.ExpandFilesInObject(user)
 
-	expiresAt := config.GenerateSessionExpiresAt()
 	usr := types.M{
 		"__type":    "Pointer",
 		"className": "_User",
@@ -150,6 +157,7 @@ func (l *LoginController) HandleLogIn() {
 		l.HandleError(err, 0)
 		return
 	}
+	l.AuditAs("user:"+utils.S(user["objectId"]), audit.ActionLogin, "_User", utils.S(user["objectId"]), nil)
 
 	l.Data["json"] = user
 	l.ServeJSON()
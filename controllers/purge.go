@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/rest"
 	"github.com/JuShangEnergy/framework/types"
 )
 
@@ -18,14 +21,20 @@ func (p *PurgeController) HandleDelete() {
 		return
 	}
 	className := p.Ctx.Input.Param(":className")
+	// 审计日志只允许追加，不允许清空
+	if className == "_AuditLog" {
+		p.HandleError(errs.E(errs.OperationForbidden, "Clients aren't allowed to perform the purge operation on the _AuditLog collection."), 0)
+		return
+	}
 	err := orm.TomatoDBController.PurgeCollection(className)
 	if err != nil {
 		p.HandleError(err, 0)
 		return
 	}
+	p.Audit(audit.ActionPurge, className, "", nil)
 
 	if className == "_Session" {
-		cache.User.Clear()
+		rest.RevokeAllSessionTokens()
 	} else if className == "_Role" {
 		cache.Role.Clear()
 	}
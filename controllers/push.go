@@ -44,13 +44,55 @@ func (p *PushController) HandlePost() {
 	p.ServeJSON()
 }
 
+// HandleCancel 取消已计划或正在发送的推送
+// @router /:objectId [delete]
+func (p *PushController) HandleCancel() {
+	p.changeStatus(push.CancelPush)
+}
+
+// HandlePause 暂停正在发送的推送
+// @router /:objectId/pause [post]
+func (p *PushController) HandlePause() {
+	p.changeStatus(push.PausePush)
+}
+
+// HandleResume 恢复已暂停的推送
+// @router /:objectId/resume [post]
+func (p *PushController) HandleResume() {
+	p.changeStatus(push.ResumePush)
+}
+
+// changeStatus 修改 _PushStatus 的状态，需要 Master Key
+func (p *PushController) changeStatus(change func(string) error) {
+	if p.EnforceMasterKeyAccess() == false {
+		return
+	}
+	if p.Auth.IsReadOnly == true {
+		p.HandleError(errs.E(errs.OperationForbidden, "read-only masterKey isn't allowed to change push status."), 0)
+		return
+	}
+	err := change(p.Ctx.Input.Param(":objectId"))
+	if err != nil {
+		p.HandleError(err, 0)
+		return
+	}
+	p.Data["json"] = types.M{"result": true}
+	p.ServeJSON()
+}
+
 // getQueryCondition 获取查询条件
+// audience_id 表示使用推送受众中保存的查询条件，不能与 where 、 channels 同时设定
 func getQueryCondition(body types.M) (types.M, error) {
 	hasWhere := (body["where"] != nil)
 	hasChannels := (body["channels"] != nil)
+	hasAudience := (body["audience_id"] != nil)
 
 	var where types.M
-	if hasWhere && hasChannels {
+	if hasAudience && (hasWhere || hasChannels) {
+		return nil, errs.E(errs.PushMisconfigured, "audience_id can not be set with channels or query.")
+	} else if hasAudience {
+		return push.AudienceWhere(utils.S(body["audience_id"]))
+	} else if hasWhere && hasChannels {
 		// 查询与频道不能同时设定
 		return nil, errs.E(errs.PushMisconfigured, "Channels and query can not be set at the same time.")
 	} else if hasWhere {
@@ -63,7 +105,7 @@ func getQueryCondition(body types.M) (types.M, error) {
 			"channels": channels,
 		}
 	} else {
-		return nil, errs.E(errs.PushMisconfigured, `Sending a push requires either "channels" or a "where" query.`)
+		return nil, errs.E(errs.PushMisconfigured, `Sending a push requires either "channels", a "where" query or an "audience_id".`)
 	}
 
 	return where, nil
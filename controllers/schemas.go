@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/orm"
 	"github.com/JuShangEnergy/framework/types"
@@ -84,12 +85,27 @@ func (s *SchemasController) HandleCreate() {
 		return
 	}
 
+	liveQuery, hasLiveQuery, err := liveQueryFlag(data)
+	if err != nil {
+		s.HandleError(err, 0)
+		return
+	}
+
 	schema := orm.TomatoDBController.LoadSchema(types.M{"clearCache": true})
 	result, err := schema.AddClassIfNotExists(className, utils.M(data["fields"]), utils.M(data["classLevelPermissions"]))
 	if err != nil {
 		s.HandleError(err, 0)
 		return
 	}
+	if hasLiveQuery && liveQuery {
+		err = schema.SetLiveQuery(className, true)
+		if err != nil {
+			s.HandleError(err, 0)
+			return
+		}
+		result["liveQuery"] = true
+	}
+	s.Audit(audit.ActionSchemaCreate, className, "", audit.Diff(nil, audit.Flatten(result)))
 
 	s.Data["json"] = result
 	s.ServeJSON()
@@ -123,12 +139,32 @@ func (s *SchemasController) HandleUpdate() {
 		submittedFields = utils.M(data["fields"])
 	}
 
+	liveQuery, hasLiveQuery, err := liveQueryFlag(data)
+	if err != nil {
+		s.HandleError(err, 0)
+		return
+	}
+
 	schema := orm.TomatoDBController.LoadSchema(types.M{"clearCache": true})
+	before, _ := schema.GetOneSchema(className, false, nil)
 	result, err := schema.UpdateClass(className, submittedFields, utils.M(data["classLevelPermissions"]))
 	if err != nil {
 		s.HandleError(err, 0)
 		return
 	}
+	if hasLiveQuery {
+		err = schema.SetLiveQuery(className, liveQuery)
+		if err != nil {
+			s.HandleError(err, 0)
+			return
+		}
+		if liveQuery {
+			result["liveQuery"] = true
+		} else {
+			delete(result, "liveQuery")
+		}
+	}
+	s.Audit(audit.ActionSchemaUpdate, className, "", audit.Diff(audit.Flatten(before), audit.Flatten(result)))
 
 	s.Data["json"] = result
 	s.ServeJSON()
@@ -152,6 +188,7 @@ func (s *SchemasController) HandleDelete() {
 		s.HandleError(err, 0)
 		return
 	}
+	s.Audit(audit.ActionSchemaDelete, className, "", nil)
 
 	s.Data["json"] = types.M{}
 	s.ServeJSON()
@@ -169,3 +206,16 @@ func (s *SchemasController) Delete() {
 func (s *SchemasController) Put() {
 	s.ClassesController.Put()
 }
+
+// liveQueryFlag 从请求数据中读取 liveQuery 开关，第二个返回值表示请求中是否包含该字段
+func liveQueryFlag(data types.M) (bool, bool, error) {
+	v, ok := data["liveQuery"]
+	if ok == false || v == nil {
+		return false, false, nil
+	}
+	liveQuery, ok := v.(bool)
+	if ok == false {
+		return false, false, errs.E(errs.InvalidJSON, "liveQuery must be a boolean.")
+	}
+	return liveQuery, true, nil
+}
@@ -22,9 +22,13 @@ func (u *UpgradeSessionController) HandleUpdateToRevocableSession() {
 		return
 	}
 
-	token := "r:" + utils.CreateToken()
 	userID := utils.S(u.Auth.User["objectId"])
 	expiresAt := config.GenerateSessionExpiresAt()
+	token, err := rest.NewSessionToken(userID, expiresAt, u.Info)
+	if err != nil {
+		u.HandleError(err, 0)
+		return
+	}
 	sessionData := types.M{
 		"sessionToken": token,
 		"user": types.M{
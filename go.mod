@@ -13,6 +13,7 @@ require (
 	github.com/qiniu/api.v7/v7 v7.8.2
 	github.com/stretchr/testify v1.4.0
 	golang.org/x/net v0.0.0-20190613194153-d28f0bde5980
+	golang.org/x/text v0.3.0
 )
 
 require (
@@ -32,7 +33,6 @@ require (
 	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a // indirect
 	golang.org/x/sys v0.0.0-20200615200032-f1bc736245b1 // indirect
-	golang.org/x/text v0.3.0 // indirect
 	google.golang.org/protobuf v1.23.0 // indirect
 	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
@@ -0,0 +1,145 @@
+package headers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/utils"
+	"github.com/beego/beego/context"
+)
+
+// allowMethods 允许的跨域请求方法
+var allowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+
+// defaultAllowHeaders 默认允许的跨域请求头
+var defaultAllowHeaders = []string{
+	"Origin", "Authorization", "Content-Type", "X-Requested-With",
+	"X-Parse-Application-Id", "X-Parse-Master-Key", "X-Parse-REST-API-Key", "X-Parse-Javascript-Key",
+	"X-Parse-Client-Version", "X-Parse-Session-Token", "X-Parse-Revocable-Session",
+	"X-Parse-Installation-Id", "X-Parse-Api-Key",
+}
+
+// customAllowHeaders customExposeHeaders 云代码中注册的自定义请求头与响应头
+var customAllowHeaders = []string{}
+var customExposeHeaders = []string{}
+
+// publicPagesPath publichtml 页面的路由前缀
+const publicPagesPath = "/v1/apps/"
+
+// AllowHeaders 在云代码中注册额外允许的跨域请求头，需要在服务启动前调用
+func AllowHeaders(names ...string) {
+	customAllowHeaders = appendHeaders(customAllowHeaders, names...)
+}
+
+// ExposeHeaders 在云代码中注册允许客户端读取的响应头，需要在服务启动前调用
+func ExposeHeaders(names ...string) {
+	customExposeHeaders = appendHeaders(customExposeHeaders, names...)
+}
+
+// CORSFilter 处理跨域请求
+// 来源匹配 CORSAllowOrigins 时返回跨域响应头，允许所有来源时不携带 Access-Control-Allow-Credentials
+// 预检请求统一返回 200
+func CORSFilter() func(*context.Context) {
+	return func(ctx *context.Context) {
+		origin := ctx.Input.Header("Origin")
+		allowAll := utils.StringInSlice("*", config.TConfig.CORSAllowOrigins)
+		if allowAll == false {
+			ctx.Output.Header("Vary", "Origin")
+		}
+		if origin != "" && (allowAll || OriginAllowed(origin, config.TConfig.CORSAllowOrigins)) {
+			if allowAll {
+				ctx.Output.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				ctx.Output.Header("Access-Control-Allow-Origin", origin)
+				ctx.Output.Header("Access-Control-Allow-Credentials", "true")
+			}
+			if expose := appendHeaders(appendHeaders(nil, config.TConfig.CORSExposeHeaders...), customExposeHeaders...); len(expose) > 0 {
+				ctx.Output.Header("Access-Control-Expose-Headers", strings.Join(expose, ", "))
+			}
+			if ctx.Input.Method() == "OPTIONS" && ctx.Input.Header("Access-Control-Request-Method") != "" {
+				ctx.Output.Header("Access-Control-Allow-Methods", strings.Join(allowMethods, ", "))
+				ctx.Output.Header("Access-Control-Allow-Headers", strings.Join(allowedHeaders(), ", "))
+				if config.TConfig.CORSMaxAge > 0 {
+					ctx.Output.Header("Access-Control-Max-Age", strconv.Itoa(config.TConfig.CORSMaxAge))
+				}
+			}
+		}
+		if ctx.Input.Method() == "OPTIONS" {
+			ctx.Output.SetStatus(200)
+			ctx.ResponseWriter.Started = true
+		}
+	}
+}
+
+// SecurityFilter 按配置添加安全相关的响应头
+// X-Frame-Options 仅用于 publichtml 页面，避免页面被嵌入到其他站点中
+func SecurityFilter() func(*context.Context) {
+	return func(ctx *context.Context) {
+		if config.TConfig.HSTSMaxAge > 0 {
+			ctx.Output.Header("Strict-Transport-Security", "max-age="+strconv.Itoa(config.TConfig.HSTSMaxAge)+"; includeSubDomains")
+		}
+		if config.TConfig.ContentTypeNosniff {
+			ctx.Output.Header("X-Content-Type-Options", "nosniff")
+		}
+		if config.TConfig.FrameOptions != "" && strings.HasPrefix(ctx.Input.URL(), publicPagesPath) {
+			ctx.Output.Header("X-Frame-Options", config.TConfig.FrameOptions)
+		}
+	}
+}
+
+// OriginAllowed 判断来源是否在允许列表中，不区分大小写
+// 列表项中的 * 匹配任意子域名，如 https://*.example.com 匹配 https://a.example.com 与 https://a.b.example.com ，不匹配 https://example.com
+func OriginAllowed(origin string, allowOrigins []string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowOrigins {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		i := strings.Index(pattern, "*")
+		if i < 0 {
+			continue
+		}
+		prefix, suffix := pattern[:i], pattern[i+1:]
+		if len(origin) <= len(prefix)+len(suffix) {
+			continue
+		}
+		if strings.HasPrefix(origin, prefix) == false || strings.HasSuffix(origin, suffix) == false {
+			continue
+		}
+		// 通配部分只能是域名，不能包含端口或路径
+		if strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:") == false {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders 返回默认、配置与云代码中注册的全部请求头
+func allowedHeaders() []string {
+	headers := appendHeaders(nil, defaultAllowHeaders...)
+	headers = appendHeaders(headers, config.TConfig.CORSAllowHeaders...)
+	return appendHeaders(headers, customAllowHeaders...)
+}
+
+// appendHeaders 追加请求头，忽略空值与重复项
+func appendHeaders(headers []string, names ...string) []string {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		exist := false
+		for _, h := range headers {
+			if strings.EqualFold(h, name) {
+				exist = true
+				break
+			}
+		}
+		if exist == false {
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
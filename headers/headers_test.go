@@ -0,0 +1,144 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/beego/beego/context"
+)
+
+func serve(filter func(*context.Context), method, url string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	ctx := context.NewContext()
+	ctx.Reset(w, req)
+	filter(ctx)
+	return w
+}
+
+func Test_OriginAllowed(t *testing.T) {
+	var origins []string
+	/************************************************************/
+	origins = []string{"https://app.example.com"}
+	if OriginAllowed("https://app.example.com", origins) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if OriginAllowed("https://APP.example.com", origins) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if OriginAllowed("http://app.example.com", origins) {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	origins = []string{"https://*.example.com"}
+	if OriginAllowed("https://a.example.com", origins) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if OriginAllowed("https://a.b.example.com", origins) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if OriginAllowed("https://example.com", origins) {
+		t.Error("expect:", false, "result:", true)
+	}
+	if OriginAllowed("https://evil.com/.example.com", origins) {
+		t.Error("expect:", false, "result:", true)
+	}
+	if OriginAllowed("https://a.example.com.evil.com", origins) {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	origins = []string{"*"}
+	if OriginAllowed("https://any.com", origins) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+}
+
+func Test_CORSFilter(t *testing.T) {
+	var w *httptest.ResponseRecorder
+	var expect []string
+	config.TConfig = &config.Config{}
+	/************************************************************/
+	config.TConfig.CORSAllowOrigins = []string{"*"}
+	w = serve(CORSFilter(), "GET", "/v1/classes/post", map[string]string{"Origin": "https://a.com"})
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("expect:", "*", "result:", w.Header())
+	}
+	/************************************************************/
+	config.TConfig.CORSAllowOrigins = []string{"https://*.example.com"}
+	config.TConfig.CORSExposeHeaders = []string{"X-RateLimit-Remaining"}
+	w = serve(CORSFilter(), "GET", "/v1/classes/post", map[string]string{"Origin": "https://a.example.com"})
+	expect = []string{"https://a.example.com", "true", "Origin", "X-RateLimit-Remaining"}
+	result := []string{
+		w.Header().Get("Access-Control-Allow-Origin"),
+		w.Header().Get("Access-Control-Allow-Credentials"),
+		w.Header().Get("Vary"),
+		w.Header().Get("Access-Control-Expose-Headers"),
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	w = serve(CORSFilter(), "GET", "/v1/classes/post", map[string]string{"Origin": "https://evil.com"})
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("expect:", "", "result:", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	/************************************************************/
+	config.TConfig.CORSAllowHeaders = []string{"X-Custom"}
+	config.TConfig.CORSMaxAge = 600
+	customAllowHeaders = []string{}
+	AllowHeaders("X-Cloud", "x-custom")
+	w = serve(CORSFilter(), "OPTIONS", "/v1/classes/post", map[string]string{
+		"Origin":                        "https://a.example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	expect = []string{"https://a.example.com", "600", "X-Parse-Installation-Id", "X-Custom", "X-Cloud"}
+	allowHeaders := allowedHeaders()
+	result = []string{
+		w.Header().Get("Access-Control-Allow-Origin"),
+		w.Header().Get("Access-Control-Max-Age"),
+		allowHeaders[len(allowHeaders)-4],
+		allowHeaders[len(allowHeaders)-2],
+		allowHeaders[len(allowHeaders)-1],
+	}
+	if reflect.DeepEqual(expect, result) == false || w.Code != 200 {
+		t.Error("expect:", expect, "result:", result, w.Code)
+	}
+	customAllowHeaders = []string{}
+}
+
+func Test_SecurityFilter(t *testing.T) {
+	var w *httptest.ResponseRecorder
+	var expect []string
+	var result []string
+	config.TConfig = &config.Config{}
+	/************************************************************/
+	w = serve(SecurityFilter(), "GET", "/v1/apps/choose_password", nil)
+	if len(w.Header()) != 0 {
+		t.Error("expect:", 0, "result:", w.Header())
+	}
+	/************************************************************/
+	config.TConfig.HSTSMaxAge = 31536000
+	config.TConfig.ContentTypeNosniff = true
+	config.TConfig.FrameOptions = "DENY"
+	w = serve(SecurityFilter(), "GET", "/v1/apps/choose_password", nil)
+	expect = []string{"max-age=31536000; includeSubDomains", "nosniff", "DENY"}
+	result = []string{
+		w.Header().Get("Strict-Transport-Security"),
+		w.Header().Get("X-Content-Type-Options"),
+		w.Header().Get("X-Frame-Options"),
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	w = serve(SecurityFilter(), "GET", "/v1/classes/post", nil)
+	if w.Header().Get("X-Frame-Options") != "" {
+		t.Error("expect:", "", "result:", w.Header().Get("X-Frame-Options"))
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"strconv"
 	"sync"
+	"time"
 
 	"strings"
 
+	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/livequery/pubsub"
 	"github.com/JuShangEnergy/framework/livequery/server"
 	"github.com/JuShangEnergy/framework/livequery/t"
@@ -43,6 +45,7 @@ request
 		"where": {"name": "test"},
 		"fields": ["name"] // Optional
 	},
+	"watch": ["score"], // Optional
 	"sessionToken": "" // Optional
 }
 response
@@ -61,6 +64,7 @@ request
 		"where": {"name": "test"},
 		"fields": ["name"] // Optional
 	},
+	"watch": ["score"], // Optional
 	"sessionToken": "" // Optional
 }
 response
@@ -165,15 +169,19 @@ response
 var s *liveQueryServer
 
 type liveQueryServer struct {
-	mutex             sync.Mutex
-	pattern           string                                     // WebSocket 所在子地址
-	addr              string                                     // WebSocket 监听地址与端口
-	clientID          int                                        // 客户端 id ，递增
-	clients           map[int]*server.Client                     // 当前已连接的客户端，以 clientID 为索引 TODO 增加并发锁
-	subscriptions     map[string]map[string]*server.Subscription // 当前所有的订阅对象 className -> (queryHash -> subscription) TODO 增加并发锁
-	keyPairs          map[string]string                          // 用于客户端鉴权的键值对，如 secretKey:abcd
-	subscriber        pubsub.Subscriber                          // 订阅者
-	sessionTokenCache *server.SessionTokenCache                  // 缓存 sessionToken 对应的用户 id
+	mutex                sync.Mutex
+	pattern              string                                     // WebSocket 所在子地址
+	addr                 string                                     // WebSocket 监听地址与端口
+	clientID             int                                        // 客户端 id ，递增
+	clients              map[int]*server.Client                     // 当前已连接的客户端，以 clientID 为索引 TODO 增加并发锁
+	subscriptions        map[string]map[string]*server.Subscription // 当前所有的订阅对象 className -> (queryHash -> subscription) TODO 增加并发锁
+	keyPairs             map[string]string                          // 用于客户端鉴权的键值对，如 secretKey:abcd
+	subscriber           pubsub.Subscriber                          // 订阅者
+	sessionTokenCache    *server.SessionTokenCache                  // 缓存 sessionToken 对应的用户 id
+	sessionRevokedPolicy string                                     // sessionToken 失效后的处理方式
+	dispatchMutex        sync.Mutex                                 // 保证对象变更消息按顺序推送
+	eventID              int64                                      // 当前推送的事件 ID ，用于 SSE 的 Last-Event-ID
+	replayEvents         *replayBuffer                              // 最近的对象变更消息，用于 SSE 重连后补发
 }
 
 // Run 初始化 server ，启动 WebSocket
@@ -186,8 +194,18 @@ type liveQueryServer struct {
 // appId tomato 对应的 appId
 // clientKey tomato 对应的 clientKey
 // masterKey tomato 对应的 masterKey
-// subType 订阅服务类型，支持 EventEmitter Redis
+// subType 订阅服务类型，支持 EventEmitter Redis RedisStreams NATS Embedded ， Embedded 仅用于与 tomato 运行在同一进程中的情况
 // subURL 订阅服务地址，如果是 EventEmitter 可不填写
+// sendQueueSize 每个客户端发送队列的长度，默认为 100
+// writeTimeout 单条消息的写入超时时间，单位为秒，默认为 10
+// overflowPolicy 发送队列已满时的处理方式，可选： dropOldest disconnect ，默认为 dropOldest
+// pingInterval 发送 ping 帧的间隔，单位为秒，为 0 时不发送，默认为 30
+// sessionRevokedPolicy sessionToken 被删除或过期后的处理方式， downgrade 表示之后按未登录用户校验权限， close 表示关闭相关订阅，默认为 downgrade
+// userSensitiveFields _User 中仅本人可见的字段，多个字段使用 | 隔开，默认为 email
+// idleTimeout 连接空闲超时时间，单位为秒，超时未收到客户端数据时断开连接，为 0 时不检测，默认为 90
+// classNames 开启 LiveQuery 的类，多个类使用 | 隔开，设置后拒绝订阅未开启的类，未设置时不校验
+// ssePattern SSE 接口路径，为空时不开启 SSE ，默认为 /live
+// sseReplaySize SSE 重连时可补发的最近事件数，默认为 100
 func Run(args map[string]string) {
 	s = &liveQueryServer{}
 	s.initServer(args)
@@ -223,16 +241,69 @@ func (l *liveQueryServer) initServer(args map[string]string) {
 	}
 	utils.TLog.Verbose("Support key pairs", l.keyPairs)
 
+	// 设置客户端发送队列
+	if size, err := strconv.Atoi(args["sendQueueSize"]); err == nil && size > 0 {
+		server.SendQueueSize = size
+	}
+	if timeout, err := strconv.Atoi(args["writeTimeout"]); err == nil && timeout > 0 {
+		server.WriteTimeout = time.Duration(timeout) * time.Second
+	}
+	if policy := args["overflowPolicy"]; policy == server.OverflowDropOldest || policy == server.OverflowDisconnect {
+		server.OverflowPolicy = policy
+	}
+
+	// 设置 _User 中仅本人可见的字段
+	if fields, ok := args["userSensitiveFields"]; ok {
+		server.UserSensitiveFields = []string{}
+		for _, field := range strings.Split(fields, "|") {
+			if field != "" {
+				server.UserSensitiveFields = append(server.UserSensitiveFields, field)
+			}
+		}
+	}
+
+	// 设置开启 LiveQuery 的类，未设置时不校验订阅的类
+	if classNames, ok := args["classNames"]; ok {
+		server.LiveQueryClasses = map[string]bool{}
+		for _, className := range strings.Split(classNames, "|") {
+			if className != "" {
+				server.LiveQueryClasses[className] = true
+			}
+		}
+	}
+
+	// 设置 SSE 接口路径与重放缓存长度
+	if pattern, ok := args["ssePattern"]; ok {
+		server.SSEPattern = pattern
+	}
+	replaySize := 100
+	if size, err := strconv.Atoi(args["sseReplaySize"]); err == nil && size >= 0 {
+		replaySize = size
+	}
+	l.replayEvents = newReplayBuffer(replaySize)
+
+	// 设置心跳与空闲超时
+	if interval, err := strconv.Atoi(args["pingInterval"]); err == nil && interval >= 0 {
+		server.PingInterval = time.Duration(interval) * time.Second
+	}
+	if timeout, err := strconv.Atoi(args["idleTimeout"]); err == nil && timeout >= 0 {
+		server.IdleTimeout = time.Duration(timeout) * time.Second
+	}
+
 	// 初始化 tomato 服务参数，用于获取用户信息
 	server.TomatoInfo["serverURL"] = args["serverURL"]
 	server.TomatoInfo["appId"] = args["appId"]
 	server.TomatoInfo["clientKey"] = args["clientKey"]
 	server.TomatoInfo["masterKey"] = args["masterKey"]
 
+	// 订阅条件中的子查询通过 tomato 执行
+	utils.SubQueryResolver = server.FindObjects
+
 	// 向 subscriber 订阅 afterSave 、 afterDelete 两个频道
 	l.subscriber = pubsub.CreateSubscriber(args["subType"], args["subURL"], args["subConfig"])
 	l.subscriber.Subscribe(server.TomatoInfo["appId"] + "afterSave")
 	l.subscriber.Subscribe(server.TomatoInfo["appId"] + "afterDelete")
+	l.subscriber.Subscribe(server.TomatoInfo["appId"] + "sessionRevoked")
 
 	// 设置从 subscriber 接收到消息时的处理函数
 	var h pubsub.HandlerType
@@ -251,9 +322,11 @@ func (l *liveQueryServer) initServer(args map[string]string) {
 		}
 		l.inflateParseObject(message)
 		if channel == server.TomatoInfo["appId"]+"afterSave" {
-			l.onAfterSave(message)
+			l.dispatch(channelAfterSave, message)
 		} else if channel == server.TomatoInfo["appId"]+"afterDelete" {
-			l.onAfterDelete(message)
+			l.dispatch(channelAfterDelete, message)
+		} else if channel == server.TomatoInfo["appId"]+"sessionRevoked" {
+			l.onSessionRevoked(message)
 		} else {
 			utils.TLog.Error("Get message", message, "from unknown channel", channel)
 		}
@@ -262,6 +335,13 @@ func (l *liveQueryServer) initServer(args map[string]string) {
 
 	// 设置 cache
 	l.sessionTokenCache = server.NewSessionTokenCache()
+
+	// 设置 sessionToken 失效后的处理方式，并定期检查已过期的 sessionToken
+	l.sessionRevokedPolicy = sessionRevokedDowngrade
+	if args["sessionRevokedPolicy"] == sessionRevokedClose {
+		l.sessionRevokedPolicy = sessionRevokedClose
+	}
+	go l.sweepExpiredSessions()
 }
 
 // run 启动 WebSocket 服务
@@ -324,16 +404,18 @@ func (l *liveQueryServer) OnMessage(ws *server.WebSocket, msg interface{}) {
 func (l *liveQueryServer) OnDisconnect(ws *server.WebSocket) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
-	utils.TLog.Log("Client disconnect:", ws.ClientID)
-
 	clientID := ws.ClientID
+	lifetime := time.Since(ws.ConnectedAt)
 	if _, ok := l.clients[clientID]; ok == false {
+		utils.TLog.Log("Client disconnect:", clientID, "lifetime:", lifetime)
 		utils.TLog.Error("Can not find client", clientID, "on disconnect")
 		return
 	}
 
 	client := l.clients[clientID]
 	delete(l.clients, clientID)
+	utils.TLog.Log("Client disconnect:", clientID, "lifetime:", lifetime, "subscriptions:", len(client.SubscriptionInfos),
+		"queueDepth:", client.QueueDepth(), "dropped:", client.Dropped())
 
 	for requestID, subscriptionInfo := range client.SubscriptionInfos {
 		subscription := subscriptionInfo.Subscription
@@ -353,34 +435,60 @@ func (l *liveQueryServer) OnDisconnect(ws *server.WebSocket) {
 	utils.TLog.Verbose("Current subscriptions", len(l.subscriptions))
 }
 
+// QueueStats 返回客户端发送队列的统计，用于监控， LiveQuery 未启动时各项均为 0
+// queueDepth 为全部客户端等待发送的消息数， maxQueueDepth 为单个客户端等待发送的最大消息数， dropped 为当前客户端因队列已满被丢弃的消息数
+func QueueStats() map[string]int {
+	stats := map[string]int{
+		"clients":       0,
+		"queueDepth":    0,
+		"maxQueueDepth": 0,
+		"dropped":       0,
+	}
+	if s == nil {
+		return stats
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, client := range s.clients {
+		depth := client.QueueDepth()
+		stats["clients"]++
+		stats["queueDepth"] += depth
+		if depth > stats["maxQueueDepth"] {
+			stats["maxQueueDepth"] = depth
+		}
+		stats["dropped"] += client.Dropped()
+	}
+	return stats
+}
+
 // inflateParseObject 展开对象
 func (l *liveQueryServer) inflateParseObject(message t.M) {
 
 }
 
-// onAfterDelete 从 subscriber 中接收到对象删除消息时调用
-func (l *liveQueryServer) onAfterDelete(message t.M) {
+// pushAfterDelete 从 subscriber 中接收到对象删除消息时调用， onlyClientID 不为 0 时仅推送给该客户端
+func (l *liveQueryServer) pushAfterDelete(message t.M, onlyClientID int) {
 	utils.TLog.Verbose("afterDelete is triggered")
 
 	deletedParseObject := message["currentParseObject"].(map[string]interface{})
 	className := deletedParseObject["className"].(string)
 	utils.TLog.Verbose("ClassName:", className, "| ObjectId:", deletedParseObject["objectId"])
-	utils.TLog.Verbose("Current client number :", len(l.clients))
 
-	// 取出当前类对应的订阅对象列表
-	classSubscriptions := l.subscriptions[className]
-	if classSubscriptions == nil {
-		utils.TLog.Error("Can not find subscriptions under this class", className)
-		return
-	}
-	for _, subscription := range classSubscriptions {
-		// 检测要删除的对象是否符合订阅条件
-		isSubscriptionMatched := l.matchesSubscription(deletedParseObject, subscription)
-		if isSubscriptionMatched == false {
+	// 取出当前类对应的订阅对象列表，并检测要删除的对象是否符合订阅条件
+	matches := l.matchSubscriptions(className, nil, deletedParseObject)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	utils.TLog.Verbose("Current client number :", len(l.clients))
+	for _, match := range matches {
+		if match.current == false {
 			continue
 		}
+		subscription := match.subscription
 		// 如果符合订阅条件，则向指定的 client 的 request 返回对象
 		for clientID, requestIDs := range subscription.ClientRequestIDs {
+			if onlyClientID != 0 && clientID != onlyClientID {
+				continue
+			}
 			client := l.clients[clientID]
 			if client == nil {
 				continue
@@ -391,19 +499,19 @@ func (l *liveQueryServer) onAfterDelete(message t.M) {
 					acl = v
 				}
 				// 检测 client 是否有权限接收这条删除信息
-				isMatched := l.matchesACL(acl, client, requestID)
+				isMatched := l.matchesACL(acl, client, requestID) && l.matchesCLP(deletedParseObject, client, requestID)
 				if isMatched == false {
 					continue
 				}
 				// 向 client 发送删除的对象
-				client.PushDelete(requestID, deletedParseObject, nil)
+				client.PushDelete(requestID, l.filterSensitiveData(className, deletedParseObject, client, requestID), nil)
 			}
 		}
 	}
 }
 
-// onAfterSave 从 subscriber 中接收到对象保存消息时调用
-func (l *liveQueryServer) onAfterSave(message t.M) {
+// pushAfterSave 从 subscriber 中接收到对象保存消息时调用， onlyClientID 不为 0 时仅推送给该客户端
+func (l *liveQueryServer) pushAfterSave(message t.M, onlyClientID int) {
 	utils.TLog.Verbose("afterSave is triggered")
 
 	var originalParseObject t.M
@@ -413,23 +521,28 @@ func (l *liveQueryServer) onAfterSave(message t.M) {
 	currentParseObject := message["currentParseObject"].(map[string]interface{})
 	className := currentParseObject["className"].(string)
 	utils.TLog.Verbose("ClassName:", className, "| ObjectId:", currentParseObject["objectId"])
-	utils.TLog.Verbose("Current client number :", len(l.clients))
-	// 取出当前类对应的订阅信息列表
-	classSubscriptions := l.subscriptions[className]
-	if classSubscriptions == nil {
-		utils.TLog.Error("Can not find subscriptions under this class", className)
-		return
+	// 计算保存前后变化的字段，用于校验订阅的 watch 字段
+	var changedFields map[string]bool
+	if originalParseObject != nil {
+		changedFields = utils.ChangedFields(originalParseObject, currentParseObject)
 	}
-
-	for _, subscription := range classSubscriptions {
-		// 检测要保存的对象是否符合订阅条件
-		isOriginalSubscriptionMatched := l.matchesSubscription(originalParseObject, subscription)
-		isCurrentSubscriptionMatched := l.matchesSubscription(currentParseObject, subscription)
+	// 取出当前类对应的订阅信息列表，并检测要保存的对象是否符合订阅条件
+	matches := l.matchSubscriptions(className, originalParseObject, currentParseObject)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	utils.TLog.Verbose("Current client number :", len(l.clients))
+	for _, match := range matches {
+		subscription := match.subscription
+		isOriginalSubscriptionMatched := match.original
+		isCurrentSubscriptionMatched := match.current
 		// 均不符合则跳过
 		if isOriginalSubscriptionMatched == false && isCurrentSubscriptionMatched == false {
 			continue
 		}
 		for clientID, requestIDs := range subscription.ClientRequestIDs {
+			if onlyClientID != 0 && clientID != onlyClientID {
+				continue
+			}
 			client := l.clients[clientID]
 			if client == nil {
 				continue
@@ -446,7 +559,7 @@ func (l *liveQueryServer) onAfterSave(message t.M) {
 							originalACL = v
 						}
 					}
-					isOriginalMatched = l.matchesACL(originalACL, client, requestID)
+					isOriginalMatched = l.matchesACL(originalACL, client, requestID) && l.matchesCLP(originalParseObject, client, requestID)
 				}
 
 				var isCurrentMatched bool
@@ -457,7 +570,7 @@ func (l *liveQueryServer) onAfterSave(message t.M) {
 					if v, ok := currentParseObject["ACL"].(map[string]interface{}); ok {
 						currentACL = v
 					}
-					isCurrentMatched = l.matchesACL(currentACL, client, requestID)
+					isCurrentMatched = l.matchesACL(currentACL, client, requestID) && l.matchesCLP(currentParseObject, client, requestID)
 				}
 
 				utils.TLog.Verbose("Original", originalParseObject,
@@ -465,19 +578,23 @@ func (l *liveQueryServer) onAfterSave(message t.M) {
 					"| Match:", isOriginalSubscriptionMatched, isCurrentSubscriptionMatched, isOriginalMatched, isCurrentMatched,
 					"| Query:", subscription.Hash)
 
+				object := l.filterSensitiveData(className, currentParseObject, client, requestID)
 				if isOriginalMatched && isCurrentMatched {
-					// 原对象与新对象均符合条件，则为 Update
-					client.PushUpdate(requestID, currentParseObject, originalParseObject)
+					// 原对象与新对象均符合条件，则为 Update ，订阅了 watch 时仅在其中的字段变化时推送
+					if info := client.GetSubscriptionInfo(requestID); info != nil && info.WatchedFieldsChanged(changedFields) == false {
+						continue
+					}
+					client.PushUpdate(requestID, object, originalParseObject)
 				} else if isOriginalMatched && !isCurrentMatched {
 					// 原对象符合条件，但是新对象不符合，则为 Leave
-					client.PushLeave(requestID, currentParseObject, originalParseObject)
+					client.PushLeave(requestID, object, originalParseObject)
 				} else if !isOriginalMatched && isCurrentMatched {
 					if originalParseObject != nil {
 						// 原对象不符合条件，但是新对象符合，则为 Enter
-						client.PushEnter(requestID, currentParseObject, originalParseObject)
+						client.PushEnter(requestID, object, originalParseObject)
 					} else {
 						// 原对象不存在，同时新对象符合条件，则为 Create
-						client.PushCreate(requestID, currentParseObject, originalParseObject)
+						client.PushCreate(requestID, object, originalParseObject)
 					}
 				} else {
 					continue
@@ -498,6 +615,9 @@ func (l *liveQueryServer) handleConnect(ws *server.WebSocket, request t.M) {
 
 	// 创建新的 client 并更新 l.clientID
 	client := server.NewClient(l.clientID, ws)
+	if masterKey, ok := request["masterKey"].(string); ok && masterKey != "" && masterKey == server.TomatoInfo["masterKey"] {
+		client.HasMasterKey = true
+	}
 	ws.ClientID = l.clientID
 	l.clientID++
 	l.mutex.Lock()
@@ -507,27 +627,92 @@ func (l *liveQueryServer) handleConnect(ws *server.WebSocket, request t.M) {
 	client.PushConnect(0, nil, nil)
 }
 
-// handleSubscribe 处理客户端 Subscribe 操作
-func (l *liveQueryServer) handleSubscribe(ws *server.WebSocket, request t.M) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	if ws.ClientID == 0 {
-		server.PushError(ws, 2, "Can not find this client, make sure you connect to server before subscribing", true)
-		utils.TLog.Error("Can not find this client, make sure you connect to server before subscribing")
-		return
+// handleSubscribe 处理客户端 Subscribe 操作，订阅失败时向客户端发送错误信息并返回 false
+func (l *liveQueryServer) handleSubscribe(ws *server.WebSocket, request t.M) bool {
+	readUserFields, ok := l.validateSubscription(ws, request)
+	if ok == false {
+		return false
 	}
+	return l.addSubscription(ws, request, readUserFields)
+}
 
-	client := l.clients[ws.ClientID]
+// subscribingClient 获取订阅的客户端，不存在时向客户端发送错误信息，调用方需要持有 l.mutex
+func (l *liveQueryServer) subscribingClient(ws *server.WebSocket) *server.Client {
+	var client *server.Client
+	if ws.ClientID != 0 {
+		client = l.clients[ws.ClientID]
+	}
 	if client == nil {
 		server.PushError(ws, 2, "Can not find this client, make sure you connect to server before subscribing", true)
 		utils.TLog.Error("Can not find this client, make sure you connect to server before subscribing")
-		return
+	}
+	return client
+}
+
+// validateSubscription 校验类是否开启了 LiveQuery 以及用户的 CLP 权限，返回订阅需要满足的 readUserFields
+// 校验过程需要请求 tomato 接口，不持有 l.mutex ，避免慢请求阻塞其他客户端的订阅、断开与消息推送
+func (l *liveQueryServer) validateSubscription(ws *server.WebSocket, request t.M) ([]string, bool) {
+	l.mutex.Lock()
+	client := l.subscribingClient(ws)
+	l.mutex.Unlock()
+	if client == nil {
+		return nil, false
+	}
+
+	query := request["query"].(map[string]interface{})
+	className := query["className"].(string)
+	var sessionToken string
+	if v, ok := request["sessionToken"].(string); ok {
+		sessionToken = v
+	}
+	// 校验该类是否开启了 LiveQuery ，未开启时不会有任何事件推送，直接拒绝订阅
+	if enabled, err := server.LiveQueryEnabled(className); err != nil || enabled == false {
+		message := "Class " + className + " is not enabled for LiveQuery."
+		if err != nil {
+			message = err.Error()
+		}
+		server.PushError(ws, errs.OperationForbidden, message, false)
+		utils.TLog.Error("Client", ws.ClientID, "can not subscribe", className, message)
+		return nil, false
+	}
+	if client.HasMasterKey {
+		return nil, true
+	}
+	// 子查询使用 masterKey 执行，非 masterKey 客户端不允许通过订阅条件探测受保护的数据
+	if utils.HasSubQuery(query["where"]) {
+		message := "Sub query is not allowed without masterKey."
+		server.PushError(ws, errs.OperationForbidden, message, false)
+		utils.TLog.Error("Client", ws.ClientID, "can not subscribe", className, message)
+		return nil, false
+	}
+	// 校验用户对该类的 CLP 权限
+	readUserFields, err := l.validateCLP(className, query, sessionToken)
+	if err != nil {
+		server.PushError(ws, errs.OperationForbidden, err.Error(), false)
+		utils.TLog.Error("Client", ws.ClientID, "can not subscribe", className, err.Error())
+		return nil, false
+	}
+	return readUserFields, true
+}
+
+// addSubscription 把已通过校验的订阅添加到客户端，并通知客户端订阅成功
+func (l *liveQueryServer) addSubscription(ws *server.WebSocket, request t.M, readUserFields []string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	// 校验期间客户端可能已断开
+	client := l.subscribingClient(ws)
+	if client == nil {
+		return false
 	}
 
 	query := request["query"].(map[string]interface{})
+	className := query["className"].(string)
+	var sessionToken string
+	if v, ok := request["sessionToken"].(string); ok {
+		sessionToken = v
+	}
 	// 计算 query 的 hash ，参与计算的字段包括： className 与 where
 	subscriptionHash := utils.QueryHash(query)
-	className := query["className"].(string)
 	if _, ok := l.subscriptions[className]; ok == false {
 		l.subscriptions[className] = map[string]*server.Subscription{}
 	}
@@ -545,7 +730,9 @@ func (l *liveQueryServer) handleSubscribe(ws *server.WebSocket, request t.M) {
 
 	// 生成订阅信息对象，用于设置到 client 中
 	subscriptionInfo := &server.SubscriptionInfo{
-		Subscription: subscription,
+		Subscription:   subscription,
+		SessionToken:   sessionToken,
+		ReadUserFields: readUserFields,
 	}
 	if fields, ok := query["fields"]; ok {
 		fieldsArray := []string{}
@@ -555,8 +742,11 @@ func (l *liveQueryServer) handleSubscribe(ws *server.WebSocket, request t.M) {
 		}
 		subscriptionInfo.Fields = fieldsArray
 	}
-	if sessionToken, ok := request["sessionToken"]; ok {
-		subscriptionInfo.SessionToken = sessionToken.(string)
+	if watch, ok := request["watch"]; ok {
+		// request["watch"] 已经过校验，确定格式为 []string
+		for _, field := range watch.([]interface{}) {
+			subscriptionInfo.Watch = append(subscriptionInfo.Watch, field.(string))
+		}
 	}
 	requestID := int(request["requestId"].(float64))
 	// 根据 requestID ，把订阅信息对象设置到 client 中
@@ -568,6 +758,7 @@ func (l *liveQueryServer) handleSubscribe(ws *server.WebSocket, request t.M) {
 
 	utils.TLog.Verbose("Create client", ws.ClientID, "new subscription:", requestID)
 	utils.TLog.Verbose("Current client number:", len(l.clients))
+	return true
 }
 
 // handleUpdateSubscription 处理客户端 update 操作
@@ -602,13 +793,23 @@ func (l *liveQueryServer) handleUnsubscribe(ws *server.WebSocket, request t.M, n
 		utils.TLog.Error("Can not find subscription with clientId", ws.ClientID, "subscriptionId", requestID)
 		return
 	}
+	l.deleteSubscription(ws.ClientID, client, requestID, subscriptionInfo)
+	if notifyClient == false {
+		return
+	}
+	// 退订成功
+	client.PushUnsubscribe(requestID, nil, nil)
+}
+
+// deleteSubscription 删除客户端的订阅信息，调用方需要持有 l.mutex
+func (l *liveQueryServer) deleteSubscription(clientID int, client *server.Client, requestID int, subscriptionInfo *server.SubscriptionInfo) {
 	// 从 client 中删除 requestID 对应的 订阅信息对象
 	client.DeleteSubscriptionInfo(requestID)
 	// 取出 订阅对象
 	subscription := subscriptionInfo.Subscription
 	className := subscription.ClassName
 	// 更新订阅对象，删除使用该对象的 ClientID 与 requestID
-	subscription.DeleteClientSubscription(ws.ClientID, requestID)
+	subscription.DeleteClientSubscription(clientID, requestID)
 	// 如果没有任何 client 订阅该对象，则从 订阅对象列表中删除
 	classSubscriptions := l.subscriptions[className]
 	if subscription.HasSubscribingClient() == false {
@@ -618,11 +819,39 @@ func (l *liveQueryServer) handleUnsubscribe(ws *server.WebSocket, request t.M, n
 	if len(classSubscriptions) == 0 {
 		delete(l.subscriptions, className)
 	}
-	if notifyClient == false {
-		return
+}
+
+// subscriptionMatch 订阅条件对保存前后对象的匹配结果
+type subscriptionMatch struct {
+	subscription *server.Subscription
+	original     bool
+	current      bool
+}
+
+// matchSubscriptions 检测对象是否符合 className 下各订阅的条件
+// 仅在取出订阅列表时持有 l.mutex ，订阅条件中的子查询在锁外执行，不会阻塞订阅与断开连接
+func (l *liveQueryServer) matchSubscriptions(className string, original, current t.M) []subscriptionMatch {
+	l.mutex.Lock()
+	classSubscriptions := l.subscriptions[className]
+	subscriptions := make([]*server.Subscription, 0, len(classSubscriptions))
+	for _, subscription := range classSubscriptions {
+		subscriptions = append(subscriptions, subscription)
 	}
-	// 退订成功
-	client.PushUnsubscribe(requestID, nil, nil)
+	l.mutex.Unlock()
+	if len(subscriptions) == 0 {
+		utils.TLog.Error("Can not find subscriptions under this class", className)
+		return nil
+	}
+
+	matches := make([]subscriptionMatch, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		matches = append(matches, subscriptionMatch{
+			subscription: subscription,
+			original:     l.matchesSubscription(original, subscription),
+			current:      l.matchesSubscription(current, subscription),
+		})
+	}
+	return matches
 }
 
 // matchesSubscription 检测对象是否符合订阅条件
@@ -634,9 +863,57 @@ func (l *liveQueryServer) matchesSubscription(object t.M, subscription *server.S
 	return utils.MatchesQuery(object, subscription.Query)
 }
 
+// validateCLP 校验 sessionToken 对应的用户对类的 find 或 get 权限
+func (l *liveQueryServer) validateCLP(className string, query t.M, sessionToken string) ([]string, error) {
+	clp, err := server.GetClassLevelPermissions(className)
+	if err != nil {
+		return nil, err
+	}
+	if clp == nil {
+		return nil, nil
+	}
+	aclGroup := []string{}
+	if sessionToken != "" {
+		if userID := l.sessionTokenCache.GetUserID(sessionToken); userID != "" {
+			aclGroup = append(aclGroup, userID)
+			aclGroup = append(aclGroup, server.GetUserRoles(userID)...)
+		}
+	}
+	var where t.M
+	if v, ok := query["where"].(map[string]interface{}); ok {
+		where = v
+	}
+	return server.ValidateCLP(clp, className, server.CLPOperation(where), aclGroup)
+}
+
+// matchesCLP 检测对象是否满足订阅时的 readUserFields 限制
+func (l *liveQueryServer) matchesCLP(object t.M, client *server.Client, requestID int) bool {
+	subscriptionInfo := client.GetSubscriptionInfo(requestID)
+	if subscriptionInfo == nil {
+		return false
+	}
+	if len(subscriptionInfo.ReadUserFields) == 0 {
+		return true
+	}
+	userID := l.sessionTokenCache.GetUserID(subscriptionInfo.SessionToken)
+	return server.MatchesPointerPermission(object, subscriptionInfo.ReadUserFields, userID)
+}
+
+// filterSensitiveData 去除客户端无权查看的敏感字段
+func (l *liveQueryServer) filterSensitiveData(className string, object t.M, client *server.Client, requestID int) t.M {
+	if className != "_User" && className != "_ApiKey" {
+		return object
+	}
+	var userID string
+	if subscriptionInfo := client.GetSubscriptionInfo(requestID); subscriptionInfo != nil && subscriptionInfo.SessionToken != "" {
+		userID = l.sessionTokenCache.GetUserID(subscriptionInfo.SessionToken)
+	}
+	return server.FilterSensitiveData(className, object, userID, client.HasMasterKey)
+}
+
 // matchesACL 检测客户端是否有权限接收消息
 func (l *liveQueryServer) matchesACL(acl t.M, client *server.Client, requestID int) bool {
-	if acl == nil {
+	if acl == nil || client.HasMasterKey {
 		return true
 	}
 
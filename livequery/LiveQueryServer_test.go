@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/JuShangEnergy/framework/livequery/server"
 	tp "github.com/JuShangEnergy/framework/livequery/t"
 )
 
@@ -74,3 +75,76 @@ func Test_getReadAccess(t *testing.T) {
 		}
 	}
 }
+
+func Test_validateSubscription(t *testing.T) {
+	defer func() { server.Embedded = nil }()
+	l := &liveQueryServer{
+		clients:           map[int]*server.Client{},
+		subscriptions:     map[string]map[string]*server.Subscription{},
+		sessionTokenCache: server.NewSessionTokenCache(),
+	}
+	l.clients[1] = server.NewClient(1, nil)
+	locked := []bool{}
+	// 请求 tomato 接口时不持有 l.mutex
+	checkLock := func() {
+		if l.mutex.TryLock() {
+			l.mutex.Unlock()
+			locked = append(locked, false)
+		} else {
+			locked = append(locked, true)
+		}
+	}
+	server.Embedded = &server.EmbeddedResolver{
+		UserForSessionToken: func(sessionToken string) (tp.M, error) {
+			checkLock()
+			return tp.M{"objectId": "1024"}, nil
+		},
+		UserRoles: func(userID string) []string {
+			checkLock()
+			return []string{}
+		},
+		ClassSchema: func(className string) (tp.M, error) {
+			checkLock()
+			return tp.M{
+				"className": className,
+				"classLevelPermissions": map[string]interface{}{
+					"find":           map[string]interface{}{"role:admin": true},
+					"readUserFields": []interface{}{"owner"},
+				},
+			}, nil
+		},
+	}
+	request := tp.M{
+		"op":           "subscribe",
+		"requestId":    float64(1),
+		"sessionToken": "r:abc",
+		"query":        map[string]interface{}{"className": "post", "where": map[string]interface{}{}},
+	}
+	/************************************************************/
+	readUserFields, ok := l.validateSubscription(&server.WebSocket{ClientID: 1}, request)
+	if ok == false || reflect.DeepEqual([]string{"owner"}, readUserFields) == false {
+		t.Error("expect:", []string{"owner"}, "result:", readUserFields, ok)
+	}
+	if reflect.DeepEqual([]bool{false, false, false}, locked) == false {
+		t.Error("expect:", []bool{false, false, false}, "result:", locked)
+	}
+}
+
+func Test_QueueStats(t *testing.T) {
+	defer func() { s = nil }()
+	/************************************************************/
+	// 未启动时返回 0
+	s = nil
+	expect := map[string]int{"clients": 0, "queueDepth": 0, "maxQueueDepth": 0, "dropped": 0}
+	if result := QueueStats(); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	s = &liveQueryServer{clients: map[int]*server.Client{}}
+	s.clients[1] = server.NewClient(1, nil)
+	s.clients[2] = server.NewClient(2, nil)
+	expect = map[string]int{"clients": 2, "queueDepth": 0, "maxQueueDepth": 0, "dropped": 0}
+	if result := QueueStats(); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
@@ -2,6 +2,7 @@ package livequery
 
 import (
 	"strings"
+	"sync/atomic"
 
 	"github.com/JuShangEnergy/framework/config"
 	"github.com/JuShangEnergy/framework/livequery/pubsub"
@@ -11,6 +12,7 @@ import (
 // LiveQuery 接收指定类的对象保存与对象删除的通知，发送对象数据到发布者，由发布者通知订阅者，订阅者实时接收数据
 type LiveQuery struct {
 	classNames         map[string]bool
+	schemaLiveQuery    atomic.Value // func(className string) bool
 	liveQueryPublisher *pubsub.CloudCodePublisher
 }
 
@@ -34,6 +36,9 @@ func NewLiveQuery(classNames []string, pubType, pubURL, pubConfig string) *LiveQ
 	liveQuery.classNames = map[string]bool{}
 	if len(classNames) > 0 {
 		for _, n := range classNames {
+			if n == "" {
+				continue
+			}
 			liveQuery.classNames[n] = true
 		}
 	}
@@ -60,9 +65,33 @@ func (l *LiveQuery) OnAfterDelete(className string, currentObject, originalObjec
 	l.liveQueryPublisher.OnCloudCodeAfterDelete(req)
 }
 
-// HasLiveQuery 是否有对应的 className
+// OnSessionRevoked sessionToken 被删除或过期时调用，通知 LiveQueryServer 不再以该用户身份推送消息
+// sessionToken 为 * 时表示全部 sessionToken 失效
+func (l *LiveQuery) OnSessionRevoked(sessionToken string) {
+	if (len(l.classNames) == 0 && l.getSchemaLiveQuery() == nil) || sessionToken == "" {
+		return
+	}
+	l.liveQueryPublisher.OnSessionRevoked(sessionToken)
+}
+
+// SetSchemaLiveQuery 设置查询类定义中 liveQuery 开关的函数，由 tomato 在配置了 LiveQuery 时设置
+// 在 LiveQueryClasses 之外，类定义中开启了 liveQuery 的类同样会发布消息
+func (l *LiveQuery) SetSchemaLiveQuery(f func(className string) bool) {
+	l.schemaLiveQuery.Store(f)
+}
+
+func (l *LiveQuery) getSchemaLiveQuery() func(className string) bool {
+	f, _ := l.schemaLiveQuery.Load().(func(className string) bool)
+	return f
+}
+
+// HasLiveQuery 是否有对应的 className ，包括配置的类与类定义中开启了 liveQuery 的类
 func (l *LiveQuery) HasLiveQuery(className string) bool {
-	return l.classNames[className]
+	if l.classNames[className] {
+		return true
+	}
+	f := l.getSchemaLiveQuery()
+	return f != nil && f(className)
 }
 
 // makePublisherRequest 组装待发布的消息，格式如下
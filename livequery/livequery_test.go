@@ -0,0 +1,28 @@
+package livequery
+
+import "testing"
+
+func Test_HasLiveQuery(t *testing.T) {
+	l := &LiveQuery{classNames: map[string]bool{"post": true}}
+	/************************************************************/
+	if l.HasLiveQuery("post") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if l.HasLiveQuery("comment") {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	// 类定义中开启了 liveQuery
+	l.SetSchemaLiveQuery(func(className string) bool {
+		return className == "comment"
+	})
+	if l.HasLiveQuery("comment") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if l.HasLiveQuery("post") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if l.HasLiveQuery("user") {
+		t.Error("expect:", false, "result:", true)
+	}
+}
@@ -29,6 +29,16 @@ func (c *CloudCodePublisher) OnCloudCodeAfterDelete(request t.M) {
 	c.onCloudCodeMessage(server.TomatoInfo["appId"]+"afterDelete", request)
 }
 
+// OnSessionRevoked sessionToken 被删除或过期时调用，sessionToken 为 * 时表示全部失效
+// 发送的消息为 JSON 格式： {"sessionToken": "..."}
+func (c *CloudCodePublisher) OnSessionRevoked(sessionToken string) {
+	res, err := json.Marshal(t.M{"sessionToken": sessionToken})
+	if err != nil {
+		return
+	}
+	c.publisher.Publish(server.TomatoInfo["appId"]+"sessionRevoked", string(res))
+}
+
 // onCloudCodeMessage 向发送者发送通知消息
 // 组装之后的 message 为 JSON 格式：
 //
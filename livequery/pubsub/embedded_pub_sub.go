@@ -0,0 +1,157 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/utils"
+)
+
+const (
+	// embeddedQueueSize 嵌入模式下每个订阅者事件通道的长度
+	embeddedQueueSize = 1024
+	// embeddedPublishTimeout 订阅者通道已满时发布者等待的最长时间，超时后该订阅者丢弃消息
+	embeddedPublishTimeout = 100 * time.Millisecond
+)
+
+// embeddedHub 嵌入模式下 tomato 与 LiveQueryServer 在同一进程内，发布者通过 embeddedHub 把事件分发给每个订阅了该通道的订阅者
+type embeddedHub struct {
+	mutex       sync.RWMutex
+	subscribers []*embeddedSubscriber
+}
+
+var embeddedSubscribers = &embeddedHub{}
+
+func (h *embeddedHub) add(s *embeddedSubscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.subscribers = append(h.subscribers, s)
+}
+
+// subscribed 返回订阅了 channel 的订阅者
+func (h *embeddedHub) subscribed(channel string) []*embeddedSubscriber {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	result := []*embeddedSubscriber{}
+	for _, s := range h.subscribers {
+		if s.subscribed(channel) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// embeddedPublisher 嵌入模式的发布者
+type embeddedPublisher struct {
+	hub     *embeddedHub
+	timeout time.Duration
+	dropped uint64
+}
+
+// Publish 把消息写入每个订阅了该通道的订阅者
+// 订阅者通道已满时最多等待 timeout ，仍无法写入时该订阅者丢弃消息，并记录丢弃的总数
+func (p *embeddedPublisher) Publish(channel, message string) {
+	event := [2]string{channel, message}
+	for _, s := range p.hub.subscribed(channel) {
+		if s.enqueue(event, p.timeout) == false {
+			dropped := atomic.AddUint64(&p.dropped, 1)
+			utils.TLog.Error("Embedded event queue is full, drop message of", channel, "total dropped:", dropped)
+		}
+	}
+}
+
+// Dropped 返回因通道已满被丢弃的消息数
+func (p *embeddedPublisher) Dropped() uint64 {
+	return atomic.LoadUint64(&p.dropped)
+}
+
+// embeddedSubscriber 嵌入模式的订阅者，每个订阅者有单独的事件通道
+// 由单个 goroutine 按顺序处理，保证同一对象的事件不会乱序
+type embeddedSubscriber struct {
+	mutex    sync.Mutex
+	once     sync.Once
+	events   chan [2]string
+	channels map[string]bool
+	listener HandlerType
+}
+
+func (s *embeddedSubscriber) Subscribe(channel string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channels[channel] = true
+}
+
+func (s *embeddedSubscriber) Unsubscribe(channel string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.channels, channel)
+}
+
+func (s *embeddedSubscriber) subscribed(channel string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.channels[channel]
+}
+
+// enqueue 写入事件，通道已满时最多等待 timeout ，写入失败时返回 false
+func (s *embeddedSubscriber) enqueue(event [2]string, timeout time.Duration) bool {
+	select {
+	case s.events <- event:
+		return true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case s.events <- event:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// On 仅支持 message 通道，设置监听器后开始处理事件
+func (s *embeddedSubscriber) On(channel string, listener HandlerType) {
+	if channel != "message" {
+		return
+	}
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+	s.once.Do(func() {
+		go s.consume()
+	})
+}
+
+func (s *embeddedSubscriber) consume() {
+	for event := range s.events {
+		s.mutex.Lock()
+		subscribed := s.channels[event[0]]
+		listener := s.listener
+		s.mutex.Unlock()
+		if subscribed && listener != nil {
+			listener(event[0], event[1])
+		}
+	}
+}
+
+func createEmbeddedPublisher() *embeddedPublisher {
+	return &embeddedPublisher{
+		hub:     embeddedSubscribers,
+		timeout: embeddedPublishTimeout,
+	}
+}
+
+func createEmbeddedSubscriber() *embeddedSubscriber {
+	s := newEmbeddedSubscriber()
+	embeddedSubscribers.add(s)
+	return s
+}
+
+func newEmbeddedSubscriber() *embeddedSubscriber {
+	return &embeddedSubscriber{
+		events:   make(chan [2]string, embeddedQueueSize),
+		channels: map[string]bool{},
+	}
+}
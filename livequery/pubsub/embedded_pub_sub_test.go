@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Embedded(t *testing.T) {
+	var mutex sync.Mutex
+	result := []string{}
+	done := make(chan struct{})
+	sub := createEmbeddedSubscriber()
+	pub := createEmbeddedPublisher()
+	sub.Subscribe("afterSave")
+	sub.On("message", func(args ...string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		result = append(result, args[0]+":"+args[1])
+		if len(result) == 100 {
+			close(done)
+		}
+	})
+	/************************************************************/
+	// 按发布顺序接收，未订阅的通道不接收
+	expect := []string{}
+	for i := 0; i < 100; i++ {
+		pub.Publish("afterDelete", strconv.Itoa(i))
+		pub.Publish("afterSave", strconv.Itoa(i))
+		expect = append(expect, "afterSave:"+strconv.Itoa(i))
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+	mutex.Lock()
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	mutex.Unlock()
+	sub.Unsubscribe("afterSave")
+}
+
+func Test_EmbeddedFanOut(t *testing.T) {
+	hub := &embeddedHub{}
+	pub := &embeddedPublisher{hub: hub, timeout: 10 * time.Millisecond}
+	received := make(chan string, 10)
+	push := newEmbeddedSubscriber()
+	live := newEmbeddedSubscriber()
+	hub.add(push)
+	hub.add(live)
+	push.Subscribe("push")
+	live.Subscribe("afterSave")
+	live.Subscribe("push")
+	push.On("message", func(args ...string) {
+		received <- "push:" + args[0] + ":" + args[1]
+	})
+	live.On("message", func(args ...string) {
+		received <- "live:" + args[0] + ":" + args[1]
+	})
+	/************************************************************/
+	// 每个订阅了该通道的订阅者都收到消息，订阅者之间不会争抢消息
+	pub.Publish("afterSave", "1")
+	pub.Publish("push", "2")
+	result := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case r := <-received:
+			result[r] = true
+		case <-time.After(time.Second):
+		}
+	}
+	expect := map[string]bool{"live:afterSave:1": true, "push:push:2": true, "live:push:2": true}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_EmbeddedPublishDrop(t *testing.T) {
+	hub := &embeddedHub{}
+	pub := &embeddedPublisher{hub: hub, timeout: 10 * time.Millisecond}
+	sub := &embeddedSubscriber{
+		events:   make(chan [2]string, 1),
+		channels: map[string]bool{"afterSave": true},
+	}
+	hub.add(sub)
+	/************************************************************/
+	// 订阅者通道已满时等待超时后丢弃，并记录丢弃数
+	pub.Publish("afterSave", "1")
+	pub.Publish("afterSave", "2")
+	if pub.Dropped() != 1 || len(sub.events) != 1 {
+		t.Error("expect:", 1, "result:", pub.Dropped(), len(sub.events))
+	}
+	/************************************************************/
+	// 等待期间通道有空位时写入
+	go func() {
+		time.Sleep(2 * time.Millisecond)
+		<-sub.events
+	}()
+	pub.Publish("afterSave", "3")
+	if pub.Dropped() != 1 || len(sub.events) != 1 {
+		t.Error("expect:", 1, "result:", pub.Dropped(), len(sub.events))
+	}
+	/************************************************************/
+	// 未订阅的通道不写入
+	pub.Publish("afterDelete", "4")
+	if pub.Dropped() != 1 || len(sub.events) != 1 {
+		t.Error("expect:", 1, "result:", pub.Dropped(), len(sub.events))
+	}
+}
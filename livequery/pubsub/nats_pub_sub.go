@@ -0,0 +1,333 @@
+package pubsub
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/utils"
+)
+
+const (
+	// natsDefaultPort 地址中未指定端口时使用的默认端口
+	natsDefaultPort = "4222"
+	// natsDialTimeout 建立连接与握手的超时时间
+	natsDialTimeout = 5 * time.Second
+	// natsReconnectInterval 连接断开后的重连间隔
+	natsReconnectInterval = time.Second
+)
+
+var errNATSClosed = errors.New("nats connection is closed")
+
+// natsOptions 连接 NATS 服务器的配置信息
+type natsOptions struct {
+	address string
+	user    string
+	pass    string
+	token   string
+	group   string // 订阅使用的 queue group ，为空时每个订阅者均接收全部消息
+}
+
+// natsConfig 解析地址与配置信息，地址格式为 nats://127.0.0.1:4222 ，
+// 配置格式为 user=abc&pass=123&token=xyz&group=push
+func natsConfig(address, config string) natsOptions {
+	values := parseConfig(config)
+	address = strings.TrimPrefix(address, "nats://")
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, natsDefaultPort)
+	}
+	return natsOptions{
+		address: address,
+		user:    values.Get("user"),
+		pass:    values.Get("pass"),
+		token:   values.Get("token"),
+		group:   values.Get("group"),
+	}
+}
+
+// natsConn 实现 NATS 文本协议的连接，连接断开后自动重连并恢复订阅
+// 仅实现发布订阅需要的 PUB 、 SUB 、 UNSUB 、 MSG 、 PING 、 PONG 命令，不支持 JetStream
+// 消息最多投递一次：没有订阅者、连接断开或订阅者退出期间的消息均会丢失，需要持久化时使用 RedisStreams
+type natsConn struct {
+	opts      natsOptions
+	mutex     sync.Mutex // 保护 conn 、 subs 与写入
+	conn      net.Conn
+	sid       int
+	subs      map[string]int // 已订阅的通道与 sid
+	listeners []HandlerType
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newNATSConn(opts natsOptions) (*natsConn, error) {
+	c := &natsConn{
+		opts:      opts,
+		subs:      map[string]int{},
+		listeners: []HandlerType{},
+		done:      make(chan struct{}),
+	}
+	conn, r, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	go c.run(conn, r)
+	return c, nil
+}
+
+// connect 建立连接并完成握手：读取 INFO ，发送 CONNECT ，并通过 PING/PONG 确认服务器已接受连接
+func (c *natsConn) connect() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", c.opts.address, natsDialTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(natsDialTimeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if strings.HasPrefix(line, "INFO") == false {
+		conn.Close()
+		return nil, nil, errors.New("nats: expected INFO, got " + strings.TrimSpace(line))
+	}
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "tomato",
+		"lang":     "go",
+		"version":  "1.0.0",
+		"protocol": 1,
+	}
+	if c.opts.user != "" {
+		options["user"] = c.opts.user
+		options["pass"] = c.opts.pass
+	}
+	if c.opts.token != "" {
+		options["auth_token"] = c.opts.token
+	}
+	data, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", data); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	line, err = r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if strings.HasPrefix(line, "PONG") == false {
+		conn.Close()
+		return nil, nil, errors.New("nats: " + strings.TrimSpace(line))
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// run 读取消息，连接断开后按 natsReconnectInterval 重连，并重新发送已有的订阅
+func (c *natsConn) run(conn net.Conn, r *bufio.Reader) {
+	for {
+		err := c.readLoop(conn, r)
+		conn.Close()
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		utils.TLog.Error("NATS connection lost:", err)
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(natsReconnectInterval):
+			}
+			conn, r, err = c.connect()
+			if err != nil {
+				utils.TLog.Error("Reconnect to NATS failed:", err)
+				continue
+			}
+			c.mutex.Lock()
+			c.conn = conn
+			for channel, sid := range c.subs {
+				err = c.writeSub(channel, sid)
+			}
+			c.mutex.Unlock()
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			break
+		}
+	}
+}
+
+// readLoop 处理服务器发送的命令，直到连接断开
+// MSG 格式为： MSG <subject> <sid> [reply-to] <#bytes>\r\n[payload]\r\n
+func (c *natsConn) readLoop(conn net.Conn, r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			args := strings.Fields(line[4:])
+			if len(args) < 3 {
+				return errors.New("nats: invalid MSG " + line)
+			}
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil {
+				return errors.New("nats: invalid MSG " + line)
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			c.mutex.Lock()
+			listeners := c.listeners
+			c.mutex.Unlock()
+			for _, listener := range listeners {
+				listener(args[0], string(payload[:size]))
+			}
+		case line == "PING":
+			if err := c.write(conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			utils.TLog.Error("NATS error:", line)
+		}
+	}
+}
+
+// write 向 conn 写入命令， conn 已被替换时不再写入
+func (c *natsConn) write(conn net.Conn, s string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn != conn {
+		return nil
+	}
+	_, err := io.WriteString(conn, s)
+	return err
+}
+
+// writeSub 发送 SUB 命令，调用方需要持有 c.mutex
+func (c *natsConn) writeSub(channel string, sid int) error {
+	var err error
+	if c.opts.group != "" {
+		_, err = fmt.Fprintf(c.conn, "SUB %s %s %d\r\n", channel, c.opts.group, sid)
+	} else {
+		_, err = fmt.Fprintf(c.conn, "SUB %s %d\r\n", channel, sid)
+	}
+	return err
+}
+
+// publish 发送消息，连接断开期间的消息将被丢弃
+func (c *natsConn) publish(channel, message string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.done:
+		return errNATSClosed
+	default:
+	}
+	_, err := fmt.Fprintf(c.conn, "PUB %s %d\r\n%s\r\n", channel, len(message), message)
+	return err
+}
+
+func (c *natsConn) subscribe(channel string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.subs[channel]; ok {
+		return nil
+	}
+	c.sid++
+	c.subs[channel] = c.sid
+	return c.writeSub(channel, c.sid)
+}
+
+func (c *natsConn) unsubscribe(channel string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sid, ok := c.subs[channel]
+	if ok == false {
+		return nil
+	}
+	delete(c.subs, channel)
+	_, err := fmt.Fprintf(c.conn, "UNSUB %d\r\n", sid)
+	return err
+}
+
+// close 关闭连接，不再重连
+func (c *natsConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.mutex.Lock()
+		c.conn.Close()
+		c.mutex.Unlock()
+	})
+}
+
+// natsPublisher 使用 NATS 实现的发布者
+type natsPublisher struct {
+	conn *natsConn
+}
+
+func (p *natsPublisher) Publish(channel, message string) {
+	if err := p.conn.publish(channel, message); err != nil {
+		utils.TLog.Error("Publish to NATS", channel, "failed:", err)
+	}
+}
+
+// natsSubscriber 使用 NATS 实现的订阅者
+// 配置了 group 时，同一 group 内的订阅者分摊消息，否则每个订阅者均接收全部消息
+// 消息不会被确认与重新投递，处理期间退出的消息会丢失
+type natsSubscriber struct {
+	conn *natsConn
+}
+
+func (s *natsSubscriber) Subscribe(channel string) {
+	if err := s.conn.subscribe(channel); err != nil {
+		utils.TLog.Error("Subscribe NATS", channel, "failed:", err)
+	}
+}
+
+func (s *natsSubscriber) Unsubscribe(channel string) {
+	if err := s.conn.unsubscribe(channel); err != nil {
+		utils.TLog.Error("Unsubscribe NATS", channel, "failed:", err)
+	}
+}
+
+// On 仅支持 message 通道，监听器按消息顺序依次调用
+func (s *natsSubscriber) On(channel string, listener HandlerType) {
+	if channel != "message" {
+		return
+	}
+	s.conn.mutex.Lock()
+	defer s.conn.mutex.Unlock()
+	s.conn.listeners = append(s.conn.listeners, listener)
+}
+
+func createNATSPublisher(address, config string) *natsPublisher {
+	conn, err := newNATSConn(natsConfig(address, config))
+	if err != nil {
+		panic(err)
+	}
+	return &natsPublisher{conn: conn}
+}
+
+func createNATSSubscriber(address, config string) *natsSubscriber {
+	conn, err := newNATSConn(natsConfig(address, config))
+	if err != nil {
+		panic(err)
+	}
+	return &natsSubscriber{conn: conn}
+}
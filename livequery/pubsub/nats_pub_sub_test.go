@@ -0,0 +1,297 @@
+package pubsub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATSServer 进程内的 NATS 服务器，仅实现测试需要的命令
+type fakeNATSServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    map[net.Conn]bool
+	subs     []*fakeNATSSub
+	next     map[string]int // 每个 queue group 下一个接收消息的位置
+}
+
+type fakeNATSSub struct {
+	conn    net.Conn
+	subject string
+	group   string
+	sid     string
+}
+
+func newFakeNATSServer(t *testing.T) *fakeNATSServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATSServer{
+		listener: l,
+		conns:    map[net.Conn]bool{},
+		next:     map[string]int{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.conns[conn] = true
+			s.mutex.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATSServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn) {
+	defer s.remove(conn)
+	io.WriteString(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "PING":
+			s.write(conn, "PONG\r\n")
+		case "SUB":
+			sub := &fakeNATSSub{conn: conn, subject: args[1], sid: args[len(args)-1]}
+			if len(args) == 4 {
+				sub.group = args[2]
+			}
+			s.mutex.Lock()
+			s.subs = append(s.subs, sub)
+			s.mutex.Unlock()
+		case "UNSUB":
+			s.mutex.Lock()
+			for i, sub := range s.subs {
+				if sub.conn == conn && sub.sid == args[1] {
+					s.subs = append(s.subs[:i], s.subs[i+1:]...)
+					break
+				}
+			}
+			s.mutex.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(args[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.deliver(args[1], string(payload[:size]))
+		}
+	}
+}
+
+// deliver 把消息发送给全部普通订阅，每个 queue group 按顺序选择一个订阅
+func (s *fakeNATSServer) deliver(subject, payload string) {
+	s.mutex.Lock()
+	targets := []*fakeNATSSub{}
+	groups := map[string][]*fakeNATSSub{}
+	names := []string{}
+	for _, sub := range s.subs {
+		if sub.subject != subject {
+			continue
+		}
+		if sub.group == "" {
+			targets = append(targets, sub)
+			continue
+		}
+		if _, ok := groups[sub.group]; ok == false {
+			names = append(names, sub.group)
+		}
+		groups[sub.group] = append(groups[sub.group], sub)
+	}
+	for _, name := range names {
+		members := groups[name]
+		targets = append(targets, members[s.next[name]%len(members)])
+		s.next[name]++
+	}
+	s.mutex.Unlock()
+	for _, sub := range targets {
+		s.write(sub.conn, fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", subject, sub.sid, len(payload), payload))
+	}
+}
+
+func (s *fakeNATSServer) write(conn net.Conn, data string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	io.WriteString(conn, data)
+}
+
+func (s *fakeNATSServer) remove(conn net.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removeLocked(conn)
+}
+
+func (s *fakeNATSServer) removeLocked(conn net.Conn) {
+	conn.Close()
+	delete(s.conns, conn)
+	subs := []*fakeNATSSub{}
+	for _, sub := range s.subs {
+		if sub.conn != conn {
+			subs = append(subs, sub)
+		}
+	}
+	s.subs = subs
+}
+
+// dropConnections 断开全部客户端连接
+func (s *fakeNATSServer) dropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.conns {
+		s.removeLocked(conn)
+	}
+}
+
+// wait 等待连接数与订阅数达到指定值
+func (s *fakeNATSServer) wait(conns, subs int) bool {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mutex.Lock()
+		ok := len(s.conns) == conns && len(s.subs) == subs
+		s.mutex.Unlock()
+		if ok {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func (s *fakeNATSServer) close() {
+	s.listener.Close()
+	s.dropConnections()
+}
+
+func Test_NATS(t *testing.T) {
+	server := newFakeNATSServer(t)
+	defer server.close()
+	pub := createNATSPublisher(server.url(), "")
+	defer pub.conn.close()
+	/************************************************************/
+	// 按发布顺序接收，未订阅的通道不接收
+	c1 := &collector{}
+	sub1 := createNATSSubscriber(server.url(), "")
+	defer sub1.conn.close()
+	sub1.Subscribe("afterSave")
+	sub1.On("message", c1.listener)
+	if server.wait(2, 1) == false {
+		t.Fatal("subscribe timeout")
+	}
+	expect := []string{}
+	for i := 0; i < 20; i++ {
+		pub.Publish("afterDelete", strconv.Itoa(i))
+		pub.Publish("afterSave", strconv.Itoa(i))
+		expect = append(expect, "afterSave:"+strconv.Itoa(i))
+	}
+	if result := c1.wait(20); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 同一 group 内的订阅者分摊消息
+	c2 := &collector{}
+	c3 := &collector{}
+	sub2 := createNATSSubscriber(server.url(), "group=push")
+	defer sub2.conn.close()
+	sub2.Subscribe("push")
+	sub2.On("message", c2.listener)
+	sub3 := createNATSSubscriber(server.url(), "group=push")
+	defer sub3.conn.close()
+	sub3.Subscribe("push")
+	sub3.On("message", c3.listener)
+	if server.wait(4, 3) == false {
+		t.Fatal("subscribe timeout")
+	}
+	expect = []string{}
+	for i := 0; i < 20; i++ {
+		pub.Publish("push", strconv.Itoa(i))
+		expect = append(expect, "push:"+strconv.Itoa(i))
+	}
+	result2 := c2.wait(10)
+	result3 := c3.wait(10)
+	if len(result2) != 10 || len(result3) != 10 {
+		t.Error("expect:", 10, 10, "result:", len(result2), len(result3))
+	}
+	result := append(result2, result3...)
+	sort.Strings(result)
+	sort.Strings(expect)
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 取消订阅后不再接收
+	sub3.Unsubscribe("push")
+	if server.wait(4, 2) == false {
+		t.Fatal("unsubscribe timeout")
+	}
+}
+
+func Test_NATSReconnect(t *testing.T) {
+	server := newFakeNATSServer(t)
+	defer server.close()
+	pub := createNATSPublisher(server.url(), "")
+	defer pub.conn.close()
+	c := &collector{}
+	sub := createNATSSubscriber(server.url(), "")
+	defer sub.conn.close()
+	sub.Subscribe("afterSave")
+	sub.On("message", c.listener)
+	if server.wait(2, 1) == false {
+		t.Fatal("subscribe timeout")
+	}
+	/************************************************************/
+	// 连接断开后自动重连并恢复订阅
+	server.dropConnections()
+	if server.wait(2, 1) == false {
+		t.Fatal("reconnect timeout")
+	}
+	pub.Publish("afterSave", "hello")
+	expect := []string{"afterSave:hello"}
+	if result := c.wait(1); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_natsConfig(t *testing.T) {
+	var address, config string
+	var result, expect natsOptions
+	/************************************************************/
+	address = "nats://127.0.0.1"
+	config = ""
+	result = natsConfig(address, config)
+	expect = natsOptions{address: "127.0.0.1:4222"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	address = "127.0.0.1:4223"
+	config = "user=abc&pass=123&group=push"
+	result = natsConfig(address, config)
+	expect = natsOptions{address: "127.0.0.1:4223", user: "abc", pass: "123", group: "push"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
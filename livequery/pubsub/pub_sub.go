@@ -1,27 +1,111 @@
 package pubsub
 
-// CreatePublisher 创建发布者，当前支持 EventEmitter 、 Redis
-func CreatePublisher(pubType, pubURL, pubConfig string) Publisher {
-	if useRedis(pubType) {
-		return createRedisPublisher(pubURL, pubConfig)
+import (
+	"net/url"
+	"sync"
+
+	"github.com/JuShangEnergy/framework/livequery/utils"
+)
+
+// Factory 发布订阅的实现，url 与 config 的格式由具体实现决定
+type Factory struct {
+	CreatePublisher  func(url, config string) Publisher
+	CreateSubscriber func(url, config string) Subscriber
+}
+
+var factories = map[string]Factory{}
+var factoriesMutex sync.RWMutex
+
+// Register 注册发布订阅的实现，pubType 已存在时覆盖原有实现
+// 内置的实现包括 EventEmitter 、 Redis 、 RedisStreams 、 NATS 、 Embedded
+func Register(pubType string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+	factories[pubType] = factory
+}
+
+// Registered 判断 pubType 是否已注册
+func Registered(pubType string) bool {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+	_, ok := factories[pubType]
+	return ok
+}
+
+func getFactory(pubType string) Factory {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+	if factory, ok := factories[pubType]; ok {
+		return factory
+	}
+	if pubType != "" {
+		utils.TLog.Error("Unsupported pubsub type", pubType, "use EventEmitter instead")
 	}
-	return createEventEmitterPublisher()
+	return factories["EventEmitter"]
+}
+
+func init() {
+	Register("EventEmitter", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return createEventEmitterPublisher()
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return createEventEmitterSubscriber()
+		},
+	})
+	Register("Redis", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return createRedisPublisher(url, config)
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return createRedisSubscriber(url, config)
+		},
+	})
+	Register("RedisStreams", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return createRedisStreamsPublisher(url, config)
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return createRedisStreamsSubscriber(url, config)
+		},
+	})
+	Register("NATS", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return createNATSPublisher(url, config)
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return createNATSSubscriber(url, config)
+		},
+	})
+	Register("Embedded", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return createEmbeddedPublisher()
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return createEmbeddedSubscriber()
+		},
+	})
 }
 
-// CreateSubscriber 创建订阅者，当前支持 EventEmitter 、 Redis
+// CreatePublisher 创建发布者，pubType 为空或者未注册时使用 EventEmitter
+func CreatePublisher(pubType, pubURL, pubConfig string) Publisher {
+	return getFactory(pubType).CreatePublisher(pubURL, pubConfig)
+}
+
+// CreateSubscriber 创建订阅者，subType 为空或者未注册时使用 EventEmitter
 func CreateSubscriber(subType, subURL, subConfig string) Subscriber {
-	if useRedis(subType) {
-		return createRedisSubscriber(subURL, subConfig)
-	}
-	return createEventEmitterSubscriber()
+	return getFactory(subType).CreateSubscriber(subURL, subConfig)
 }
 
-// useRedis 判断类型是否为 redis
-func useRedis(pubType string) bool {
-	if pubType == "Redis" {
-		return true
+// parseConfig 解析 key=value 格式的配置信息，多个配置使用 & 隔开，如：
+// password=abc&group=push
+func parseConfig(config string) url.Values {
+	values, err := url.ParseQuery(config)
+	if err != nil {
+		utils.TLog.Error("Invalid pubsub config", config, err)
+		return url.Values{}
 	}
-	return false
+	return values
 }
 
 // HandlerType ...
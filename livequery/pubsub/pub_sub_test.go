@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func Test_Register(t *testing.T) {
+	/************************************************************/
+	// 内置实现均已注册
+	for _, pubType := range []string{"EventEmitter", "Redis", "RedisStreams", "NATS", "Embedded"} {
+		if Registered(pubType) == false {
+			t.Error("expect:", pubType, "registered", "result:", false)
+		}
+	}
+	/************************************************************/
+	// 注册自定义实现
+	client := newMemoryStreams()
+	Register("Memory", Factory{
+		CreatePublisher: func(url, config string) Publisher {
+			return newRedisStreamsPublisher(client, 0)
+		},
+		CreateSubscriber: func(url, config string) Subscriber {
+			return newRedisStreamsSubscriber(client, config, config)
+		},
+	})
+	pub := CreatePublisher("Memory", "", "")
+	sub := CreateSubscriber("Memory", "", "node1")
+	defer sub.(*redisStreamsSubscriber).close()
+	c := &collector{}
+	sub.Subscribe("afterSave")
+	sub.On("message", c.listener)
+	pub.Publish("afterSave", "hello")
+	expect := []string{"afterSave:hello"}
+	if result := c.wait(1); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 未注册的类型使用 EventEmitter
+	if _, ok := CreatePublisher("Unknown", "", "").(*eventEmitterPublisher); ok == false {
+		t.Error("expect:", "*eventEmitterPublisher", "result:", false)
+	}
+}
+
+func Test_parseConfig(t *testing.T) {
+	result := parseConfig("password=abc&group=push")
+	expect := url.Values{"password": []string{"abc"}, "group": []string{"push"}}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	result = parseConfig("%zz")
+	expect = url.Values{}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
@@ -17,14 +17,19 @@ func (r *redisPublisher) Publish(channel, message string) {
 }
 
 func (r *redisPublisher) connectInit() {
+	r.p = NewRedisPool(r.address, r.password)
+}
+
+// NewRedisPool 创建 Redis 连接池， password 不为空时进行认证，推送队列同样使用该方法创建连接池
+func NewRedisPool(address, password string) *redis.Pool {
 	dialFunc := func() (c redis.Conn, err error) {
-		c, err = redis.Dial("tcp", r.address)
+		c, err = redis.Dial("tcp", address)
 		if err != nil {
 			return nil, err
 		}
 
-		if r.password != "" {
-			if _, err := c.Do("AUTH", r.password); err != nil {
+		if password != "" {
+			if _, err := c.Do("AUTH", password); err != nil {
 				c.Close()
 				return nil, err
 			}
@@ -33,7 +38,7 @@ func (r *redisPublisher) connectInit() {
 		return
 	}
 	// initialize a new pool
-	r.p = &redis.Pool{
+	return &redis.Pool{
 		MaxIdle:     3,
 		IdleTimeout: 180 * time.Second,
 		Dial:        dialFunc,
@@ -0,0 +1,399 @@
+package pubsub
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/utils"
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	// redisStreamsMaxLen 每个 stream 保留的最大消息数，超出后删除最旧的消息
+	redisStreamsMaxLen = 10000
+	// redisStreamsReadCount 每次读取的最大消息数
+	redisStreamsReadCount = 10
+	// redisStreamsBlock 读取消息时的最长阻塞时间，新订阅的通道最迟在该时间后生效
+	redisStreamsBlock = time.Second
+	// redisStreamsRetryInterval 读取失败后的重试间隔
+	redisStreamsRetryInterval = time.Second
+	// redisStreamsClaimIdle 未确认消息的最长空闲时间，超过后由其他消费者认领
+	redisStreamsClaimIdle = time.Minute
+)
+
+var errInvalidStreamReply = errors.New("invalid XREADGROUP reply")
+
+// streamMessage stream 中的一条消息， data 为空表示消息已被删除
+type streamMessage struct {
+	stream string
+	id     string
+	data   string
+}
+
+// streamClient Redis Streams 的基本操作，测试时可替换为进程内的实现
+type streamClient interface {
+	// add 添加消息， maxLen 大于 0 时限制 stream 的长度
+	add(stream, data string, maxLen int) error
+	// createGroup 创建消费组，只接收创建之后的消息，消费组已存在时不做处理
+	createGroup(stream, group string) error
+	// readGroup 读取消息， pending 为 true 时读取已投递给该消费者但未确认的消息，否则阻塞读取新消息
+	readGroup(group, consumer string, streams []string, pending bool, count int, block time.Duration) ([]streamMessage, error)
+	// ack 确认消息已处理
+	ack(stream, group, id string) error
+	// claim 把消费组中空闲超过 minIdle 的未确认消息转移给 consumer ，用于接管已下线或更名的消费者的消息
+	claim(stream, group, consumer string, minIdle time.Duration, count int) ([]streamMessage, error)
+}
+
+// redisStreamsPublisher 使用 Redis Streams 实现的发布者
+type redisStreamsPublisher struct {
+	client streamClient
+	maxLen int
+}
+
+func (p *redisStreamsPublisher) Publish(channel, message string) {
+	if err := p.client.add(channel, message, p.maxLen); err != nil {
+		utils.TLog.Error("Publish to redis stream", channel, "failed:", err)
+	}
+}
+
+// redisStreamsSubscriber 使用 Redis Streams 消费组实现的订阅者
+// 消息处理完成后才会确认，断开或重启期间的消息在重新连接后继续投递
+// 同一消费组内的订阅者分摊消息，不同消费组均会收到全部消息
+// 其他消费者空闲超过 claimIdle 的未确认消息会被认领并处理，避免消费者下线或更名后消息无人处理
+type redisStreamsSubscriber struct {
+	client    streamClient
+	group     string
+	consumer  string
+	claimIdle time.Duration
+	lastClaim time.Time
+	mutex     sync.Mutex
+	once      sync.Once
+	channels  map[string]bool
+	pending   bool // 是否需要读取未确认的消息
+	listeners []HandlerType
+	done      chan struct{}
+}
+
+func (s *redisStreamsSubscriber) Subscribe(channel string) {
+	if err := s.client.createGroup(channel, s.group); err != nil {
+		utils.TLog.Error("Create redis stream group", s.group, "on", channel, "failed:", err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channels[channel] = true
+	s.pending = true
+}
+
+func (s *redisStreamsSubscriber) Unsubscribe(channel string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.channels, channel)
+}
+
+// On 仅支持 message 通道，设置监听器后开始接收消息
+// 监听器按消息顺序依次调用，返回后确认消息
+func (s *redisStreamsSubscriber) On(channel string, listener HandlerType) {
+	if channel != "message" {
+		return
+	}
+	s.mutex.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.mutex.Unlock()
+	s.once.Do(func() {
+		go s.receive()
+	})
+}
+
+func (s *redisStreamsSubscriber) receive() {
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		s.mutex.Lock()
+		streams := []string{}
+		for channel := range s.channels {
+			streams = append(streams, channel)
+		}
+		pending := s.pending
+		s.mutex.Unlock()
+		if len(streams) == 0 {
+			time.Sleep(redisStreamsBlock)
+			continue
+		}
+		sort.Strings(streams)
+
+		if time.Since(s.lastClaim) >= s.claimIdle {
+			s.lastClaim = time.Now()
+			for _, stream := range streams {
+				messages, err := s.client.claim(stream, s.group, s.consumer, s.claimIdle, redisStreamsReadCount)
+				if err != nil {
+					utils.TLog.Error("Claim redis stream", stream, "failed:", err)
+					continue
+				}
+				s.handle(messages)
+			}
+		}
+
+		messages, err := s.client.readGroup(s.group, s.consumer, streams, pending, redisStreamsReadCount, redisStreamsBlock)
+		if err != nil {
+			utils.TLog.Error("Read redis streams failed:", err)
+			time.Sleep(redisStreamsRetryInterval)
+			continue
+		}
+		// 未确认的消息处理完后，开始读取新消息
+		if pending && len(messages) == 0 {
+			s.mutex.Lock()
+			s.pending = false
+			s.mutex.Unlock()
+			continue
+		}
+		s.handle(messages)
+	}
+}
+
+// handle 依次调用监听器处理消息，处理后确认消息
+func (s *redisStreamsSubscriber) handle(messages []streamMessage) {
+	for _, m := range messages {
+		if m.data != "" {
+			s.mutex.Lock()
+			listeners := s.listeners
+			s.mutex.Unlock()
+			for _, listener := range listeners {
+				listener(m.stream, m.data)
+			}
+		}
+		if err := s.client.ack(m.stream, s.group, m.id); err != nil {
+			utils.TLog.Error("Ack redis stream message", m.stream, m.id, "failed:", err)
+		}
+	}
+}
+
+// close 停止接收消息
+func (s *redisStreamsSubscriber) close() {
+	close(s.done)
+}
+
+// redisStreamClient 使用 Redis 实现的 streamClient
+type redisStreamClient struct {
+	p *redis.Pool
+}
+
+func (c *redisStreamClient) add(stream, data string, maxLen int) error {
+	conn := c.p.Get()
+	defer conn.Close()
+	args := []interface{}{stream}
+	if maxLen > 0 {
+		args = append(args, "MAXLEN", "~", maxLen)
+	}
+	args = append(args, "*", "message", data)
+	_, err := conn.Do("XADD", args...)
+	return err
+}
+
+func (c *redisStreamClient) createGroup(stream, group string) error {
+	conn := c.p.Get()
+	defer conn.Close()
+	_, err := conn.Do("XGROUP", "CREATE", stream, group, "$", "MKSTREAM")
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (c *redisStreamClient) readGroup(group, consumer string, streams []string, pending bool, count int, block time.Duration) ([]streamMessage, error) {
+	conn := c.p.Get()
+	defer conn.Close()
+	args := []interface{}{"GROUP", group, consumer, "COUNT", count}
+	id := "0"
+	if pending == false {
+		args = append(args, "BLOCK", int(block/time.Millisecond))
+		id = ">"
+	}
+	args = append(args, "STREAMS")
+	for _, stream := range streams {
+		args = append(args, stream)
+	}
+	for range streams {
+		args = append(args, id)
+	}
+	reply, err := redis.Values(conn.Do("XREADGROUP", args...))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseStreamMessages(reply)
+}
+
+func (c *redisStreamClient) ack(stream, group, id string) error {
+	conn := c.p.Get()
+	defer conn.Close()
+	_, err := conn.Do("XACK", stream, group, id)
+	return err
+}
+
+// claim 使用 XAUTOCLAIM 认领消息，返回值格式为： [cursor, [[id, [field, value, ...]], ...], ...]
+// cursor 为 0-0 时表示已遍历全部未确认的消息
+func (c *redisStreamClient) claim(stream, group, consumer string, minIdle time.Duration, count int) ([]streamMessage, error) {
+	conn := c.p.Get()
+	defer conn.Close()
+	messages := []streamMessage{}
+	cursor := "0-0"
+	for {
+		reply, err := redis.Values(conn.Do("XAUTOCLAIM", stream, group, consumer, int(minIdle/time.Millisecond), cursor, "COUNT", count))
+		if err != nil {
+			return nil, err
+		}
+		if len(reply) < 2 {
+			return nil, errInvalidStreamReply
+		}
+		if cursor, err = redis.String(reply[0], nil); err != nil {
+			return nil, err
+		}
+		entries, err := redis.Values(reply[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		claimed, err := parseStreamEntries(stream, entries)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, claimed...)
+		if cursor == "0-0" {
+			return messages, nil
+		}
+	}
+}
+
+// parseStreamMessages 解析 XREADGROUP 的返回值，格式为：
+// [[stream, [[id, [field, value, ...]], ...]], ...]
+func parseStreamMessages(reply []interface{}) ([]streamMessage, error) {
+	messages := []streamMessage{}
+	for _, s := range reply {
+		values, err := redis.Values(s, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, errInvalidStreamReply
+		}
+		stream, err := redis.String(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := redis.Values(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		streamMessages, err := parseStreamEntries(stream, entries)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, streamMessages...)
+	}
+	return messages, nil
+}
+
+// parseStreamEntries 解析 stream 中的消息列表，格式为： [[id, [field, value, ...]], ...]
+func parseStreamEntries(stream string, entries []interface{}) ([]streamMessage, error) {
+	messages := []streamMessage{}
+	for _, e := range entries {
+		// XAUTOCLAIM 中已被删除的消息为 nil ，无法确认，直接跳过
+		if e == nil {
+			continue
+		}
+		entry, err := redis.Values(e, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry) != 2 {
+			return nil, errInvalidStreamReply
+		}
+		id, err := redis.String(entry[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		m := streamMessage{stream: stream, id: id}
+		// 已被删除的消息 fields 为 nil
+		if fields, err := redis.StringMap(entry[1], nil); err == nil {
+			m.data = fields["message"]
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// redisStreamsConfig 解析配置信息，格式为 password=abc&group=tomato&consumer=node1&maxLen=10000
+// group 默认为主机名，即每个节点各自接收全部消息，多个节点需要分摊消息时设置为相同的 group
+// consumer 默认为主机名，重启后使用相同的 consumer 才能继续处理未确认的消息
+func redisStreamsConfig(config string) (password, group, consumer string, maxLen int) {
+	values := parseConfig(config)
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "tomato"
+	}
+	password = values.Get("password")
+	group = values.Get("group")
+	if group == "" {
+		group = hostname
+	}
+	consumer = values.Get("consumer")
+	if consumer == "" {
+		consumer = hostname
+	}
+	maxLen = redisStreamsMaxLen
+	if v, err := strconv.Atoi(values.Get("maxLen")); err == nil {
+		maxLen = v
+	}
+	return
+}
+
+func createRedisStreamsPublisher(address, config string) *redisStreamsPublisher {
+	password, _, _, maxLen := redisStreamsConfig(config)
+	p := NewRedisPool(address, password)
+	c := p.Get()
+	defer c.Close()
+	if c.Err() != nil {
+		panic(c.Err())
+	}
+	return newRedisStreamsPublisher(&redisStreamClient{p: p}, maxLen)
+}
+
+func createRedisStreamsSubscriber(address, config string) *redisStreamsSubscriber {
+	password, group, consumer, _ := redisStreamsConfig(config)
+	p := NewRedisPool(address, password)
+	c := p.Get()
+	defer c.Close()
+	if c.Err() != nil {
+		panic(c.Err())
+	}
+	return newRedisStreamsSubscriber(&redisStreamClient{p: p}, group, consumer)
+}
+
+func newRedisStreamsPublisher(client streamClient, maxLen int) *redisStreamsPublisher {
+	return &redisStreamsPublisher{
+		client: client,
+		maxLen: maxLen,
+	}
+}
+
+func newRedisStreamsSubscriber(client streamClient, group, consumer string) *redisStreamsSubscriber {
+	return &redisStreamsSubscriber{
+		client:    client,
+		group:     group,
+		consumer:  consumer,
+		claimIdle: redisStreamsClaimIdle,
+		channels:  map[string]bool{},
+		listeners: []HandlerType{},
+		done:      make(chan struct{}),
+	}
+}
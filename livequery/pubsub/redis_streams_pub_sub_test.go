@@ -0,0 +1,265 @@
+package pubsub
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStreams 进程内的 streamClient ，用于代替 Redis 测试
+type memoryStreams struct {
+	mutex   sync.Mutex
+	seq     int
+	streams map[string][]streamMessage
+	groups  map[string]*memoryGroup
+}
+
+type memoryGroup struct {
+	next      int                  // 下一条未投递消息的位置
+	pending   map[string]string    // 已投递未确认的消息 id 与消费者
+	delivered map[string]time.Time // 未确认消息的投递时间
+}
+
+func newMemoryStreams() *memoryStreams {
+	return &memoryStreams{
+		streams: map[string][]streamMessage{},
+		groups:  map[string]*memoryGroup{},
+	}
+}
+
+func (m *memoryStreams) add(stream, data string, maxLen int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.seq++
+	m.streams[stream] = append(m.streams[stream], streamMessage{stream: stream, id: strconv.Itoa(m.seq) + "-0", data: data})
+	return nil
+}
+
+func (m *memoryStreams) createGroup(stream, group string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.groups[stream+"|"+group]; ok == false {
+		m.groups[stream+"|"+group] = &memoryGroup{next: len(m.streams[stream]), pending: map[string]string{}, delivered: map[string]time.Time{}}
+	}
+	return nil
+}
+
+func (m *memoryStreams) readGroup(group, consumer string, streams []string, pending bool, count int, block time.Duration) ([]streamMessage, error) {
+	deadline := time.Now().Add(10 * time.Millisecond)
+	for {
+		m.mutex.Lock()
+		messages := []streamMessage{}
+		for _, stream := range streams {
+			g := m.groups[stream+"|"+group]
+			if pending {
+				for _, message := range m.streams[stream] {
+					if g.pending[message.id] == consumer && len(messages) < count {
+						messages = append(messages, message)
+					}
+				}
+				continue
+			}
+			for ; g.next < len(m.streams[stream]) && len(messages) < count; g.next++ {
+				message := m.streams[stream][g.next]
+				g.pending[message.id] = consumer
+				g.delivered[message.id] = time.Now()
+				messages = append(messages, message)
+			}
+		}
+		m.mutex.Unlock()
+		if pending || len(messages) > 0 || time.Now().After(deadline) {
+			return messages, nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (m *memoryStreams) ack(stream, group, id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.groups[stream+"|"+group].pending, id)
+	delete(m.groups[stream+"|"+group].delivered, id)
+	return nil
+}
+
+func (m *memoryStreams) claim(stream, group, consumer string, minIdle time.Duration, count int) ([]streamMessage, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	g := m.groups[stream+"|"+group]
+	messages := []streamMessage{}
+	for _, message := range m.streams[stream] {
+		if _, ok := g.pending[message.id]; ok && time.Since(g.delivered[message.id]) >= minIdle {
+			g.pending[message.id] = consumer
+			g.delivered[message.id] = time.Now()
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (m *memoryStreams) pendingCount(stream, group string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.groups[stream+"|"+group].pending)
+}
+
+// collector 收集订阅者接收到的消息
+type collector struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (c *collector) listener(args ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.messages = append(c.messages, args[0]+":"+args[1])
+}
+
+// wait 等待接收到 n 条消息
+func (c *collector) wait(n int) []string {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mutex.Lock()
+		messages := append([]string{}, c.messages...)
+		c.mutex.Unlock()
+		if len(messages) >= n || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_RedisStreams(t *testing.T) {
+	client := newMemoryStreams()
+	pub := newRedisStreamsPublisher(client, 0)
+	/************************************************************/
+	// 按发布顺序接收，处理后确认消息
+	c1 := &collector{}
+	sub1 := newRedisStreamsSubscriber(client, "node1", "node1")
+	defer sub1.close()
+	sub1.Subscribe("afterSave")
+	sub1.Subscribe("afterDelete")
+	sub1.On("message", c1.listener)
+	expect := []string{}
+	for i := 0; i < 20; i++ {
+		pub.Publish("afterSave", strconv.Itoa(i))
+		expect = append(expect, "afterSave:"+strconv.Itoa(i))
+	}
+	if result := c1.wait(20); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if n := client.pendingCount("afterSave", "node1"); n != 0 {
+		t.Error("expect:", 0, "result:", n)
+	}
+	/************************************************************/
+	// 不同消费组均接收全部消息，同一消费组内分摊消息
+	c2 := &collector{}
+	sub2 := newRedisStreamsSubscriber(client, "node2", "node2")
+	defer sub2.close()
+	sub2.Subscribe("afterDelete")
+	sub2.On("message", c2.listener)
+	c3 := &collector{}
+	sub3 := newRedisStreamsSubscriber(client, "push", "worker1")
+	defer sub3.close()
+	sub3.Subscribe("afterDelete")
+	sub3.On("message", c3.listener)
+	sub4 := newRedisStreamsSubscriber(client, "push", "worker2")
+	defer sub4.close()
+	sub4.Subscribe("afterDelete")
+	sub4.On("message", c3.listener)
+	expect = []string{}
+	for i := 0; i < 20; i++ {
+		pub.Publish("afterDelete", strconv.Itoa(i))
+		expect = append(expect, "afterDelete:"+strconv.Itoa(i))
+	}
+	if result := c1.wait(40); reflect.DeepEqual(expect, result[20:]) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if result := c2.wait(20); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	result := c3.wait(20)
+	sort.Strings(result)
+	sorted := append([]string{}, expect...)
+	sort.Strings(sorted)
+	if reflect.DeepEqual(sorted, result) == false {
+		t.Error("expect:", sorted, "result:", result)
+	}
+}
+
+func Test_RedisStreamsResume(t *testing.T) {
+	client := newMemoryStreams()
+	pub := newRedisStreamsPublisher(client, 0)
+	sub := newRedisStreamsSubscriber(client, "node1", "node1")
+	sub.Subscribe("afterSave")
+	sub.close()
+	/************************************************************/
+	// 已投递但未确认的消息与断开期间的消息，在重新订阅后继续投递
+	pub.Publish("afterSave", "0")
+	client.readGroup("node1", "node1", []string{"afterSave"}, false, 1, 0)
+	pub.Publish("afterSave", "1")
+	pub.Publish("afterSave", "2")
+	c := &collector{}
+	sub = newRedisStreamsSubscriber(client, "node1", "node1")
+	defer sub.close()
+	sub.Subscribe("afterSave")
+	sub.On("message", c.listener)
+	expect := []string{"afterSave:0", "afterSave:1", "afterSave:2"}
+	if result := c.wait(3); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_RedisStreamsClaim(t *testing.T) {
+	client := newMemoryStreams()
+	pub := newRedisStreamsPublisher(client, 0)
+	sub := newRedisStreamsSubscriber(client, "push", "worker1")
+	sub.Subscribe("afterSave")
+	sub.close()
+	/************************************************************/
+	// 已下线的消费者未确认的消息，空闲超过 claimIdle 后由其他消费者认领
+	pub.Publish("afterSave", "0")
+	pub.Publish("afterSave", "1")
+	client.readGroup("push", "worker1", []string{"afterSave"}, false, 2, 0)
+	c := &collector{}
+	sub = newRedisStreamsSubscriber(client, "push", "worker2")
+	sub.claimIdle = 20 * time.Millisecond
+	defer sub.close()
+	sub.Subscribe("afterSave")
+	sub.On("message", c.listener)
+	expect := []string{"afterSave:0", "afterSave:1"}
+	if result := c.wait(2); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if n := client.pendingCount("afterSave", "push"); n != 0 {
+		t.Error("expect:", 0, "result:", n)
+	}
+}
+
+func Test_parseStreamMessages(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			[]byte("afterSave"),
+			[]interface{}{
+				[]interface{}{[]byte("1-0"), []interface{}{[]byte("message"), []byte("hello")}},
+				[]interface{}{[]byte("2-0"), nil},
+			},
+		},
+	}
+	result, err := parseStreamMessages(reply)
+	expect := []streamMessage{
+		{stream: "afterSave", id: "1-0", data: "hello"},
+		{stream: "afterSave", id: "2-0", data: ""},
+	}
+	if err != nil || reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result, err)
+	}
+	/************************************************************/
+	_, err = parseStreamMessages([]interface{}{[]interface{}{[]byte("afterSave")}})
+	if err != errInvalidStreamReply {
+		t.Error("expect:", errInvalidStreamReply, "result:", err)
+	}
+}
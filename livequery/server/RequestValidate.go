@@ -114,6 +114,22 @@ func validateSubscribe(data t.M) error {
 		return errors.New("need query")
 	}
 
+	// watch 字段可选，仅当其中的字段变化时推送 update 事件
+	if v, ok := data["watch"]; ok {
+		if watch, ok := v.([]interface{}); ok {
+			if len(watch) < 1 {
+				return errors.New("watch minItems is not 1")
+			}
+			for _, field := range watch {
+				if _, ok := field.(string); ok == false {
+					return errors.New("watch is not []string")
+				}
+			}
+		} else {
+			return errors.New("watch is not []string")
+		}
+	}
+
 	// sessionToken 字段可选
 	if v, ok := data["sessionToken"]; ok {
 		if _, ok := v.(string); ok == false {
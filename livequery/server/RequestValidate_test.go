@@ -219,6 +219,54 @@ func Test_validateSubscribe(t *testing.T) {
 			}},
 			wantErr: nil,
 		},
+		{
+			name: "8",
+			args: args{data: tp.M{
+				"requestId": 1024.0,
+				"query": map[string]interface{}{
+					"className": "post",
+					"where":     map[string]interface{}{},
+				},
+				"watch": "score",
+			}},
+			wantErr: errors.New("watch is not []string"),
+		},
+		{
+			name: "9",
+			args: args{data: tp.M{
+				"requestId": 1024.0,
+				"query": map[string]interface{}{
+					"className": "post",
+					"where":     map[string]interface{}{},
+				},
+				"watch": []interface{}{},
+			}},
+			wantErr: errors.New("watch minItems is not 1"),
+		},
+		{
+			name: "10",
+			args: args{data: tp.M{
+				"requestId": 1024.0,
+				"query": map[string]interface{}{
+					"className": "post",
+					"where":     map[string]interface{}{},
+				},
+				"watch": []interface{}{"score", 1024},
+			}},
+			wantErr: errors.New("watch is not []string"),
+		},
+		{
+			name: "11",
+			args: args{data: tp.M{
+				"requestId": 1024.0,
+				"query": map[string]interface{}{
+					"className": "post",
+					"where":     map[string]interface{}{},
+				},
+				"watch": []interface{}{"score"},
+			}},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		if err := validateSubscribe(tt.args.data); reflect.DeepEqual(err, tt.wantErr) == false {
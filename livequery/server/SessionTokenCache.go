@@ -1,15 +1,23 @@
 package server
 
 import (
+	"time"
+
 	"github.com/JuShangEnergy/framework/dependencies/lru"
 	"github.com/JuShangEnergy/framework/livequery/utils"
 )
 
-// SessionTokenCache 缓存 SessionToken 及其对应的用户 ID
+// SessionTokenCache 缓存 SessionToken 及其对应的用户 ID 与过期时间
 type SessionTokenCache struct {
 	cache *lru.Cache
 }
 
+// sessionEntry 缓存的 session 信息，过期时间为零值时表示未知
+type sessionEntry struct {
+	userID    string
+	expiresAt time.Time
+}
+
 // NewSessionTokenCache ...
 func NewSessionTokenCache() *SessionTokenCache {
 	return &SessionTokenCache{
@@ -17,24 +25,71 @@ func NewSessionTokenCache() *SessionTokenCache {
 	}
 }
 
-// GetUserID 获取用户 ID
+// GetUserID 获取用户 ID ，sessionToken 已过期时返回空
+// 嵌入模式下直接使用与 tomato 共用的用户缓存，不再缓存到本地
 func (s *SessionTokenCache) GetUserID(sessionToken string) string {
+	if Embedded != nil {
+		user, err := userForSessionToken(sessionToken)
+		if err != nil {
+			utils.TLog.Error("Can not fetch userId for sessionToken", sessionToken, ", error", err.Error())
+			return ""
+		}
+		if v, ok := user["objectId"].(string); ok {
+			return v
+		}
+		return ""
+	}
 	if v, ok := s.cache.Get(sessionToken); ok {
-		utils.TLog.Verbose("Fetch userId", v, "of sessionToken", sessionToken, "from Cache")
-		return v.(string)
+		entry := v.(*sessionEntry)
+		if entry.expired() {
+			utils.TLog.Verbose("SessionToken", sessionToken, "is expired")
+			return ""
+		}
+		utils.TLog.Verbose("Fetch userId", entry.userID, "of sessionToken", sessionToken, "from Cache")
+		return entry.userID
 	}
 
-	user, err := userForSessionToken(sessionToken)
+	session, err := sessionForSessionToken(sessionToken)
 	if err != nil {
 		utils.TLog.Error("Can not fetch userId for sessionToken", sessionToken, ", error", err.Error())
 		return ""
 	}
 
-	utils.TLog.Verbose("Fetch userId", user["objectId"], "of sessionToken", sessionToken, "from Parse")
-	var userID string
-	if v, ok := user["objectId"].(string); ok {
-		userID = v
+	entry := &sessionEntry{}
+	if user, ok := session["user"].(map[string]interface{}); ok {
+		entry.userID, _ = user["objectId"].(string)
+	}
+	if expiresAt, ok := session["expiresAt"].(map[string]interface{}); ok {
+		if iso, ok := expiresAt["iso"].(string); ok {
+			entry.expiresAt, _ = time.Parse("2006-01-02T15:04:05.000Z", iso)
+		}
+	}
+	utils.TLog.Verbose("Fetch userId", entry.userID, "of sessionToken", sessionToken, "from Parse")
+	s.cache.Add(sessionToken, entry)
+	if entry.expired() {
+		return ""
+	}
+	return entry.userID
+}
+
+// IsExpired 判断已缓存的 sessionToken 是否过期，未缓存时返回 false
+func (s *SessionTokenCache) IsExpired(sessionToken string) bool {
+	if v, ok := s.cache.Get(sessionToken); ok {
+		return v.(*sessionEntry).expired()
 	}
-	s.cache.Add(sessionToken, userID)
-	return userID
+	return false
+}
+
+// Remove 删除 sessionToken 的缓存，用于 session 被删除或过期时
+func (s *SessionTokenCache) Remove(sessionToken string) {
+	s.cache.Remove(sessionToken)
+}
+
+// Clear 清空缓存
+func (s *SessionTokenCache) Clear() {
+	s.cache.Clear()
+}
+
+func (e *sessionEntry) expired() bool {
+	return e.expiresAt.IsZero() == false && e.expiresAt.Before(time.Now())
 }
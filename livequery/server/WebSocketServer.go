@@ -1,12 +1,36 @@
 package server
 
 import (
-	"github.com/beego/beego"
-	"golang.org/x/net/websocket"
+	"errors"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/utils"
+	"github.com/beego/beego"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// OverflowDropOldest 发送队列已满时丢弃最旧的消息
+	OverflowDropOldest = "dropOldest"
+	// OverflowDisconnect 发送队列已满时通知客户端重连并断开连接
+	OverflowDisconnect = "disconnect"
 )
 
+// SendQueueSize 每个客户端发送队列的长度
+var SendQueueSize = 100
+
+// WriteTimeout 单条消息的写入超时时间
+var WriteTimeout = 10 * time.Second
+
+// OverflowPolicy 发送队列已满时的处理方式
+var OverflowPolicy = OverflowDropOldest
+
+// queueOverflowError 断开连接前发送给客户端的错误信息
+var queueOverflowError = `{"op":"error","code":1,"error":"Send queue is full","reconnect":true}`
+
 // WebSocketHandler ...
 type WebSocketHandler interface {
 	OnConnect(ws *WebSocket)
@@ -22,8 +46,13 @@ func RunWebSocketServer(pattern, addr string, h WebSocketHandler) {
 	handlerFunc := http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
 			s := websocket.Server{Handler: websocket.Handler(httpHandler)}
-			s.ServeHTTP(w, req)
+			s.ServeHTTP(&idleResponseWriter{ResponseWriter: w, idleTimeout: IdleTimeout}, req)
 		})
+	// handler 支持 SSE 时，同时开启 SSE 接口
+	var sseHandlerFunc http.HandlerFunc
+	if sh, ok := h.(SSEHandler); ok && SSEPattern != "" {
+		sseHandlerFunc = sh.ServeSSE
+	}
 	// 如果未设置监听地址，则与 beego 共用
 	if addr == "" {
 		// http://127.0.0.1:8080/v1 ==>> pattern = /v1
@@ -39,10 +68,16 @@ func RunWebSocketServer(pattern, addr string, h WebSocketHandler) {
 		}
 		pattern = serverURL[i:]
 		beego.Handler(pattern, handlerFunc)
+		if sseHandlerFunc != nil {
+			beego.Handler(SSEPattern, sseHandlerFunc)
+		}
 		return
 	}
 	// 如果设置了地址，则开启新服务去处理 WebSocket
 	http.Handle(pattern, handlerFunc)
+	if sseHandlerFunc != nil {
+		http.Handle(SSEPattern, sseHandlerFunc)
+	}
 	err := http.ListenAndServe(addr, nil)
 	if err != nil {
 		panic("ListenAndServe: " + err.Error())
@@ -50,16 +85,14 @@ func RunWebSocketServer(pattern, addr string, h WebSocketHandler) {
 }
 
 func httpHandler(ws *websocket.Conn) {
-	socket := &WebSocket{
-		ws:       ws,
-		ClientID: 0,
-	}
+	socket := newWebSocket(ws)
 	handler.OnConnect(socket)
 	var v string
 	for {
 		err := socket.receive(&v)
 		if err != nil {
 			handler.OnDisconnect(socket)
+			socket.Close()
 			return
 		}
 		handler.OnMessage(socket, v)
@@ -67,15 +100,167 @@ func httpHandler(ws *websocket.Conn) {
 }
 
 // WebSocket ...
+// 发送的消息先写入有界队列，由单独的 goroutine 按顺序写入连接
 type WebSocket struct {
-	ws       *websocket.Conn
-	ClientID int
+	ws          *websocket.Conn
+	ClientID    int
+	ConnectedAt time.Time // 建立连接的时间
+	queue       chan interface{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	mutex       sync.Mutex // 保证入队与丢弃旧消息的原子性
+	writeMu     sync.Mutex // 保证同一时间只有一个 goroutine 写连接
+	dropped     int
+	encode      func(v interface{}) interface{} // 入队前转换消息，为 nil 时不转换
+	write       func(v interface{}) error
+	close       func() error
+	ping        func() error
+}
+
+// newWebSocket 创建 WebSocket ，并启动写入 goroutine
+func newWebSocket(conn *websocket.Conn) *WebSocket {
+	w := &WebSocket{ws: conn, ConnectedAt: time.Now()}
+	w.write = func(v interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+		return websocket.Message.Send(conn, v)
+	}
+	w.close = conn.Close
+	w.ping = func() error {
+		return ping(conn)
+	}
+	w.start()
+	return w
+}
+
+func (w *WebSocket) start() {
+	w.queue = make(chan interface{}, SendQueueSize)
+	w.done = make(chan struct{})
+	go w.writeLoop()
+}
+
+// writeLoop 依次发送队列中的消息，并按 PingInterval 发送 ping 帧，写入失败或超时时关闭连接
+func (w *WebSocket) writeLoop() {
+	var tick <-chan time.Time
+	if PingInterval > 0 && w.ping != nil {
+		ticker := time.NewTicker(PingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			w.writeMu.Lock()
+			err := w.ping()
+			w.writeMu.Unlock()
+			if err != nil {
+				utils.TLog.Error("Ping client", w.ClientID, "failed:", err)
+				w.Close()
+				return
+			}
+		case v := <-w.queue:
+			// 连接已关闭时不再发送队列中剩余的消息
+			select {
+			case <-w.done:
+				return
+			default:
+			}
+			if _, ok := v.(closeSignal); ok {
+				w.Close()
+				return
+			}
+			w.writeMu.Lock()
+			err := w.write(v)
+			w.writeMu.Unlock()
+			if err != nil {
+				utils.TLog.Error("Write to client", w.ClientID, "failed:", err)
+				w.Close()
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
 }
 
 func (w *WebSocket) receive(v interface{}) error {
 	return websocket.Message.Receive(w.ws, v)
 }
 
+// send 将消息加入发送队列，不会阻塞调用方
+// 队列已满时按 OverflowPolicy 丢弃最旧的消息，或者通知客户端重连并断开连接
 func (w *WebSocket) send(v interface{}) error {
-	return websocket.Message.Send(w.ws, v)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.encode != nil {
+		v = w.encode(v)
+	}
+	for {
+		select {
+		case <-w.done:
+			return errors.New("websocket is closed")
+		default:
+		}
+		select {
+		case w.queue <- v:
+			return nil
+		default:
+		}
+		if OverflowPolicy == OverflowDisconnect {
+			utils.TLog.Error("Send queue of client", w.ClientID, "is full, disconnect")
+			w.closeWithError(queueOverflowError)
+			return errors.New("send queue is full")
+		}
+		select {
+		case <-w.queue:
+			w.dropped++
+			// 首次丢弃及之后每丢弃 100 条记录一次，避免日志过多
+			if w.dropped == 1 || w.dropped%100 == 0 {
+				utils.TLog.Error("Send queue of client", w.ClientID, "is full, dropped:", w.dropped)
+			}
+		default:
+		}
+	}
+}
+
+// closeWithError 停止发送队列，发送错误信息后关闭连接
+func (w *WebSocket) closeWithError(msg string) {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		go func() {
+			w.writeMu.Lock()
+			w.write(msg)
+			w.writeMu.Unlock()
+			w.close()
+		}()
+	})
+}
+
+// closeSignal 加入发送队列后，之前的消息发送完成时关闭连接
+type closeSignal struct{}
+
+// CloseAfterSend 发送队列中已有的消息后关闭连接
+func (w *WebSocket) CloseAfterSend() {
+	if w.send(closeSignal{}) != nil {
+		w.Close()
+	}
+}
+
+// Close 停止发送队列并关闭连接，未发送的消息将被丢弃
+func (w *WebSocket) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.close()
+	})
+}
+
+// QueueDepth 返回发送队列中等待发送的消息数
+func (w *WebSocket) QueueDepth() int {
+	return len(w.queue)
+}
+
+// Dropped 返回因队列已满被丢弃的消息数
+func (w *WebSocket) Dropped() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.dropped
 }
@@ -2,7 +2,10 @@ package server
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_WebSocketServer(t *testing.T) {
@@ -24,3 +27,114 @@ func (h handle) OnMessage(ws *WebSocket, msg interface{}) {
 func (h handle) OnDisconnect(ws *WebSocket) {
 	fmt.Println("OnDisconnect")
 }
+
+func newTestWebSocket(write func(v interface{}) error) *WebSocket {
+	w := &WebSocket{
+		write: write,
+		close: func() error { return nil },
+	}
+	w.start()
+	return w
+}
+
+func Test_send(t *testing.T) {
+	var w *WebSocket
+	var expect []interface{}
+	var result []interface{}
+	var mutex sync.Mutex
+	var block chan struct{}
+	/************************************************************/
+	// 按顺序发送
+	done := make(chan struct{})
+	w = newTestWebSocket(func(v interface{}) error {
+		mutex.Lock()
+		result = append(result, v)
+		if len(result) == 3 {
+			close(done)
+		}
+		mutex.Unlock()
+		return nil
+	})
+	w.send("1")
+	w.send("2")
+	w.send("3")
+	<-done
+	expect = []interface{}{"1", "2", "3"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	w.Close()
+	/************************************************************/
+	// 队列已满时丢弃最旧的消息
+	SendQueueSize = 2
+	OverflowPolicy = OverflowDropOldest
+	result = nil
+	block = make(chan struct{})
+	started := make(chan struct{}, 1)
+	w = newTestWebSocket(func(v interface{}) error {
+		started <- struct{}{}
+		<-block
+		mutex.Lock()
+		result = append(result, v)
+		mutex.Unlock()
+		return nil
+	})
+	w.send("1")
+	<-started
+	w.send("2")
+	w.send("3")
+	w.send("4")
+	if w.QueueDepth() != 2 || w.Dropped() != 1 {
+		t.Error("expect:", 2, 1, "result:", w.QueueDepth(), w.Dropped())
+	}
+	close(block)
+	<-started
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	mutex.Lock()
+	expect = []interface{}{"1", "3", "4"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	mutex.Unlock()
+	w.Close()
+	/************************************************************/
+	// 队列已满时通知客户端重连并断开连接
+	OverflowPolicy = OverflowDisconnect
+	result = nil
+	block = make(chan struct{})
+	closed := make(chan struct{})
+	w = newTestWebSocket(func(v interface{}) error {
+		if v != queueOverflowError {
+			<-block
+		}
+		mutex.Lock()
+		result = append(result, v)
+		mutex.Unlock()
+		return nil
+	})
+	w.close = func() error {
+		close(closed)
+		return nil
+	}
+	w.send("1")
+	time.Sleep(10 * time.Millisecond)
+	w.send("2")
+	w.send("3")
+	if err := w.send("4"); err == nil {
+		t.Error("expect:", "send queue is full", "result:", err)
+	}
+	close(block)
+	<-closed
+	mutex.Lock()
+	expect = []interface{}{"1", queueOverflowError}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	mutex.Unlock()
+	if err := w.send("5"); err == nil {
+		t.Error("expect:", "websocket is closed", "result:", err)
+	}
+	SendQueueSize = 100
+	OverflowPolicy = OverflowDropOldest
+}
@@ -13,9 +13,11 @@ var dafaultFields = []string{"className", "objectId", "updatedAt", "createdAt",
 // Client 客户端信息
 // ws 当前对象的 WebSocket 连接
 // SubscriptionInfos 当前客户端发起的所有请求对应的订阅信息
+// HasMasterKey 连接时是否使用了 masterKey ，使用时不校验 CLP 与 ACL
 type Client struct {
 	id                int
 	ws                *WebSocket
+	HasMasterKey      bool
 	SubscriptionInfos map[int]*SubscriptionInfo
 	PushConnect       func(int, t.M, t.M)
 	PushSubscribe     func(int, t.M, t.M)
@@ -46,7 +48,10 @@ func NewClient(id int, ws *WebSocket) *Client {
 }
 
 func pushResponse(ws *WebSocket, msg string) {
-	go ws.send(msg)
+	if ws == nil {
+		return
+	}
+	ws.send(msg)
 }
 
 // PushError 发送错误信息
@@ -64,6 +69,23 @@ func PushError(ws *WebSocket, code int, errMsg string, reconnect bool) {
 	pushResponse(ws, string(data))
 }
 
+// PushSubscriptionError 向指定订阅发送错误信息
+func (c *Client) PushSubscriptionError(requestID int, code int, errMsg string, reconnect bool) {
+	errResp := t.M{
+		"op":        "error",
+		"clientId":  c.id,
+		"requestId": requestID,
+		"error":     errMsg,
+		"code":      code,
+		"reconnect": reconnect,
+	}
+	data, err := json.Marshal(errResp)
+	if err != nil {
+		return
+	}
+	pushResponse(c.ws, string(data))
+}
+
 // AddSubscriptionInfo 添加 requestID 对应的订阅信息
 func (c *Client) AddSubscriptionInfo(requestID int, subscriptionInfo *SubscriptionInfo) {
 	c.SubscriptionInfos[requestID] = subscriptionInfo
@@ -74,6 +96,22 @@ func (c *Client) GetSubscriptionInfo(requestID int) *SubscriptionInfo {
 	return c.SubscriptionInfos[requestID]
 }
 
+// QueueDepth 返回客户端发送队列中等待发送的消息数
+func (c *Client) QueueDepth() int {
+	if c.ws == nil {
+		return 0
+	}
+	return c.ws.QueueDepth()
+}
+
+// Dropped 返回客户端因发送队列已满被丢弃的消息数
+func (c *Client) Dropped() int {
+	if c.ws == nil {
+		return 0
+	}
+	return c.ws.Dropped()
+}
+
 // DeleteSubscriptionInfo 删除 requestID 对应的订阅信息
 func (c *Client) DeleteSubscriptionInfo(requestID int) {
 	delete(c.SubscriptionInfos, requestID)
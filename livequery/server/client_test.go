@@ -245,3 +245,10 @@ func Test_transformUpdateOperators(t *testing.T) {
 		}
 	}
 }
+
+func Test_ClientQueueDepthWithoutConnection(t *testing.T) {
+	c := NewClient(1, nil)
+	if c.QueueDepth() != 0 || c.Dropped() != 0 {
+		t.Error("expect:", 0, 0, "result:", c.QueueDepth(), c.Dropped())
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// PingInterval 服务端发送 ping 帧的间隔，为 0 时不发送
+var PingInterval = 30 * time.Second
+
+// IdleTimeout 连接空闲超时时间，超过该时间未收到客户端任何数据（包括 pong 帧）时断开连接，为 0 时不检测
+var IdleTimeout = 90 * time.Second
+
+// idleConn 每次读取前刷新读超时时间，客户端在 IdleTimeout 内没有发送任何数据时读取失败
+// pong 帧由 websocket 库内部处理，无法在上层感知，因此在连接层面检测
+type idleConn struct {
+	net.Conn
+	r           io.Reader
+	idleTimeout time.Duration
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	if c.idleTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	}
+	return c.r.Read(p)
+}
+
+// idleResponseWriter 在 websocket 握手接管连接时替换为 idleConn
+type idleResponseWriter struct {
+	http.ResponseWriter
+	idleTimeout time.Duration
+}
+
+func (w *idleResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if ok == false {
+		return nil, nil, errors.New("webserver doesn't support hijacking")
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// 接管前已缓存的数据需要先读出
+	c := &idleConn{Conn: conn, r: buf.Reader, idleTimeout: w.idleTimeout}
+	return c, bufio.NewReadWriter(bufio.NewReader(c), buf.Writer), nil
+}
+
+// ping 发送 ping 帧，客户端收到后会自动回复 pong 帧
+func ping(conn *websocket.Conn) error {
+	conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	payloadType := conn.PayloadType
+	conn.PayloadType = websocket.PingFrame
+	_, err := conn.Write([]byte{})
+	conn.PayloadType = payloadType
+	return err
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_idleConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	c := &idleConn{Conn: server, r: server, idleTimeout: 50 * time.Millisecond}
+	buf := make([]byte, 8)
+	/************************************************************/
+	// 超时前收到数据时读取成功
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.Write([]byte("pong"))
+	}()
+	n, err := c.Read(buf)
+	if err != nil || string(buf[:n]) != "pong" {
+		t.Error("expect:", "pong", "result:", string(buf[:n]), err)
+	}
+	/************************************************************/
+	// 超时未收到数据时读取失败
+	start := time.Now()
+	_, err = c.Read(buf)
+	if ne, ok := err.(net.Error); ok == false || ne.Timeout() == false {
+		t.Error("expect:", "timeout", "result:", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expect:", "timeout after 50ms", "result:", time.Since(start))
+	}
+}
+
+func Test_writeLoopPing(t *testing.T) {
+	PingInterval = 10 * time.Millisecond
+	defer func() { PingInterval = 30 * time.Second }()
+	/************************************************************/
+	pinged := make(chan struct{}, 10)
+	w := &WebSocket{
+		write: func(v interface{}) error { return nil },
+		close: func() error { return nil },
+		ping: func() error {
+			pinged <- struct{}{}
+			return nil
+		},
+	}
+	w.start()
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Error("expect:", "ping", "result:", "no ping")
+	}
+	w.Close()
+	/************************************************************/
+	// ping 失败时关闭连接
+	closed := make(chan struct{})
+	w = &WebSocket{
+		write: func(v interface{}) error { return nil },
+		close: func() error {
+			close(closed)
+			return nil
+		},
+		ping: func() error { return net.ErrWriteToConnected },
+	}
+	w.start()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("expect:", "closed", "result:", "not closed")
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
-
 	"net/url"
+	"time"
 
 	"github.com/JuShangEnergy/framework/livequery/t"
 )
@@ -14,8 +14,37 @@ import (
 // TomatoInfo ...
 var TomatoInfo = map[string]string{}
 
+// httpClient 请求 tomato 接口使用的客户端，设置超时时间，避免接口无响应时订阅与鉴权一直等待
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// EmbeddedResolver 嵌入模式下直接在进程内查询用户与角色，不再请求 tomato 接口
+type EmbeddedResolver struct {
+	UserForSessionToken func(sessionToken string) (t.M, error)
+	UserRoles           func(userID string) []string
+	ClassSchema         func(className string) (t.M, error)
+	Find                func(className string, where, options t.M) ([]t.M, error)
+}
+
+// Embedded 嵌入模式下的查询函数，由 tomato 在启动 LiveQuery 时设置，为 nil 时通过 HTTP 请求查询
+var Embedded *EmbeddedResolver
+
 // userForSessionToken 访问接口 获取用户信息
 func userForSessionToken(sessionToken string) (t.M, error) {
+	if Embedded != nil {
+		return Embedded.UserForSessionToken(sessionToken)
+	}
+	session, err := sessionForSessionToken(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	if user, ok := session["user"].(map[string]interface{}); ok && user != nil {
+		return user, nil
+	}
+	return t.M{}, nil
+}
+
+// sessionForSessionToken 访问接口 获取 sessionToken 对应的 _Session 对象，包含用户与过期时间
+func sessionForSessionToken(sessionToken string) (t.M, error) {
 	// TODO 后续使用 go SDK 实现
 	where := url.QueryEscape(`{"sessionToken":"` + sessionToken + `"}`)
 	req, err := http.NewRequest("GET", TomatoInfo["serverURL"]+"/classes/_Session"+"?where="+where, nil)
@@ -26,7 +55,7 @@ func userForSessionToken(sessionToken string) (t.M, error) {
 	req.Header.Add("X-Parse-Application-Id", TomatoInfo["appId"])
 	req.Header.Add("X-Parse-Master-Key", TomatoInfo["masterKey"])
 
-	client := http.DefaultClient
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -46,9 +75,7 @@ func userForSessionToken(sessionToken string) (t.M, error) {
 	if results, ok := response["results"].([]interface{}); ok {
 		for _, result := range results {
 			if session, ok := result.(map[string]interface{}); ok {
-				if user, ok := session["user"].(map[string]interface{}); ok && user != nil {
-					return user, nil
-				}
+				return session, nil
 			}
 		}
 	}
@@ -56,8 +83,136 @@ func userForSessionToken(sessionToken string) (t.M, error) {
 	return t.M{}, nil
 }
 
+// GetClassLevelPermissions 获取类的 CLP ，类不存在时返回 nil
+func GetClassLevelPermissions(className string) (t.M, error) {
+	schema, err := GetClassSchema(className)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	if clp, ok := schema["classLevelPermissions"].(map[string]interface{}); ok {
+		return clp, nil
+	}
+	return nil, nil
+}
+
+// GetClassSchema 获取类定义，类不存在时返回 nil
+func GetClassSchema(className string) (t.M, error) {
+	if Embedded != nil {
+		return Embedded.ClassSchema(className)
+	}
+	req, err := http.NewRequest("GET", TomatoInfo["serverURL"]+"/schemas/"+url.PathEscape(className), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("X-Parse-Application-Id", TomatoInfo["appId"])
+	req.Header.Add("X-Parse-Master-Key", TomatoInfo["masterKey"])
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response t.M
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, errors.New("Can not fetch schema of class " + className)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// 类不存在
+		if code, ok := response["code"].(float64); ok && code == 103 {
+			return nil, nil
+		}
+		return nil, errors.New("Can not fetch schema of class " + className)
+	}
+	return response, nil
+}
+
+// FindObjects 使用 masterKey 查询对象，用于匹配订阅条件中的子查询，如 $inQuery $select
+// options 为 limit skip keys order 等查询选项
+func FindObjects(className string, where, options t.M) ([]t.M, error) {
+	if Embedded != nil {
+		return Embedded.Find(className, where, options)
+	}
+	w, err := json.Marshal(where)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("where", string(w))
+	for k, v := range options {
+		if s, ok := v.(string); ok {
+			params.Set(k, s)
+			continue
+		}
+		o, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(k, string(o))
+	}
+	req, err := http.NewRequest("GET", TomatoInfo["serverURL"]+"/classes/"+url.PathEscape(className)+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("X-Parse-Application-Id", TomatoInfo["appId"])
+	req.Header.Add("X-Parse-Master-Key", TomatoInfo["masterKey"])
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response t.M
+	err = json.Unmarshal(body, &response)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Can not find objects of class " + className)
+	}
+	r := []t.M{}
+	if results, ok := response["results"].([]interface{}); ok {
+		for _, result := range results {
+			if object, ok := result.(map[string]interface{}); ok {
+				r = append(r, object)
+			}
+		}
+	}
+	return r, nil
+}
+
+// LiveQueryClasses 配置的开启 LiveQuery 的类，为 nil 时不校验订阅的类
+var LiveQueryClasses map[string]bool
+
+// LiveQueryEnabled 类是否开启了 LiveQuery ，配置在 LiveQueryClasses 中或类定义中 liveQuery 为 true
+func LiveQueryEnabled(className string) (bool, error) {
+	if LiveQueryClasses == nil || LiveQueryClasses[className] {
+		return true, nil
+	}
+	schema, err := GetClassSchema(className)
+	if err != nil || schema == nil {
+		return false, err
+	}
+	liveQuery, _ := schema["liveQuery"].(bool)
+	return liveQuery, nil
+}
+
 // GetUserRoles 获取用户对应的角色列表
 func GetUserRoles(userID string) []string {
+	if Embedded != nil {
+		return Embedded.UserRoles(userID)
+	}
 	p := url.QueryEscape(`{"users":{"__type":"Pointer","className":"User","objectId":"` + userID + `"}}`)
 	req, err := http.NewRequest("GET", TomatoInfo["serverURL"]+"/roles?where="+p, nil)
 	if err != nil {
@@ -67,7 +222,7 @@ func GetUserRoles(userID string) []string {
 	req.Header.Add("X-Parse-Application-Id", TomatoInfo["appId"])
 	req.Header.Add("X-Parse-Master-Key", TomatoInfo["masterKey"])
 
-	client := http.DefaultClient
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return []string{}
@@ -114,7 +269,7 @@ func GetRelatedRoles(roleID string) []string {
 	req.Header.Add("X-Parse-Application-Id", TomatoInfo["appId"])
 	req.Header.Add("X-Parse-Master-Key", TomatoInfo["masterKey"])
 
-	client := http.DefaultClient
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return []string{}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+
+	tp "github.com/JuShangEnergy/framework/livequery/t"
 )
 
 func Test_userForSessionToken(t *testing.T) {
@@ -25,3 +27,38 @@ func Test_userForSessionToken(t *testing.T) {
 		t.Error("expect:", "57d7c2013cdd0164775cea4f", "result:", user["objectId"])
 	}
 }
+
+func Test_LiveQueryEnabled(t *testing.T) {
+	defer func() {
+		Embedded = nil
+		LiveQueryClasses = nil
+	}()
+	Embedded = &EmbeddedResolver{
+		ClassSchema: func(className string) (tp.M, error) {
+			switch className {
+			case "comment":
+				return tp.M{"className": className, "liveQuery": true}, nil
+			case "user":
+				return tp.M{"className": className}, nil
+			}
+			return nil, nil
+		},
+	}
+	var enabled bool
+	var err error
+	/************************************************************/
+	// 未配置类列表时不校验
+	LiveQueryClasses = nil
+	enabled, err = LiveQueryEnabled("user")
+	if enabled == false || err != nil {
+		t.Error("expect:", true, "result:", enabled, err)
+	}
+	/************************************************************/
+	LiveQueryClasses = map[string]bool{"post": true}
+	for className, expect := range map[string]bool{"post": true, "comment": true, "user": false, "other": false} {
+		enabled, err = LiveQueryEnabled(className)
+		if enabled != expect || err != nil {
+			t.Error("expect:", expect, "result:", enabled, err, className)
+		}
+	}
+}
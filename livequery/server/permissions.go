@@ -0,0 +1,142 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/JuShangEnergy/framework/livequery/t"
+)
+
+// UserSensitiveFields _User 中仅本人与 masterKey 可见的字段，与 tomato 的 UserSensitiveFields 配置相同
+var UserSensitiveFields = []string{"email"}
+
+// userHiddenFields _User 中不对外返回的内部字段
+var userHiddenFields = []string{
+	"password",
+	"_hashed_password",
+	"sessionToken",
+	"_email_verify_token",
+	"_perishable_token",
+	"_perishable_token_expires_at",
+	"_tombstone",
+	"_email_verify_token_expires_at",
+	"_failed_login_count",
+	"_account_lockout_expires_at",
+	"_password_changed_at",
+	"_password_history",
+}
+
+// CLPOperation 返回订阅需要校验的类操作权限，订阅条件仅包含 objectId 时为 get ，否则为 find
+func CLPOperation(where t.M) string {
+	if len(where) == 1 {
+		if _, ok := where["objectId"].(string); ok {
+			return "get"
+		}
+	}
+	return "find"
+}
+
+// ValidateCLP 校验用户对类的操作权限，规则与 orm 中 Schema.validatePermission 相同
+// aclGroup 为用户 ID 与所属角色，未登录时为空
+// 仅通过 readUserFields 授权时允许订阅，返回的字段用于在推送时校验对象是否指向当前用户
+func ValidateCLP(clp t.M, className, operation string, aclGroup []string) ([]string, error) {
+	if clp == nil {
+		return nil, nil
+	}
+	perms, ok := clp[operation].(map[string]interface{})
+	if ok == false || perms == nil {
+		return nil, nil
+	}
+	if _, ok := perms["*"]; ok {
+		return nil, nil
+	}
+	for _, v := range aclGroup {
+		if _, ok := perms[v]; ok {
+			return nil, nil
+		}
+	}
+
+	if perms["requiresAuthentication"] != nil {
+		if len(aclGroup) == 0 {
+			return nil, errors.New("Permission denied, user needs to be authenticated.")
+		}
+		return nil, nil
+	}
+
+	if len(aclGroup) > 0 {
+		if fields, ok := clp["readUserFields"].([]interface{}); ok && len(fields) > 0 {
+			readUserFields := []string{}
+			for _, field := range fields {
+				if f, ok := field.(string); ok {
+					readUserFields = append(readUserFields, f)
+				}
+			}
+			return readUserFields, nil
+		}
+	}
+
+	return nil, errors.New("Permission denied for action " + operation + " on class " + className + ".")
+}
+
+// MatchesPointerPermission 检测对象中是否有 readUserFields 指向当前用户，字段可以是用户指针或者用户指针数组
+func MatchesPointerPermission(object t.M, readUserFields []string, userID string) bool {
+	if len(readUserFields) == 0 {
+		return true
+	}
+	if object == nil || userID == "" {
+		return false
+	}
+	for _, field := range readUserFields {
+		switch v := object[field].(type) {
+		case map[string]interface{}:
+			if isUserPointer(v, userID) {
+				return true
+			}
+		case []interface{}:
+			for _, p := range v {
+				if pointer, ok := p.(map[string]interface{}); ok && isUserPointer(pointer, userID) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isUserPointer(pointer map[string]interface{}, userID string) bool {
+	id, _ := pointer["objectId"].(string)
+	return id == userID
+}
+
+// FilterSensitiveData 返回去除敏感字段后的对象副本，规则与 orm 中 filterSensitiveData 相同
+// _User 的内部字段不推送， UserSensitiveFields 与 authData 仅推送给用户本人
+func FilterSensitiveData(className string, object t.M, userID string, isMaster bool) t.M {
+	if object == nil {
+		return nil
+	}
+	if className != "_User" && className != "_ApiKey" {
+		return object
+	}
+	result := t.M{}
+	for k, v := range object {
+		result[k] = v
+	}
+	if className == "_ApiKey" {
+		delete(result, "keyHash")
+		return result
+	}
+
+	for _, field := range userHiddenFields {
+		delete(result, field)
+	}
+	if isMaster {
+		return result
+	}
+	if id, _ := object["objectId"].(string); id != "" && id == userID {
+		return result
+	}
+	delete(result, "authData")
+	for _, field := range UserSensitiveFields {
+		delete(result, field)
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	tp "github.com/JuShangEnergy/framework/livequery/t"
+)
+
+func Test_CLPOperation(t *testing.T) {
+	if op := CLPOperation(tp.M{"objectId": "1024"}); op != "get" {
+		t.Error("expect:", "get", "result:", op)
+	}
+	if op := CLPOperation(tp.M{"objectId": "1024", "name": "joe"}); op != "find" {
+		t.Error("expect:", "find", "result:", op)
+	}
+	if op := CLPOperation(tp.M{"objectId": map[string]interface{}{"$in": []interface{}{"1024"}}}); op != "find" {
+		t.Error("expect:", "find", "result:", op)
+	}
+	if op := CLPOperation(nil); op != "find" {
+		t.Error("expect:", "find", "result:", op)
+	}
+}
+
+func Test_ValidateCLP(t *testing.T) {
+	var clp tp.M
+	var fields []string
+	var err error
+	/************************************************************/
+	fields, err = ValidateCLP(nil, "post", "find", nil)
+	if fields != nil || err != nil {
+		t.Error("expect:", nil, "result:", fields, err)
+	}
+	/************************************************************/
+	clp = tp.M{"find": map[string]interface{}{"*": true}}
+	fields, err = ValidateCLP(clp, "post", "find", nil)
+	if fields != nil || err != nil {
+		t.Error("expect:", nil, "result:", fields, err)
+	}
+	/************************************************************/
+	clp = tp.M{"find": map[string]interface{}{"role:admin": true}}
+	fields, err = ValidateCLP(clp, "post", "find", []string{"1024", "role:admin"})
+	if fields != nil || err != nil {
+		t.Error("expect:", nil, "result:", fields, err)
+	}
+	_, err = ValidateCLP(clp, "post", "find", []string{"1024"})
+	if err == nil || err.Error() != "Permission denied for action find on class post." {
+		t.Error("expect:", "Permission denied for action find on class post.", "result:", err)
+	}
+	/************************************************************/
+	clp = tp.M{"get": map[string]interface{}{"requiresAuthentication": true}}
+	_, err = ValidateCLP(clp, "post", "get", []string{})
+	if err == nil || err.Error() != "Permission denied, user needs to be authenticated." {
+		t.Error("expect:", "Permission denied, user needs to be authenticated.", "result:", err)
+	}
+	fields, err = ValidateCLP(clp, "post", "get", []string{"1024"})
+	if fields != nil || err != nil {
+		t.Error("expect:", nil, "result:", fields, err)
+	}
+	/************************************************************/
+	clp = tp.M{
+		"find":           map[string]interface{}{},
+		"readUserFields": []interface{}{"owner", "members"},
+	}
+	fields, err = ValidateCLP(clp, "post", "find", []string{"1024"})
+	if reflect.DeepEqual([]string{"owner", "members"}, fields) == false || err != nil {
+		t.Error("expect:", []string{"owner", "members"}, "result:", fields, err)
+	}
+	_, err = ValidateCLP(clp, "post", "find", []string{})
+	if err == nil {
+		t.Error("expect:", "Permission denied", "result:", err)
+	}
+}
+
+func Test_MatchesPointerPermission(t *testing.T) {
+	object := tp.M{
+		"owner": map[string]interface{}{"__type": "Pointer", "className": "_User", "objectId": "1024"},
+		"members": []interface{}{
+			map[string]interface{}{"__type": "Pointer", "className": "_User", "objectId": "2048"},
+		},
+	}
+	if MatchesPointerPermission(object, nil, "") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if MatchesPointerPermission(object, []string{"owner"}, "1024") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if MatchesPointerPermission(object, []string{"owner", "members"}, "2048") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if MatchesPointerPermission(object, []string{"owner"}, "2048") {
+		t.Error("expect:", false, "result:", true)
+	}
+	if MatchesPointerPermission(object, []string{"owner"}, "") {
+		t.Error("expect:", false, "result:", true)
+	}
+}
+
+func Test_FilterSensitiveData(t *testing.T) {
+	var object tp.M
+	var result tp.M
+	var expect tp.M
+	/************************************************************/
+	object = tp.M{"objectId": "1", "title": "hello"}
+	result = FilterSensitiveData("post", object, "", false)
+	if reflect.DeepEqual(object, result) == false {
+		t.Error("expect:", object, "result:", result)
+	}
+	/************************************************************/
+	object = tp.M{
+		"objectId":            "1024",
+		"username":            "joe",
+		"email":               "joe@example.com",
+		"authData":            map[string]interface{}{"weixin": map[string]interface{}{"id": "1"}},
+		"_hashed_password":    "hash",
+		"_email_verify_token": "token",
+	}
+	result = FilterSensitiveData("_User", object, "2048", false)
+	expect = tp.M{"objectId": "1024", "username": "joe"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if _, ok := object["email"]; ok == false {
+		t.Error("expect:", "original object unchanged", "result:", object)
+	}
+	/************************************************************/
+	result = FilterSensitiveData("_User", object, "1024", false)
+	expect = tp.M{
+		"objectId": "1024",
+		"username": "joe",
+		"email":    "joe@example.com",
+		"authData": map[string]interface{}{"weixin": map[string]interface{}{"id": "1"}},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	result = FilterSensitiveData("_User", object, "", true)
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	object = tp.M{"objectId": "1", "name": "ci", "keyHash": "hash"}
+	result = FilterSensitiveData("_ApiKey", object, "", true)
+	expect = tp.M{"objectId": "1", "name": "ci"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
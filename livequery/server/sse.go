@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEPattern SSE 接口路径，为空时不开启 SSE
+var SSEPattern = "/live"
+
+// SSEHandler 处理 SSE 请求，由 liveQueryServer 实现
+type SSEHandler interface {
+	ServeSSE(w http.ResponseWriter, r *http.Request)
+}
+
+// sseEvent SSE 连接中的一条消息， id 为 0 时不发送 id 字段
+type sseEvent struct {
+	id    int64
+	event string
+	data  string
+}
+
+// eventOps 需要携带事件 ID 的消息类型，客户端重连时可通过 Last-Event-ID 从这些消息之后继续接收
+var eventOps = map[string]bool{
+	"create": true,
+	"enter":  true,
+	"update": true,
+	"leave":  true,
+	"delete": true,
+}
+
+// newSSEEvent 把发送给客户端的 JSON 消息转换为 SSE 消息，消息的 op 作为事件名
+func newSSEEvent(data string, eventID int64) sseEvent {
+	var message struct {
+		Op string `json:"op"`
+	}
+	json.Unmarshal([]byte(data), &message)
+	e := sseEvent{event: message.Op, data: data}
+	if eventOps[message.Op] {
+		e.id = eventID
+	}
+	return e
+}
+
+// String 按 text/event-stream 格式输出
+func (e sseEvent) String() string {
+	var b strings.Builder
+	if e.id > 0 {
+		fmt.Fprintf(&b, "id: %d\n", e.id)
+	}
+	if e.event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.event)
+	}
+	for _, line := range strings.Split(e.data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// NewSSEWebSocket 创建基于 SSE 的连接，与 WebSocket 共用发送队列、溢出处理与心跳
+// eventID 返回当前推送的事件 ID ，在消息入队时调用
+// 返回的 channel 在连接关闭后关闭，此后不会再写入 w ，调用方需要等待其关闭后再结束请求
+func NewSSEWebSocket(w http.ResponseWriter, eventID func() int64) (*WebSocket, <-chan struct{}, error) {
+	flusher, ok := w.(http.Flusher)
+	if ok == false {
+		return nil, nil, errors.New("Streaming is not supported")
+	}
+	rc := http.NewResponseController(w)
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	socket := &WebSocket{ConnectedAt: time.Now()}
+	closed := make(chan struct{})
+	var closeOnce sync.Once
+	isClosed := false
+	// write 与 ping 均在持有 writeMu 时调用
+	writeString := func(s string) error {
+		if isClosed {
+			return errors.New("sse is closed")
+		}
+		rc.SetWriteDeadline(time.Now().Add(WriteTimeout))
+		if _, err := fmt.Fprint(w, s); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	socket.encode = func(v interface{}) interface{} {
+		if data, ok := v.(string); ok {
+			return newSSEEvent(data, eventID())
+		}
+		return v
+	}
+	socket.write = func(v interface{}) error {
+		switch e := v.(type) {
+		case sseEvent:
+			return writeString(e.String())
+		case string:
+			return writeString(newSSEEvent(e, 0).String())
+		}
+		return nil
+	}
+	socket.ping = func() error {
+		return writeString(": ping\n\n")
+	}
+	socket.close = func() error {
+		closeOnce.Do(func() {
+			socket.writeMu.Lock()
+			isClosed = true
+			socket.writeMu.Unlock()
+			close(closed)
+		})
+		return nil
+	}
+	socket.start()
+	return socket, closed, nil
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// sseRecorder 把写入的内容发送到 channel ，便于按顺序读取
+type sseRecorder struct {
+	header http.Header
+	status int
+	writes chan string
+}
+
+func (r *sseRecorder) Header() http.Header         { return r.header }
+func (r *sseRecorder) WriteHeader(status int)      { r.status = status }
+func (r *sseRecorder) Flush()                      {}
+func (r *sseRecorder) Write(p []byte) (int, error) { r.writes <- string(p); return len(p), nil }
+
+func Test_sseEvent(t *testing.T) {
+	var e sseEvent
+	var expect string
+	/************************************************************/
+	e = newSSEEvent(`{"op":"create","requestId":1}`, 1024)
+	expect = "id: 1024\nevent: create\ndata: {\"op\":\"create\",\"requestId\":1}\n\n"
+	if e.String() != expect {
+		t.Error("expect:", expect, "result:", e.String())
+	}
+	/************************************************************/
+	// 非事件消息不携带 id
+	e = newSSEEvent(`{"op":"subscribed","requestId":1}`, 1024)
+	expect = "event: subscribed\ndata: {\"op\":\"subscribed\",\"requestId\":1}\n\n"
+	if e.String() != expect {
+		t.Error("expect:", expect, "result:", e.String())
+	}
+	/************************************************************/
+	e = sseEvent{data: "a\nb"}
+	expect = "data: a\ndata: b\n\n"
+	if e.String() != expect {
+		t.Error("expect:", expect, "result:", e.String())
+	}
+}
+
+func Test_NewSSEWebSocket(t *testing.T) {
+	w := &sseRecorder{header: http.Header{}, writes: make(chan string, 10)}
+	ws, closed, err := NewSSEWebSocket(w, func() int64 { return 7 })
+	if err != nil {
+		t.Fatal("expect:", nil, "result:", err)
+	}
+	if w.status != http.StatusOK || w.header.Get("Content-Type") != "text/event-stream" {
+		t.Error("expect:", "text/event-stream", "result:", w.status, w.header)
+	}
+	/************************************************************/
+	ws.send(`{"op":"update","requestId":1}`)
+	select {
+	case result := <-w.writes:
+		expect := "id: 7\nevent: update\ndata: {\"op\":\"update\",\"requestId\":1}\n\n"
+		if result != expect {
+			t.Error("expect:", expect, "result:", result)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect:", "event", "result:", "timeout")
+	}
+	/************************************************************/
+	ws.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("expect:", "closed", "result:", "timeout")
+	}
+	if err := ws.write(`{"op":"error"}`); err == nil {
+		t.Error("expect:", "sse is closed", "result:", err)
+	}
+}
+
+func Test_CloseAfterSend(t *testing.T) {
+	w := &sseRecorder{header: http.Header{}, writes: make(chan string, 10)}
+	ws, closed, _ := NewSSEWebSocket(w, func() int64 { return 0 })
+	/************************************************************/
+	// 先发送错误事件，再关闭连接
+	PushError(ws, 101, "Object not found.", false)
+	ws.CloseAfterSend()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("expect:", "closed", "result:", "timeout")
+	}
+	select {
+	case result := <-w.writes:
+		expect := "event: error\ndata: {\"code\":101,\"error\":\"Object not found.\",\"op\":\"error\",\"reconnect\":false}\n\n"
+		if result != expect {
+			t.Error("expect:", expect, "result:", result)
+		}
+	default:
+		t.Error("expect:", "error event", "result:", "nothing")
+	}
+}
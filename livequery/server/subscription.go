@@ -1,13 +1,33 @@
 package server
 
-import "github.com/JuShangEnergy/framework/livequery/t"
+import (
+	"strings"
+
+	"github.com/JuShangEnergy/framework/livequery/t"
+)
 
 // SubscriptionInfo 订阅对象信息
 // 每一个客户端请求对应一个对象
 type SubscriptionInfo struct {
-	Subscription *Subscription
-	SessionToken string
-	Fields       []string
+	Subscription   *Subscription
+	SessionToken   string
+	Fields         []string
+	ReadUserFields []string // 仅通过 CLP 的 readUserFields 授权时，对象中需要指向当前用户的字段
+	Watch          []string // 仅当这些字段变化时推送 update 事件，为空时不限制
+}
+
+// WatchedFieldsChanged 检测 watch 中的字段是否有变化， changedFields 为对象中变化的顶层字段
+// watch 中的字段为 stats.score 这样的子字段时，按顶层字段 stats 判断
+func (s *SubscriptionInfo) WatchedFieldsChanged(changedFields map[string]bool) bool {
+	if len(s.Watch) == 0 {
+		return true
+	}
+	for _, field := range s.Watch {
+		if changedFields[strings.SplitN(field, ".", 2)[0]] {
+			return true
+		}
+	}
+	return false
 }
 
 // Subscription 订阅对象
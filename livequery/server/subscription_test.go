@@ -0,0 +1,30 @@
+package server
+
+import "testing"
+
+func Test_WatchedFieldsChanged(t *testing.T) {
+	var info *SubscriptionInfo
+	/************************************************************/
+	// 未设置 watch 时不限制
+	info = &SubscriptionInfo{}
+	if info.WatchedFieldsChanged(map[string]bool{}) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	/************************************************************/
+	info = &SubscriptionInfo{Watch: []string{"score"}}
+	if info.WatchedFieldsChanged(map[string]bool{"score": true}) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if info.WatchedFieldsChanged(map[string]bool{"name": true}) {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	// 子字段按顶层字段判断
+	info = &SubscriptionInfo{Watch: []string{"stats.score"}}
+	if info.WatchedFieldsChanged(map[string]bool{"stats": true}) == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if info.WatchedFieldsChanged(map[string]bool{"name": true}) {
+		t.Error("expect:", false, "result:", true)
+	}
+}
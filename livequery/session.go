@@ -0,0 +1,80 @@
+package livequery
+
+import (
+	"time"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/livequery/t"
+	"github.com/JuShangEnergy/framework/livequery/utils"
+)
+
+const (
+	// sessionRevokedDowngrade sessionToken 失效后按未登录用户校验权限
+	sessionRevokedDowngrade = "downgrade"
+	// sessionRevokedClose sessionToken 失效后关闭相关订阅
+	sessionRevokedClose = "close"
+)
+
+// sessionSweepInterval 检查已过期 sessionToken 的间隔
+var sessionSweepInterval = time.Minute
+
+// onSessionRevoked 从 subscriber 中接收到 sessionToken 失效消息时调用
+// sessionToken 为 * 时表示全部 sessionToken 失效
+func (l *liveQueryServer) onSessionRevoked(message t.M) {
+	sessionToken, _ := message["sessionToken"].(string)
+	if sessionToken == "" {
+		return
+	}
+	utils.TLog.Verbose("sessionRevoked is triggered")
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if sessionToken == "*" {
+		l.sessionTokenCache.Clear()
+	} else {
+		l.sessionTokenCache.Remove(sessionToken)
+	}
+	l.revokeSessions(func(token string) bool {
+		return sessionToken == "*" || token == sessionToken
+	})
+}
+
+// sweepExpiredSessions 定期处理 sessionToken 已过期的订阅
+func (l *liveQueryServer) sweepExpiredSessions() {
+	ticker := time.NewTicker(sessionSweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.mutex.Lock()
+		l.revokeSessions(l.sessionTokenCache.IsExpired)
+		l.mutex.Unlock()
+	}
+}
+
+// revokeSessions 处理 sessionToken 已失效的订阅，调用方需要持有 l.mutex
+// downgrade 时清除订阅中的 sessionToken ，之后按未登录用户校验 ACL ，未登录用户无权订阅该类时关闭订阅
+// close 时直接关闭订阅并通知客户端
+func (l *liveQueryServer) revokeSessions(revoked func(sessionToken string) bool) {
+	for clientID, client := range l.clients {
+		for requestID, subscriptionInfo := range client.SubscriptionInfos {
+			if subscriptionInfo.SessionToken == "" || revoked(subscriptionInfo.SessionToken) == false {
+				continue
+			}
+			if l.sessionRevokedPolicy == sessionRevokedDowngrade {
+				subscriptionInfo.SessionToken = ""
+				subscriptionInfo.ReadUserFields = nil
+				if client.HasMasterKey {
+					continue
+				}
+				subscription := subscriptionInfo.Subscription
+				_, err := l.validateCLP(subscription.ClassName, t.M{"where": map[string]interface{}(subscription.Query)}, "")
+				if err == nil {
+					utils.TLog.Verbose("Downgrade client", clientID, "subscription", requestID, "to public access")
+					continue
+				}
+			}
+			l.deleteSubscription(clientID, client, requestID, subscriptionInfo)
+			client.PushSubscriptionError(requestID, errs.InvalidSessionToken, "Session token is expired or revoked.", false)
+			utils.TLog.Verbose("Close client", clientID, "subscription", requestID, "for revoked session")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package livequery
+
+import (
+	"testing"
+
+	"github.com/JuShangEnergy/framework/livequery/server"
+	tp "github.com/JuShangEnergy/framework/livequery/t"
+)
+
+func newSessionTestServer(policy string, clp tp.M) (*liveQueryServer, *server.Client) {
+	server.Embedded = &server.EmbeddedResolver{
+		UserForSessionToken: func(sessionToken string) (tp.M, error) {
+			return tp.M{"objectId": "1024"}, nil
+		},
+		UserRoles: func(userID string) []string {
+			return []string{}
+		},
+		ClassSchema: func(className string) (tp.M, error) {
+			return tp.M{"className": className, "classLevelPermissions": map[string]interface{}(clp)}, nil
+		},
+	}
+	l := &liveQueryServer{
+		clients:              map[int]*server.Client{},
+		subscriptions:        map[string]map[string]*server.Subscription{},
+		sessionTokenCache:    server.NewSessionTokenCache(),
+		sessionRevokedPolicy: policy,
+	}
+	client := server.NewClient(1, nil)
+	l.clients[1] = client
+	subscription := server.NewSubscription("post", tp.M{}, "hash")
+	subscription.AddClientSubscription(1, 1)
+	subscription.AddClientSubscription(1, 2)
+	l.subscriptions["post"] = map[string]*server.Subscription{"hash": subscription}
+	client.AddSubscriptionInfo(1, &server.SubscriptionInfo{Subscription: subscription, SessionToken: "r:abc"})
+	client.AddSubscriptionInfo(2, &server.SubscriptionInfo{Subscription: subscription, SessionToken: "r:def"})
+	return l, client
+}
+
+func Test_onSessionRevoked(t *testing.T) {
+	defer func() { server.Embedded = nil }()
+	var l *liveQueryServer
+	var client *server.Client
+	/************************************************************/
+	// downgrade 时清除 sessionToken ，保留订阅
+	l, client = newSessionTestServer(sessionRevokedDowngrade, nil)
+	l.onSessionRevoked(tp.M{"sessionToken": "r:abc"})
+	if info := client.GetSubscriptionInfo(1); info == nil || info.SessionToken != "" {
+		t.Error("expect:", "downgraded subscription", "result:", info)
+	}
+	if info := client.GetSubscriptionInfo(2); info == nil || info.SessionToken != "r:def" {
+		t.Error("expect:", "r:def", "result:", info)
+	}
+	/************************************************************/
+	// downgrade 时未登录用户无权订阅，关闭订阅
+	l, client = newSessionTestServer(sessionRevokedDowngrade, tp.M{
+		"find": map[string]interface{}{"requiresAuthentication": true},
+	})
+	l.onSessionRevoked(tp.M{"sessionToken": "r:abc"})
+	if info := client.GetSubscriptionInfo(1); info != nil {
+		t.Error("expect:", nil, "result:", info)
+	}
+	if info := client.GetSubscriptionInfo(2); info == nil {
+		t.Error("expect:", "subscription", "result:", info)
+	}
+	/************************************************************/
+	// close 时关闭全部订阅
+	l, client = newSessionTestServer(sessionRevokedClose, nil)
+	l.onSessionRevoked(tp.M{"sessionToken": "*"})
+	if len(client.SubscriptionInfos) != 0 {
+		t.Error("expect:", 0, "result:", client.SubscriptionInfos)
+	}
+	if len(l.subscriptions) != 0 {
+		t.Error("expect:", 0, "result:", l.subscriptions)
+	}
+}
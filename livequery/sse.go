@@ -0,0 +1,257 @@
+package livequery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/livequery/server"
+	"github.com/JuShangEnergy/framework/livequery/t"
+	"github.com/JuShangEnergy/framework/livequery/utils"
+)
+
+const (
+	channelAfterSave   = "afterSave"
+	channelAfterDelete = "afterDelete"
+)
+
+// replayEvent 重放缓存中的一条对象变更消息
+type replayEvent struct {
+	id      int64
+	channel string
+	message t.M
+}
+
+// replayBuffer 保存最近的对象变更消息，用于 SSE 客户端重连后补发断开期间的事件
+// 事件 ID 以启动时间为起点递增，重启后客户端携带的旧 ID 不会与新事件混淆
+type replayBuffer struct {
+	size   int
+	lastID int64
+	events []replayEvent
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{
+		size:   size,
+		lastID: time.Now().UnixNano(),
+		events: []replayEvent{},
+	}
+}
+
+// add 保存消息并返回分配的事件 ID
+func (b *replayBuffer) add(channel string, message t.M) int64 {
+	b.lastID++
+	if b.size <= 0 {
+		return b.lastID
+	}
+	b.events = append(b.events, replayEvent{id: b.lastID, channel: channel, message: message})
+	if len(b.events) > b.size {
+		b.events = b.events[len(b.events)-b.size:]
+	}
+	return b.lastID
+}
+
+// since 返回 ID 大于 id 的消息，超出缓存范围的消息已无法补发
+func (b *replayBuffer) since(id int64) []replayEvent {
+	for i, e := range b.events {
+		if e.id > id {
+			return b.events[i:]
+		}
+	}
+	return nil
+}
+
+// dispatch 分配事件 ID 并推送对象变更消息，同一时间只推送一条消息，保证事件顺序与 ID 一致
+func (l *liveQueryServer) dispatch(channel string, message t.M) {
+	l.dispatchMutex.Lock()
+	defer l.dispatchMutex.Unlock()
+	atomic.StoreInt64(&l.eventID, l.replayEvents.add(channel, message))
+	l.publish(channel, message, 0)
+}
+
+// publish 向订阅的客户端推送消息， clientID 不为 0 时仅推送给该客户端
+// 订阅条件在 l.mutex 之外匹配，避免子查询阻塞订阅与断开连接，推送期间持有 l.mutex
+func (l *liveQueryServer) publish(channel string, message t.M, clientID int) {
+	switch channel {
+	case channelAfterSave:
+		l.pushAfterSave(message, clientID)
+	case channelAfterDelete:
+		l.pushAfterDelete(message, clientID)
+	}
+}
+
+// replay 向重连的客户端补发 lastEventID 之后的消息，调用方需要持有 l.dispatchMutex
+func (l *liveQueryServer) replay(clientID int, lastEventID int64) {
+	events := l.replayEvents.since(lastEventID)
+	utils.TLog.Verbose("Replay", len(events), "events to client", clientID)
+	for _, e := range events {
+		atomic.StoreInt64(&l.eventID, e.id)
+		l.publish(e.channel, e.message, clientID)
+	}
+	atomic.StoreInt64(&l.eventID, l.replayEvents.lastID)
+}
+
+// ServeSSE 处理 SSE 请求，每个请求对应一个客户端与一个订阅，例如：
+//
+//	GET /live?className=Player&where={"name":"test"}&fields=name,score&watch=score
+//
+// sessionToken 通过 X-Parse-Session-Token 请求头或 sessionToken 参数传入
+// masterKey 通过 X-Parse-Master-Key 请求头或 masterKey 参数传入
+// 重连时通过 Last-Event-ID 请求头或 lastEventId 参数，从重放缓存中补发断开期间的事件
+func (l *liveQueryServer) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeSSEError(w, http.StatusMethodNotAllowed, 1, "Method not allowed")
+		return
+	}
+	request, err := sseRequest(r)
+	if err == nil {
+		err = server.Validate(request, "subscribe")
+	}
+	if err != nil {
+		writeSSEError(w, http.StatusBadRequest, 1, err.Error())
+		return
+	}
+	if l.validateKeys(request, l.keyPairs) == false {
+		writeSSEError(w, http.StatusForbidden, 4, "Key in request is not valid")
+		return
+	}
+
+	// 建立连接前校验权限，失败时直接返回错误状态码，避免客户端不断重连
+	query := request["query"].(map[string]interface{})
+	className := query["className"].(string)
+	sessionToken, _ := request["sessionToken"].(string)
+	masterKey, _ := request["masterKey"].(string)
+	hasMasterKey := masterKey != "" && masterKey == server.TomatoInfo["masterKey"]
+	if enabled, err := server.LiveQueryEnabled(className); err != nil || enabled == false {
+		writeSSEError(w, http.StatusForbidden, errs.OperationForbidden, "Class "+className+" is not enabled for LiveQuery.")
+		return
+	}
+	var readUserFields []string
+	if hasMasterKey == false {
+		if utils.HasSubQuery(query["where"]) {
+			writeSSEError(w, http.StatusForbidden, errs.OperationForbidden, "Sub query is not allowed without masterKey.")
+			return
+		}
+		readUserFields, err = l.validateCLP(className, query, sessionToken)
+		if err != nil {
+			writeSSEError(w, http.StatusForbidden, errs.OperationForbidden, err.Error())
+			return
+		}
+	}
+
+	ws, closed, err := server.NewSSEWebSocket(w, func() int64 {
+		return atomic.LoadInt64(&l.eventID)
+	})
+	if err != nil {
+		writeSSEError(w, http.StatusInternalServerError, 1, err.Error())
+		return
+	}
+
+	// 添加订阅与补发事件期间暂停推送新消息，保证客户端收到的事件不重复、不乱序
+	// 权限已在建立连接前校验，此处不再请求 tomato 接口，避免长时间阻塞消息推送
+	l.dispatchMutex.Lock()
+	l.mutex.Lock()
+	client := server.NewClient(l.clientID, ws)
+	client.HasMasterKey = hasMasterKey
+	ws.ClientID = l.clientID
+	l.clientID++
+	l.clients[ws.ClientID] = client
+	l.mutex.Unlock()
+	utils.TLog.Log("Create new SSE client:", ws.ClientID)
+	subscribed := l.addSubscription(ws, request, readUserFields)
+	if lastEventID := sseLastEventID(r); subscribed && lastEventID > 0 {
+		l.replay(ws.ClientID, lastEventID)
+	}
+	l.dispatchMutex.Unlock()
+	if subscribed == false {
+		// 订阅失败时连接中没有任何订阅，发送错误事件后关闭连接
+		ws.CloseAfterSend()
+	}
+
+	select {
+	case <-closed:
+	case <-r.Context().Done():
+	}
+	l.OnDisconnect(ws)
+	ws.Close()
+	<-closed
+}
+
+// sseRequest 把 SSE 请求参数转换为 subscribe 请求，用于复用 subscribe 的校验与处理逻辑
+func sseRequest(r *http.Request) (t.M, error) {
+	params := r.URL.Query()
+	where := map[string]interface{}{}
+	if w := params.Get("where"); w != "" {
+		if err := json.Unmarshal([]byte(w), &where); err != nil {
+			return nil, errs.E(errs.InvalidJSON, "where is not valid JSON")
+		}
+	}
+	query := map[string]interface{}{
+		"className": params.Get("className"),
+		"where":     where,
+	}
+	if f := params.Get("fields"); f != "" {
+		fields := []interface{}{}
+		for _, field := range strings.Split(f, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		query["fields"] = fields
+	}
+	request := t.M{
+		"op":        "subscribe",
+		"requestId": float64(1),
+		"query":     query,
+	}
+	if w := params.Get("watch"); w != "" {
+		watch := []interface{}{}
+		for _, field := range strings.Split(w, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				watch = append(watch, field)
+			}
+		}
+		request["watch"] = watch
+	}
+	if sessionToken := headerOrParam(r, "X-Parse-Session-Token", "sessionToken"); sessionToken != "" {
+		request["sessionToken"] = sessionToken
+	}
+	if masterKey := headerOrParam(r, "X-Parse-Master-Key", "masterKey"); masterKey != "" {
+		request["masterKey"] = masterKey
+	}
+	// 其他参数用于校验 keyPairs ，如 clientKey
+	for key := range params {
+		if _, ok := request[key]; ok == false && key != "className" && key != "where" && key != "fields" && key != "watch" {
+			request[key] = params.Get(key)
+		}
+	}
+	return request, nil
+}
+
+// sseLastEventID 获取客户端最后收到的事件 ID ，没有时返回 0
+func sseLastEventID(r *http.Request) int64 {
+	id, err := strconv.ParseInt(headerOrParam(r, "Last-Event-ID", "lastEventId"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func headerOrParam(r *http.Request, header, param string) string {
+	if v := r.Header.Get(header); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(param)
+}
+
+// writeSSEError 建立 SSE 连接前返回错误信息
+func writeSSEError(w http.ResponseWriter, status int, code int, errMsg string) {
+	data, _ := json.Marshal(t.M{"code": code, "error": errMsg})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
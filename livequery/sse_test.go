@@ -0,0 +1,246 @@
+package livequery
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/server"
+	tp "github.com/JuShangEnergy/framework/livequery/t"
+)
+
+func Test_replayBuffer(t *testing.T) {
+	b := newReplayBuffer(2)
+	first := b.add(channelAfterSave, tp.M{"n": 1})
+	second := b.add(channelAfterSave, tp.M{"n": 2})
+	third := b.add(channelAfterDelete, tp.M{"n": 3})
+	if second != first+1 || third != second+1 {
+		t.Error("expect:", "increasing ids", "result:", first, second, third)
+	}
+	/************************************************************/
+	// 超出长度的消息被丢弃
+	events := b.since(0)
+	if len(events) != 2 || events[0].id != second || events[1].channel != channelAfterDelete {
+		t.Error("expect:", "2 events", "result:", events)
+	}
+	events = b.since(second)
+	if len(events) != 1 || events[0].id != third {
+		t.Error("expect:", third, "result:", events)
+	}
+	if events = b.since(third); len(events) != 0 {
+		t.Error("expect:", 0, "result:", events)
+	}
+}
+
+func Test_sseRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", `/live?className=post&where=`+url.QueryEscape(`{"title":"hello"}`)+`&fields=title,+author&watch=title&clientKey=abc`, nil)
+	r.Header.Set("X-Parse-Session-Token", "r:abc")
+	request, err := sseRequest(r)
+	if err != nil {
+		t.Fatal("expect:", nil, "result:", err)
+	}
+	if err := server.Validate(request, "subscribe"); err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	query := request["query"].(map[string]interface{})
+	if query["className"] != "post" || query["where"].(map[string]interface{})["title"] != "hello" {
+		t.Error("expect:", "post", "result:", query)
+	}
+	if fields := query["fields"].([]interface{}); len(fields) != 2 || fields[1] != "author" {
+		t.Error("expect:", []string{"title", "author"}, "result:", fields)
+	}
+	if watch := request["watch"].([]interface{}); len(watch) != 1 || watch[0] != "title" {
+		t.Error("expect:", []string{"title"}, "result:", watch)
+	}
+	if request["sessionToken"] != "r:abc" || request["clientKey"] != "abc" {
+		t.Error("expect:", "r:abc abc", "result:", request)
+	}
+	/************************************************************/
+	r = httptest.NewRequest("GET", `/live?className=post&where=abc`, nil)
+	if _, err := sseRequest(r); err == nil {
+		t.Error("expect:", "where is not valid JSON", "result:", err)
+	}
+}
+
+// readSSEEvent 读取一条 SSE 消息，返回 id 与 event
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	var id, event string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal("expect:", "event", "result:", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return id, event
+		}
+		if strings.HasPrefix(line, "id: ") {
+			id = line[4:]
+		} else if strings.HasPrefix(line, "event: ") {
+			event = line[7:]
+		}
+	}
+}
+
+func Test_ServeSSE(t *testing.T) {
+	defer func() { server.Embedded = nil }()
+	var schemaRequests int32
+	server.Embedded = &server.EmbeddedResolver{
+		ClassSchema: func(className string) (tp.M, error) {
+			atomic.AddInt32(&schemaRequests, 1)
+			return nil, nil
+		},
+	}
+	l := &liveQueryServer{
+		clientID:          1,
+		clients:           map[int]*server.Client{},
+		subscriptions:     map[string]map[string]*server.Subscription{},
+		sessionTokenCache: server.NewSessionTokenCache(),
+		replayEvents:      newReplayBuffer(10),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(l.ServeSSE))
+	defer ts.Close()
+	message := func(title string) tp.M {
+		return tp.M{"currentParseObject": map[string]interface{}{"className": "post", "objectId": "1", "title": title}}
+	}
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest("GET", ts.URL+"/live?className=post", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("expect:", nil, "result:", err)
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+	/************************************************************/
+	resp, reader := open("")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("expect:", http.StatusOK, "result:", resp.StatusCode)
+	}
+	if _, event := readSSEEvent(t, reader); event != "subscribed" {
+		t.Error("expect:", "subscribed", "result:", event)
+	}
+	// 建立连接前已校验权限，订阅时不再重复查询类定义
+	if n := atomic.LoadInt32(&schemaRequests); n != 1 {
+		t.Error("expect:", 1, "result:", n)
+	}
+	l.dispatch(channelAfterSave, message("a"))
+	id, event := readSSEEvent(t, reader)
+	if event != "create" || id == "" {
+		t.Error("expect:", "create", "result:", id, event)
+	}
+	resp.Body.Close()
+	/************************************************************/
+	// 断开期间的事件在重连后补发
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mutex.Lock()
+		n := len(l.clients)
+		l.mutex.Unlock()
+		if n == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	l.dispatch(channelAfterSave, message("b"))
+	l.dispatch(channelAfterDelete, message("b"))
+	resp, reader = open(id)
+	defer resp.Body.Close()
+	readSSEEvent(t, reader)
+	lastID, _ := strconv.ParseInt(id, 10, 64)
+	for _, expect := range []string{"create", "delete"} {
+		id, event = readSSEEvent(t, reader)
+		lastID++
+		if event != expect || id != strconv.FormatInt(lastID, 10) {
+			t.Error("expect:", expect, lastID, "result:", event, id)
+		}
+	}
+}
+
+func Test_ServeSSESubQuery(t *testing.T) {
+	defer func() { server.Embedded = nil }()
+	server.Embedded = &server.EmbeddedResolver{
+		ClassSchema: func(className string) (tp.M, error) {
+			return nil, nil
+		},
+	}
+	l := &liveQueryServer{
+		clientID:          1,
+		clients:           map[int]*server.Client{},
+		subscriptions:     map[string]map[string]*server.Subscription{},
+		sessionTokenCache: server.NewSessionTokenCache(),
+		replayEvents:      newReplayBuffer(10),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(l.ServeSSE))
+	defer ts.Close()
+	where := url.QueryEscape(`{"author":{"$inQuery":{"className":"_User","where":{"email":"a@b.c"}}}}`)
+	/************************************************************/
+	// 非 masterKey 客户端不允许在订阅条件中使用子查询
+	resp, err := http.Get(ts.URL + "/live?className=post&where=" + where)
+	if err != nil {
+		t.Fatal("expect:", nil, "result:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error("expect:", http.StatusForbidden, "result:", resp.StatusCode)
+	}
+	l.mutex.Lock()
+	n := len(l.clients)
+	l.mutex.Unlock()
+	if n != 0 {
+		t.Error("expect:", 0, "result:", n)
+	}
+}
+
+func Test_pushAfterSaveWatch(t *testing.T) {
+	defer func() { server.Embedded = nil }()
+	server.Embedded = &server.EmbeddedResolver{
+		ClassSchema: func(className string) (tp.M, error) {
+			return nil, nil
+		},
+	}
+	l := &liveQueryServer{
+		clientID:          1,
+		clients:           map[int]*server.Client{},
+		subscriptions:     map[string]map[string]*server.Subscription{},
+		sessionTokenCache: server.NewSessionTokenCache(),
+		replayEvents:      newReplayBuffer(10),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(l.ServeSSE))
+	defer ts.Close()
+	device := func(score, heartbeat float64) map[string]interface{} {
+		return map[string]interface{}{"className": "Device", "objectId": "1", "score": score, "lastHeartbeat": heartbeat}
+	}
+	resp, err := http.Get(ts.URL + "/live?className=Device&watch=score")
+	if err != nil {
+		t.Fatal("expect:", nil, "result:", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if _, event := readSSEEvent(t, reader); event != "subscribed" {
+		t.Error("expect:", "subscribed", "result:", event)
+	}
+	/************************************************************/
+	// 仅 lastHeartbeat 变化时不推送 update ，score 变化时推送
+	l.dispatch(channelAfterSave, tp.M{"originalParseObject": device(1, 1), "currentParseObject": device(1, 2)})
+	skipped := atomic.LoadInt64(&l.eventID)
+	l.dispatch(channelAfterSave, tp.M{"originalParseObject": device(1, 2), "currentParseObject": device(2, 3)})
+	id, event := readSSEEvent(t, reader)
+	if event != "update" || id != strconv.FormatInt(skipped+1, 10) {
+		t.Error("expect:", "update", skipped+1, "result:", event, id)
+	}
+	/************************************************************/
+	// watch 只限制 update ，delete 照常推送
+	l.dispatch(channelAfterDelete, tp.M{"currentParseObject": device(2, 3)})
+	if _, event = readSSEEvent(t, reader); event != "delete" {
+		t.Error("expect:", "delete", "result:", event)
+	}
+}
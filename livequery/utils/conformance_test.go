@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+
+	tp "github.com/JuShangEnergy/framework/livequery/t"
+)
+
+// conformanceSuite 订阅条件与 REST 查询的一致性测试用例，orm 中使用同一份用例测试 DBController.Find
+type conformanceSuite struct {
+	Classes   map[string]tp.M   `json:"classes"`
+	Objects   map[string][]tp.M `json:"objects"`
+	Relations []struct {
+		ClassName  string   `json:"className"`
+		ObjectID   string   `json:"objectId"`
+		Key        string   `json:"key"`
+		RelatedIDs []string `json:"relatedIds"`
+	} `json:"relations"`
+	Cases []struct {
+		Name      string   `json:"name"`
+		ClassName string   `json:"className"`
+		Where     tp.M     `json:"where"`
+		Expect    []string `json:"expect"`
+		SubQuery  bool     `json:"subQuery"`
+	} `json:"cases"`
+}
+
+func loadConformanceSuite(t *testing.T) *conformanceSuite {
+	data, err := ioutil.ReadFile("testdata/conformance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite := &conformanceSuite{}
+	if err := json.Unmarshal(data, suite); err != nil {
+		t.Fatal(err)
+	}
+	for className, objects := range suite.Objects {
+		for _, object := range objects {
+			object["className"] = className
+		}
+	}
+	return suite
+}
+
+// resolve 在用例数据中执行子查询
+func (s *conformanceSuite) resolve(className string, where, options tp.M) ([]tp.M, error) {
+	where = copyM(where)
+	relatedIDs := map[string]bool{}
+	relatedTo, hasRelatedTo := where["$relatedTo"].(map[string]interface{})
+	if hasRelatedTo {
+		delete(where, "$relatedTo")
+		owner := relatedTo["object"].(map[string]interface{})
+		for _, r := range s.Relations {
+			if r.ClassName == owner["className"] && r.ObjectID == owner["objectId"] && r.Key == relatedTo["key"] {
+				for _, id := range r.RelatedIDs {
+					relatedIDs[id] = true
+				}
+			}
+		}
+	}
+	results := []tp.M{}
+	for _, object := range s.Objects[className] {
+		if hasRelatedTo && relatedIDs[object["objectId"].(string)] == false {
+			continue
+		}
+		if MatchesQuery(object, where) {
+			results = append(results, object)
+		}
+	}
+	if limit, ok := options["limit"].(float64); ok && len(results) > int(limit) {
+		results = results[:int(limit)]
+	}
+	return results, nil
+}
+
+func copyM(m tp.M) tp.M {
+	r := tp.M{}
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
+
+func Test_MatchesQueryConformance(t *testing.T) {
+	suite := loadConformanceSuite(t)
+	SubQueryResolver = suite.resolve
+	defer func() { SubQueryResolver = nil }()
+
+	for _, c := range suite.Cases {
+		result := []string{}
+		for _, object := range suite.Objects[c.ClassName] {
+			if MatchesQuery(object, c.Where) {
+				result = append(result, object["objectId"].(string))
+			}
+		}
+		sort.Strings(result)
+		if reflect.DeepEqual(c.Expect, result) == false {
+			t.Error(c.Name, "expect:", c.Expect, "result:", result)
+		}
+	}
+}
+
+func Test_MatchesQueryWithoutResolver(t *testing.T) {
+	suite := loadConformanceSuite(t)
+	SubQueryResolver = nil
+	// 未设置子查询时，包含子查询的条件均不符合
+	for _, c := range suite.Cases {
+		if c.SubQuery == false && c.Name != "$relatedTo" {
+			continue
+		}
+		for _, object := range suite.Objects[c.ClassName] {
+			if MatchesQuery(object, c.Where) {
+				t.Error(c.Name, "expect:", false, "result:", true, object["objectId"])
+			}
+		}
+	}
+}
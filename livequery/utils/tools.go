@@ -3,11 +3,15 @@ package utils
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/JuShangEnergy/framework/livequery/t"
+	"golang.org/x/text/unicode/norm"
 )
 
 // QueryHash 计算 query 的 hash
@@ -89,7 +93,24 @@ func MatchesQuery(object, query t.M) bool {
 	return true
 }
 
+// ChangedFields 比较对象保存前后的字段，返回值发生变化的字段，仅在一方存在的字段也视为变化
+func ChangedFields(original, current t.M) map[string]bool {
+	changed := map[string]bool{}
+	for k, v := range current {
+		if o, ok := original[k]; ok == false || reflect.DeepEqual(o, v) == false {
+			changed[k] = true
+		}
+	}
+	for k := range original {
+		if _, ok := current[k]; ok == false {
+			changed[k] = true
+		}
+	}
+	return changed
+}
+
 // matchesKeyConstraints 检测对象中的字段是否符合指定的条件
+// 支持的条件与 REST 查询一致，数组字段中只要有一个元素符合即可
 func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool {
 	if object == nil {
 		return false
@@ -99,10 +120,19 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 		return false
 	}
 
-	if strings.Contains(key, ".") {
+	if strings.Contains(key, ".") && strings.HasPrefix(key, "$") == false {
 		keyComponents := strings.Split(key, ".")
 		subObjectKey := keyComponents[0]
 		keyRemainder := strings.Join(keyComponents[1:], ".")
+		// 子对象为数组时，只要有一个元素符合即可
+		if subObjects, ok := object[subObjectKey].([]interface{}); ok {
+			for _, o := range subObjects {
+				if subObject, ok := o.(map[string]interface{}); ok && matchesKeyConstraints(subObject, keyRemainder, constraints) {
+					return true
+				}
+			}
+			return false
+		}
 		subObject := t.M{}
 		if o, ok := object[subObjectKey].(map[string]interface{}); ok && o != nil {
 			subObject = o
@@ -125,11 +155,24 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 		return false
 	}
 
-	// 不支持 relatedTo
-	if key == "$relatedTo" {
+	// 处理 $and ，需要全部符合
+	if key == "$and" {
+		if querys, ok := constraints.([]interface{}); ok {
+			for _, query := range querys {
+				if q, ok := query.(map[string]interface{}); ok == false || MatchesQuery(object, q) == false {
+					return false
+				}
+			}
+			return true
+		}
 		return false
 	}
 
+	// 处理 $relatedTo ，通过子查询判断对象是否在指定的 Relation 中
+	if key == "$relatedTo" {
+		return matchesRelatedTo(object, constraints)
+	}
+
 	// 只支持 key == "$or" 时，constraints 为数组的情况
 	if _, ok := constraints.([]interface{}); ok {
 		return false
@@ -141,15 +184,7 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 		constraint = v
 	} else {
 		// 当 object[key] 为数组时，只要有一个符合即可
-		if objects, ok := object[key].([]interface{}); ok {
-			for _, o := range objects {
-				if equalObject(o, constraints) {
-					return true
-				}
-			}
-			return false
-		}
-		return equalObject(object[key], constraints)
+		return anyValue(object[key], func(v interface{}) bool { return equalValue(v, constraints) })
 	}
 
 	// 处理 constraint 为 __type 类型时的情况
@@ -179,32 +214,37 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 			})
 		}
 
-		return equalObjectsGeneric(object[key], constraints, equalObject)
+		return equalObjectsGeneric(object[key], constraints, equalValue)
 	}
 
 	// 处理 constraint 包含限制条件时的情况
+	value, propertyExists := object[key]
 	for condition, compareTo := range constraint {
 		switch condition {
 		case "$lt", "$lte", "$gt", "$gte":
-			if compareNumber(object[key], compareTo, condition) == false {
+			if anyValue(value, func(v interface{}) bool { return compareValue(v, compareTo, condition) }) == false {
+				return false
+			}
+		case "$eq":
+			if anyValue(value, func(v interface{}) bool { return equalValue(v, compareTo) }) == false {
 				return false
 			}
 		case "$ne":
-			if equalObject(object[key], compareTo) {
+			if anyValue(value, func(v interface{}) bool { return equalValue(v, compareTo) }) {
 				return false
 			}
 		case "$in":
-			if inSlice(compareTo, object[key]) == false {
+			if containedIn(value, compareTo) == false {
 				return false
 			}
 		case "$nin":
-			if inSlice(compareTo, object[key]) {
+			if containedIn(value, compareTo) {
 				return false
 			}
 		case "$all":
 			if compareToObjects, ok := compareTo.([]interface{}); ok {
 				for _, compareToObject := range compareToObjects {
-					if inSlice(object[key], compareToObject) == false {
+					if anyValue(value, func(v interface{}) bool { return equalValue(v, compareToObject) }) == false {
 						return false
 					}
 				}
@@ -212,12 +252,6 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 				return false
 			}
 		case "$exists":
-			var propertyExists bool
-			if _, ok := object[key]; ok {
-				propertyExists = true
-			} else {
-				propertyExists = false
-			}
 			var existenceIsRequired bool
 			if v, ok := constraint["$exists"].(bool); ok {
 				existenceIsRequired = v
@@ -228,29 +262,605 @@ func matchesKeyConstraints(object t.M, key string, constraints interface{}) bool
 				return false
 			}
 		case "$regex":
-			if compareRegexp(compareTo, object[key]) == false {
+			options, _ := constraint["$options"].(string)
+			if anyValue(value, func(v interface{}) bool { return compareRegexpWithOptions(compareTo, options, v) }) == false {
 				return false
 			}
 		case "$nearSphere":
-			if compareGeoPoint(compareTo, object[key], constraint["$maxDistance"]) == false {
+			if compareGeoPoint(compareTo, value, maxDistanceInRadians(constraint)) == false {
 				return false
 			}
 		case "$within":
-			if compareBox(compareTo, object[key]) == false {
+			if compareBox(compareTo, value) == false {
+				return false
+			}
+		case "$geoWithin":
+			if compareGeoWithin(compareTo, value) == false {
+				return false
+			}
+		case "$geoIntersects":
+			if compareGeoIntersects(compareTo, value) == false {
+				return false
+			}
+		case "$text":
+			if anyValue(value, func(v interface{}) bool { return compareText(compareTo, v) }) == false {
+				return false
+			}
+		case "$inQuery", "$notInQuery":
+			matched, ok := matchesInQuery(compareTo, value)
+			if ok == false || matched != (condition == "$inQuery") {
+				return false
+			}
+		case "$select", "$dontSelect":
+			matched, ok := matchesSelect(compareTo, value)
+			if ok == false || matched != (condition == "$select") {
 				return false
 			}
 		case "$options":
-		case "$maxDistance":
-		case "$select":
+		case "$maxDistance", "$maxDistanceInRadians", "$maxDistanceInMiles", "$maxDistanceInKilometers":
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// anyValue 检测 value 是否符合条件，value 为数组时只要有一个元素符合即可
+func anyValue(value interface{}, fn func(v interface{}) bool) bool {
+	if values, ok := value.([]interface{}); ok {
+		for _, v := range values {
+			if fn(v) {
+				return true
+			}
+		}
+	}
+	return fn(value)
+}
+
+// containedIn 检测 value 是否在 list 中，value 为数组时只要有一个元素在 list 中即可
+func containedIn(value, list interface{}) bool {
+	objects, ok := list.([]interface{})
+	if ok == false {
+		return false
+	}
+	return anyValue(value, func(v interface{}) bool {
+		for _, o := range objects {
+			if equalValue(v, o) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// equalValue 比较两个值是否相等，在 equalObject 的基础上支持 int 与 float64 、 Pointer 、 Date 与 ISO 字符串之间的比较
+func equalValue(i1, i2 interface{}) bool {
+	if n1, ok := toNumber(i1); ok {
+		if n2, ok := toNumber(i2); ok {
+			return n1 == n2
+		}
+		return false
+	}
+	if c1, ok := pointerKey(i1); ok {
+		c2, ok := pointerKey(i2)
+		return ok && c1 == c2
+	}
+	if d1, ok := toDate(i1); ok {
+		if d2, ok := toDate(i2); ok {
+			return d1.Equal(d2)
+		}
+	}
+	return equalObject(i1, i2)
+}
+
+// compareValue 比较大小，支持数字、日期与字符串，类型不同时不符合条件
+func compareValue(i1, i2 interface{}, op string) bool {
+	var result int
+	if n1, ok := toNumber(i1); ok {
+		n2, ok := toNumber(i2)
+		if ok == false {
+			return false
+		}
+		result = compareFloat(n1, n2)
+	} else if d1, ok := toDate(i1); ok {
+		d2, ok := toDate(i2)
+		if ok == false {
 			return false
-		case "$dontSelect":
+		}
+		result = compareFloat(float64(d1.UnixNano()), float64(d2.UnixNano()))
+	} else if s1, ok := i1.(string); ok {
+		s2, ok := i2.(string)
+		if ok == false {
+			return false
+		}
+		result = strings.Compare(s1, s2)
+	} else {
+		return false
+	}
+
+	switch op {
+	case "$lt":
+		return result < 0
+	case "$lte":
+		return result <= 0
+	case "$gt":
+		return result > 0
+	case "$gte":
+		return result >= 0
+	default:
+		return false
+	}
+}
+
+func compareFloat(f1, f2 float64) int {
+	if f1 < f2 {
+		return -1
+	}
+	if f1 > f2 {
+		return 1
+	}
+	return 0
+}
+
+func toNumber(i interface{}) (float64, bool) {
+	switch v := i.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// toDate 转换 Date 类型或者 ISO 格式的字符串，如 createdAt 与 updatedAt
+func toDate(i interface{}) (time.Time, bool) {
+	var iso string
+	switch v := i.(type) {
+	case string:
+		iso = v
+	case map[string]interface{}:
+		if v["__type"] != "Date" {
+			return time.Time{}, false
+		}
+		iso, _ = v["iso"].(string)
+	default:
+		return time.Time{}, false
+	}
+	d, err := time.Parse(time.RFC3339Nano, iso)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// pointerKey 返回 Pointer 或者已展开对象的 className 与 objectId
+func pointerKey(i interface{}) (string, bool) {
+	if v, ok := i.(map[string]interface{}); ok && (v["__type"] == "Pointer" || v["__type"] == "Object") {
+		className, _ := v["className"].(string)
+		objectID, _ := v["objectId"].(string)
+		return className + ":" + objectID, true
+	}
+	return "", false
+}
+
+// SubQueryResolver 执行子查询，返回符合条件的对象，用于 $inQuery $notInQuery $select $dontSelect $relatedTo
+// 由 server 包在启动时设置，为 nil 时包含子查询的条件均不符合
+var SubQueryResolver func(className string, where, options t.M) ([]t.M, error)
+
+// HasSubQuery 检测查询条件中是否包含需要执行子查询的操作符，包括嵌套在 $or $and 中的条件
+func HasSubQuery(where interface{}) bool {
+	switch w := where.(type) {
+	case map[string]interface{}:
+		for k, v := range w {
+			switch k {
+			case "$inQuery", "$notInQuery", "$select", "$dontSelect", "$relatedTo":
+				return true
+			}
+			if HasSubQuery(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range w {
+			if HasSubQuery(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subQuery 执行子查询，query 中 className 与 where 以外的字段作为查询选项，如 limit
+func subQuery(query interface{}) ([]t.M, bool) {
+	q, ok := query.(map[string]interface{})
+	if ok == false || SubQueryResolver == nil {
+		return nil, false
+	}
+	className, ok := q["className"].(string)
+	if ok == false || className == "" {
+		return nil, false
+	}
+	where := t.M{}
+	if w, ok := q["where"].(map[string]interface{}); ok {
+		where = w
+	}
+	options := t.M{}
+	for k, v := range q {
+		if k != "className" && k != "where" {
+			options[k] = v
+		}
+	}
+	results, err := SubQueryResolver(className, where, options)
+	if err != nil {
+		TLog.Error("Sub query on", className, "failed:", err)
+		return nil, false
+	}
+	return results, true
+}
+
+// matchesInQuery 检测 Pointer 字段是否在子查询的结果中，ok 为 false 时表示子查询无效
+func matchesInQuery(query, value interface{}) (matched bool, ok bool) {
+	results, ok := subQuery(query)
+	if ok == false {
+		return false, false
+	}
+	className, _ := query.(map[string]interface{})["className"].(string)
+	matched = anyValue(value, func(v interface{}) bool {
+		key, ok := pointerKey(v)
+		if ok == false {
 			return false
+		}
+		for _, result := range results {
+			objectID, _ := result["objectId"].(string)
+			if key == className+":"+objectID {
+				return true
+			}
+		}
+		return false
+	})
+	return matched, true
+}
+
+// matchesSelect 检测字段值是否在子查询结果的 key 字段中，ok 为 false 时表示子查询无效
+func matchesSelect(selectValue, value interface{}) (matched bool, ok bool) {
+	s, ok := selectValue.(map[string]interface{})
+	if ok == false {
+		return false, false
+	}
+	key, ok := s["key"].(string)
+	if ok == false || key == "" {
+		return false, false
+	}
+	results, ok := subQuery(s["query"])
+	if ok == false {
+		return false, false
+	}
+	matched = anyValue(value, func(v interface{}) bool {
+		for _, result := range results {
+			if equalValue(v, result[key]) {
+				return true
+			}
+		}
+		return false
+	})
+	return matched, true
+}
+
+// matchesRelatedTo 检测对象是否在 constraints 指定的 Relation 中，如：
+// {"object":{"__type":"Pointer","className":"Post","objectId":"abc"},"key":"likes"}
+func matchesRelatedTo(object t.M, constraints interface{}) bool {
+	c, ok := constraints.(map[string]interface{})
+	if ok == false {
+		return false
+	}
+	if _, ok := pointerKey(c["object"]); ok == false {
+		return false
+	}
+	if key, ok := c["key"].(string); ok == false || key == "" {
+		return false
+	}
+	className, _ := object["className"].(string)
+	objectID, _ := object["objectId"].(string)
+	if className == "" || objectID == "" {
+		return false
+	}
+	results, ok := subQuery(map[string]interface{}{
+		"className": className,
+		"where": map[string]interface{}{
+			"$relatedTo": c,
+			"objectId":   objectID,
+		},
+		"limit": float64(1),
+	})
+	return ok && len(results) > 0
+}
+
+// compareRegexpWithOptions 按 $options 比较正则表达式，支持 i m s x 四种选项
+func compareRegexpWithOptions(exp interface{}, options string, object interface{}) bool {
+	c, ok := exp.(string)
+	if ok == false {
+		return false
+	}
+	flags := ""
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		case 'x':
+			c = removeRegexpWhiteSpace(c)
 		default:
 			return false
 		}
 	}
+	if flags != "" {
+		c = "(?" + flags + ")" + c
+	}
+	return compareRegexp(c, object)
+}
 
-	return true
+// removeRegexpWhiteSpace 处理 x 选项，去除未转义的空白字符与 # 开头的注释
+func removeRegexpWhiteSpace(exp string) string {
+	var b strings.Builder
+	escaped := false
+	comment := false
+	for _, r := range exp {
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+			}
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			b.WriteRune(r)
+			escaped = true
+		case r == '#':
+			comment = true
+		case unicode.IsSpace(r) == false:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// maxDistanceInRadians 获取 $nearSphere 的最大距离，统一转换为弧度，未设置时返回 nil
+func maxDistanceInRadians(constraint t.M) interface{} {
+	if v, ok := toNumber(constraint["$maxDistance"]); ok {
+		return v
+	}
+	if v, ok := toNumber(constraint["$maxDistanceInRadians"]); ok {
+		return v
+	}
+	if v, ok := toNumber(constraint["$maxDistanceInMiles"]); ok {
+		return v / earthRadiusInMiles
+	}
+	if v, ok := toNumber(constraint["$maxDistanceInKilometers"]); ok {
+		return v / earthRadiusInKilometers
+	}
+	return nil
+}
+
+const (
+	earthRadiusInMiles      = 3958.8
+	earthRadiusInKilometers = 6371.0
+)
+
+// toGeoPoint 获取 GeoPoint 的经纬度，同时支持 [longitude, latitude] 格式
+func toGeoPoint(i interface{}) (longitude, latitude float64, ok bool) {
+	switch v := i.(type) {
+	case map[string]interface{}:
+		longitude, ok1 := toNumber(v["longitude"])
+		latitude, ok2 := toNumber(v["latitude"])
+		return longitude, latitude, ok1 && ok2
+	case []interface{}:
+		if len(v) != 2 {
+			return 0, 0, false
+		}
+		longitude, ok1 := toNumber(v[0])
+		latitude, ok2 := toNumber(v[1])
+		return longitude, latitude, ok1 && ok2
+	}
+	return 0, 0, false
+}
+
+// compareGeoWithin 校验一点是否在 $polygon $centerSphere 或者 $box 指定的区域内
+func compareGeoWithin(compareTo, point interface{}) bool {
+	geoWithin, ok := compareTo.(map[string]interface{})
+	if ok == false {
+		return false
+	}
+	x, y, ok := toGeoPoint(point)
+	if ok == false {
+		return false
+	}
+
+	if polygon, ok := geoWithin["$polygon"].([]interface{}); ok {
+		if len(polygon) < 3 {
+			return false
+		}
+		vertices := [][2]float64{}
+		for _, p := range polygon {
+			if v, ok := p.(map[string]interface{}); ok == false || v["__type"] != "GeoPoint" {
+				return false
+			}
+			px, py, ok := toGeoPoint(p)
+			if ok == false {
+				return false
+			}
+			vertices = append(vertices, [2]float64{px, py})
+		}
+		return inPolygon(x, y, vertices)
+	}
+
+	if centerSphere, ok := geoWithin["$centerSphere"].([]interface{}); ok {
+		if len(centerSphere) != 2 {
+			return false
+		}
+		cx, cy, ok := toGeoPoint(centerSphere[0])
+		if ok == false {
+			return false
+		}
+		radius, ok := toNumber(centerSphere[1])
+		if ok == false || radius < 0 {
+			return false
+		}
+		return distance(cx, cy, x, y) <= radius
+	}
+
+	if _, ok := geoWithin["$box"]; ok {
+		return compareBox(geoWithin, point)
+	}
+
+	return false
+}
+
+// compareGeoIntersects 校验 Polygon 类型的字段是否包含 $point 指定的点
+// Polygon 的坐标格式为 [[latitude, longitude], ...]
+func compareGeoIntersects(compareTo, polygon interface{}) bool {
+	geoIntersects, ok := compareTo.(map[string]interface{})
+	if ok == false {
+		return false
+	}
+	x, y, ok := toGeoPoint(geoIntersects["$point"])
+	if ok == false {
+		return false
+	}
+	p, ok := polygon.(map[string]interface{})
+	if ok == false || p["__type"] != "Polygon" {
+		return false
+	}
+	coordinates, ok := p["coordinates"].([]interface{})
+	if ok == false || len(coordinates) < 3 {
+		return false
+	}
+	vertices := [][2]float64{}
+	for _, c := range coordinates {
+		latitude, longitude, ok := toGeoPoint(c)
+		if ok == false {
+			return false
+		}
+		vertices = append(vertices, [2]float64{longitude, latitude})
+	}
+	return inPolygon(x, y, vertices)
+}
+
+// inPolygon 使用射线法判断点 (x, y) 是否在多边形内，在边上时视为在多边形内
+func inPolygon(x, y float64, vertices [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+		xi, yi := vertices[i][0], vertices[i][1]
+		xj, yj := vertices[j][0], vertices[j][1]
+		// 点在边上
+		if (x-xi)*(yj-yi) == (y-yi)*(xj-xi) &&
+			math.Min(xi, xj) <= x && x <= math.Max(xi, xj) &&
+			math.Min(yi, yj) <= y && y <= math.Max(yi, yj) {
+			return true
+		}
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// compareText 全文搜索，$term 中的词有一个出现即符合，引号内的短语必须出现， - 开头的词不能出现
+// 默认不区分大小写与变音符号，可通过 $caseSensitive 与 $diacriticSensitive 修改
+// 与数据库的全文索引不同，不做词干提取与停用词处理，如 apple 不匹配 apples ，订阅条件中的词需要与对象中的词完全一致
+func compareText(compareTo, object interface{}) bool {
+	text, ok := compareTo.(map[string]interface{})
+	if ok == false {
+		return false
+	}
+	search, ok := text["$search"].(map[string]interface{})
+	if ok == false {
+		return false
+	}
+	term, ok := search["$term"].(string)
+	if ok == false {
+		return false
+	}
+	s, ok := object.(string)
+	if ok == false {
+		return false
+	}
+	caseSensitive, _ := search["$caseSensitive"].(bool)
+	diacriticSensitive, _ := search["$diacriticSensitive"].(bool)
+	normalize := func(s string) string {
+		if caseSensitive == false {
+			s = strings.ToLower(s)
+		}
+		if diacriticSensitive == false {
+			s = removeDiacritics(s)
+		}
+		return s
+	}
+
+	s = normalize(s)
+	words := map[string]bool{}
+	for _, w := range textWords(s) {
+		words[w] = true
+	}
+
+	phrases, terms := splitTextTerm(normalize(term))
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) == false {
+			return false
+		}
+	}
+	matched := len(phrases) > 0
+	for _, w := range terms {
+		if strings.HasPrefix(w, "-") {
+			for _, n := range textWords(w[1:]) {
+				if words[n] {
+					return false
+				}
+			}
+			continue
+		}
+		for _, n := range textWords(w) {
+			if words[n] {
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// splitTextTerm 拆分搜索词，返回引号内的短语与其他的词
+func splitTextTerm(term string) (phrases []string, terms []string) {
+	parts := strings.Split(term, `"`)
+	for i, part := range parts {
+		if i%2 == 1 {
+			if part = strings.TrimSpace(part); part != "" {
+				phrases = append(phrases, part)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return phrases, terms
+}
+
+// textWords 按非字母数字字符拆分单词
+func textWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r) == false && unicode.IsNumber(r) == false
+	})
+}
+
+// removeDiacritics 去除变音符号，如 é 转换为 e
+func removeDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) == false {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
 }
 
 func equalObjectsGeneric(object, compareTo interface{}, eqlFn func(obj, cmp interface{}) bool) bool {
@@ -363,7 +973,8 @@ func distance(x1, y1, x2, y2 float64) float64 {
 	x2 = x2 * math.Pi / 180
 	y2 = y2 * math.Pi / 180
 	// d=R*arcos[cos(Y1)*cos(Y2)*cos(X1-X2)+sin(Y1)*sin(Y2)]
-	return math.Acos(math.Cos(y1)*math.Cos(y2)*math.Cos(x1-x2) + math.Sin(y1)*math.Sin(y2))
+	// 浮点误差可能使结果略大于 1 ，导致两点重合时返回 NaN
+	return math.Acos(math.Min(1, math.Cos(y1)*math.Cos(y2)*math.Cos(x1-x2)+math.Sin(y1)*math.Sin(y2)))
 }
 
 // compareRegexp 比较 object 是否符合正则表达式 exp
@@ -1056,3 +1056,80 @@ func Test_equalObject(t *testing.T) {
 		}
 	}
 }
+
+func Test_ChangedFields(t *testing.T) {
+	data := []struct {
+		original tp.M
+		current  tp.M
+		expect   map[string]bool
+	}{
+		{
+			original: nil,
+			current:  tp.M{"name": "joe"},
+			expect:   map[string]bool{"name": true},
+		},
+		{
+			original: tp.M{"name": "joe", "age": 12.0},
+			current:  tp.M{"name": "joe", "age": 13.0},
+			expect:   map[string]bool{"age": true},
+		},
+		{
+			original: tp.M{"name": "joe", "tags": []interface{}{"a"}},
+			current:  tp.M{"tags": []interface{}{"a"}},
+			expect:   map[string]bool{"name": true},
+		},
+		{
+			original: tp.M{"meta": map[string]interface{}{"w": 1.0}},
+			current:  tp.M{"meta": map[string]interface{}{"w": 2.0}},
+			expect:   map[string]bool{"meta": true},
+		},
+		{
+			original: tp.M{"name": "joe"},
+			current:  tp.M{"name": "joe"},
+			expect:   map[string]bool{},
+		},
+	}
+	for _, d := range data {
+		result := ChangedFields(d.original, d.current)
+		if reflect.DeepEqual(d.expect, result) == false {
+			t.Error("expect:", d.expect, "result:", result)
+		}
+	}
+}
+
+func Test_HasSubQuery(t *testing.T) {
+	type testData struct {
+		where  interface{}
+		expect bool
+	}
+	data := []testData{
+		{where: nil, expect: false},
+		{where: map[string]interface{}{"name": "joe"}, expect: false},
+		{where: map[string]interface{}{"age": map[string]interface{}{"$gt": 10.0}}, expect: false},
+		{
+			where:  map[string]interface{}{"user": map[string]interface{}{"$inQuery": map[string]interface{}{"className": "_User"}}},
+			expect: true,
+		},
+		{
+			where:  map[string]interface{}{"name": map[string]interface{}{"$dontSelect": map[string]interface{}{}}},
+			expect: true,
+		},
+		{
+			where:  map[string]interface{}{"$relatedTo": map[string]interface{}{}},
+			expect: true,
+		},
+		{
+			where: map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"name": "joe"},
+				map[string]interface{}{"user": map[string]interface{}{"$notInQuery": map[string]interface{}{}}},
+			}},
+			expect: true,
+		},
+	}
+	for _, d := range data {
+		result := HasSubQuery(d.where)
+		if result != d.expect {
+			t.Error("expect:", d.expect, "result:", result, d.where)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package orm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// Test_FindConformance 使用与 livequery/utils 相同的用例，校验 DBController.Find 与 LiveQuery 的匹配结果一致
+// subQuery 的用例由 rest 层展开子查询，在 rest 的 Test_FindConformance 中校验
+func Test_FindConformance(t *testing.T) {
+	data, err := ioutil.ReadFile("../livequery/utils/testdata/conformance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Classes   map[string]types.M   `json:"classes"`
+		Objects   map[string][]types.M `json:"objects"`
+		Relations []struct {
+			ClassName  string   `json:"className"`
+			ObjectID   string   `json:"objectId"`
+			Key        string   `json:"key"`
+			RelatedIDs []string `json:"relatedIds"`
+		} `json:"relations"`
+		Cases []struct {
+			Name      string   `json:"name"`
+			ClassName string   `json:"className"`
+			Where     types.M  `json:"where"`
+			Expect    []string `json:"expect"`
+			SubQuery  bool     `json:"subQuery"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	initEnv()
+	for className, schema := range suite.Classes {
+		Adapter.CreateClass(className, schema)
+		for _, object := range suite.Objects[className] {
+			Adapter.CreateObject(className, schema, object)
+		}
+	}
+	for _, r := range suite.Relations {
+		for _, id := range r.RelatedIDs {
+			TomatoDBController.addRelation(r.Key, r.ClassName, r.ObjectID, id)
+		}
+	}
+	// $text 查询需要全文索引
+	TomatoDBController.CreateIndex("Item", []string{"$text:description"})
+	defer TomatoDBController.DeleteEverything()
+
+	for _, c := range suite.Cases {
+		if c.SubQuery {
+			continue
+		}
+		results, err := TomatoDBController.Find(c.ClassName, c.Where, types.M{})
+		result := []string{}
+		for _, object := range results {
+			result = append(result, utils.S(utils.M(object)["objectId"]))
+		}
+		sort.Strings(result)
+		if err != nil || reflect.DeepEqual(c.Expect, result) == false {
+			t.Error(c.Name, "expect:", c.Expect, "result:", result, err)
+		}
+	}
+}
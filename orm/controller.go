@@ -470,25 +470,7 @@ func (d *DBController) Create(className string, object, options types.M) error {
 	if object == nil {
 		object = types.M{}
 	}
-	// 复制数据，不要修改原数据
-	object = utils.CopyMapM(object)
-
-	object = transformObjectACL(object)
-
-	if v, ok := object["createdAt"]; ok {
-		if reflect.TypeOf(v).String() == "string" {
-			object["createdAt"] = types.M{
-				"__type": "Date",
-				"iso":    v,
-			}
-		}
-	}
-	if v, ok := object["updatedAt"]; ok {
-		object["updatedAt"] = types.M{
-			"__type": "Date",
-			"iso":    v,
-		}
-	}
+	object = prepareObjectForCreate(object)
 
 	isMaster := false
 	aclGroup := []string{}
@@ -539,6 +521,64 @@ func (d *DBController) Create(className string, object, options types.M) error {
 	return d.handleRelationUpdates(className, "", object, relationUpdates)
 }
 
+// CreateObjects 批量创建对象，仅用于写入内部的系统表，不校验权限，不处理 Relation
+func (d *DBController) CreateObjects(className string, objects []types.M) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	err := d.validateClassName(className)
+	if err != nil {
+		return err
+	}
+
+	schema := d.LoadSchema(nil)
+	err = schema.EnforceClassExists(className)
+	if err != nil {
+		return err
+	}
+
+	schema.reloadData(nil)
+
+	sch, err := schema.GetOneSchema(className, true, nil)
+	if err != nil {
+		return err
+	}
+
+	adapterObjects := []types.M{}
+	for _, object := range objects {
+		object = prepareObjectForCreate(object)
+		transformAuthData(className, object, sch)
+		flattenUpdateOperatorsForCreate(object)
+		adapterObjects = append(adapterObjects, object)
+	}
+
+	return Adapter.CreateObjects(className, convertSchemaToAdapterSchema(sch), adapterObjects)
+}
+
+// prepareObjectForCreate 复制待创建的对象，转换 ACL 与 createdAt 、 updatedAt 的格式
+func prepareObjectForCreate(object types.M) types.M {
+	// 复制数据，不要修改原数据
+	object = utils.CopyMapM(object)
+
+	object = transformObjectACL(object)
+
+	if v, ok := object["createdAt"]; ok {
+		if reflect.TypeOf(v).String() == "string" {
+			object["createdAt"] = types.M{
+				"__type": "Date",
+				"iso":    v,
+			}
+		}
+	}
+	if v, ok := object["updatedAt"]; ok {
+		object["updatedAt"] = types.M{
+			"__type": "Date",
+			"iso":    v,
+		}
+	}
+	return object
+}
+
 // validateClassName 校验表名是否合法
 func (d *DBController) validateClassName(className string) error {
 	if ClassNameIsValid(className) == false {
@@ -1167,6 +1207,11 @@ func (d *DBController) owningIds(className, key string, relatedIds types.S) type
 
 // filterSensitiveData 对 _User 表数据进行特殊处理
 func filterSensitiveData(isMaster bool, aclGroup []string, className string, object types.M) types.M {
+	// API Key 的哈希值不对外返回
+	if className == "_ApiKey" && object != nil {
+		delete(object, "keyHash")
+		return object
+	}
 	if className != "_User" {
 		return object
 	}
@@ -1351,6 +1396,8 @@ func (d *DBController) PerformInitialization() {
 	Adapter.EnsureUniqueness("_User", requiredUserFields, []string{"email"})
 	Adapter.EnsureUniqueness("_Role", requiredRoleFields, []string{"name"})
 	Adapter.PerformInitialization(types.M{"VolatileClassesSchemas": volatileClassesSchemas()})
+	// 用于按 push_hash 与设备查询未打开的推送记录
+	Adapter.CreateIndex("_PushDelivery", []string{"pushHash", "installationId", "openedAt"})
 }
 
 func addWriteACL(query types.M, acl []string) types.M {
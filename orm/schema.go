@@ -17,9 +17,9 @@ import (
 var clpValidKeys = []string{"find", "count", "get", "create", "update", "delete", "addField", "readUserFields", "writeUserFields"}
 
 // SystemClasses 系统表
-var SystemClasses = []string{"_User", "_Installation", "_Role", "_Session", "_Product", "_PushStatus", "_JobStatus", "_Audience", "_JobSchedule"}
+var SystemClasses = []string{"_User", "_Installation", "_Role", "_Session", "_Product", "_PushStatus", "_JobStatus", "_Audience", "_JobSchedule", "_ApiKey", "_AuditLog", "_PushJob", "_PushDelivery", "_PushBatch"}
 
-var volatileClasses = []string{"_JobStatus", "_PushStatus", "_Hooks", "_GlobalConfig", "_Audience", "_JobSchedule"}
+var volatileClasses = []string{"_JobStatus", "_PushStatus", "_Hooks", "_GlobalConfig", "_Audience", "_JobSchedule", "_ApiKey", "_AuditLog", "_PushJob", "_PushDelivery", "_PushBatch"}
 
 // DefaultColumns 所有类的默认字段，以及系统类的默认字段
 var DefaultColumns = map[string]types.M{
@@ -50,6 +50,7 @@ var DefaultColumns = map[string]types.M{
 		"appName":          types.M{"type": "String"},
 		"appIdentifier":    types.M{"type": "String"},
 		"parseVersion":     types.M{"type": "String"},
+		"webPushKeys":      types.M{"type": "Object"}, // p256dh and auth of the web push subscription
 	},
 	"_Role": types.M{
 		"name":  types.M{"type": "String"},
@@ -74,20 +75,27 @@ var DefaultColumns = map[string]types.M{
 		"subtitle":          types.M{"type": "String"},
 	},
 	"_PushStatus": types.M{
-		"pushTime":      types.M{"type": "String"},
-		"source":        types.M{"type": "String"}, // rest or webui
-		"query":         types.M{"type": "String"}, // the stringified JSON query
-		"payload":       types.M{"type": "String"}, // the stringified payload,
-		"title":         types.M{"type": "String"},
-		"expiry":        types.M{"type": "Number"},
-		"status":        types.M{"type": "String"},
-		"numSent":       types.M{"type": "Number"},
-		"numFailed":     types.M{"type": "Number"},
-		"pushHash":      types.M{"type": "String"},
-		"errorMessage":  types.M{"type": "Object"},
-		"sentPerType":   types.M{"type": "Object"},
-		"failedPerType": types.M{"type": "Object"},
-		"count":         types.M{"type": "Number"},
+		"pushTime":          types.M{"type": "String"},
+		"source":            types.M{"type": "String"}, // rest or webui
+		"query":             types.M{"type": "String"}, // the stringified JSON query
+		"payload":           types.M{"type": "String"}, // the stringified payload,
+		"title":             types.M{"type": "String"},
+		"expiry":            types.M{"type": "Number"},
+		"status":            types.M{"type": "String"},
+		"numSent":           types.M{"type": "Number"},
+		"numFailed":         types.M{"type": "Number"},
+		"pushHash":          types.M{"type": "String"},
+		"errorMessage":      types.M{"type": "Object"},
+		"sentPerType":       types.M{"type": "Object"},
+		"failedPerType":     types.M{"type": "Object"},
+		"count":             types.M{"type": "Number"},
+		"numTokensRemoved":  types.M{"type": "Number"},
+		"numTokensReplaced": types.M{"type": "Number"},
+		"numDeadLettered":   types.M{"type": "Number"},
+		"sentPerLocale":     types.M{"type": "Object"},
+		"failedPerLocale":   types.M{"type": "Object"},
+		"numDeferred":       types.M{"type": "Number"},
+		"numOpened":         types.M{"type": "Number"},
 	},
 	"_JobStatus": types.M{
 		"jobName":    types.M{"type": "String"},
@@ -118,9 +126,54 @@ var DefaultColumns = map[string]types.M{
 		"params":   types.M{"type": "Object"},
 	},
 	"_Audience": types.M{
-		"objectId": types.M{"type": "String"},
-		"name":     types.M{"type": "String"},
-		"query":    types.M{"type": "String"},
+		"objectId":  types.M{"type": "String"},
+		"name":      types.M{"type": "String"},
+		"query":     types.M{"type": "String"}, // the stringified JSON query
+		"lastUsed":  types.M{"type": "Date"},
+		"timesUsed": types.M{"type": "Number"},
+	},
+	"_ApiKey": types.M{
+		"name":       types.M{"type": "String"},
+		"keyHash":    types.M{"type": "String"},
+		"classes":    types.M{"type": "Array"},
+		"operations": types.M{"type": "Array"},
+		"cidrs":      types.M{"type": "Array"},
+		"expiresAt":  types.M{"type": "Date"},
+		"lastUsedAt": types.M{"type": "Date"},
+	},
+	"_AuditLog": types.M{
+		"action":    types.M{"type": "String"},
+		"actor":     types.M{"type": "String"},
+		"ip":        types.M{"type": "String"},
+		"method":    types.M{"type": "String"},
+		"route":     types.M{"type": "String"},
+		"className": types.M{"type": "String"},
+		"targetId":  types.M{"type": "String"},
+		"changes":   types.M{"type": "String"}, // the stringified JSON diff
+	},
+	"_PushJob": types.M{
+		"channel":     types.M{"type": "String"},
+		"data":        types.M{"type": "String"}, // the stringified push work item
+		"status":      types.M{"type": "String"}, // pending or dead
+		"runAt":       types.M{"type": "Date"},
+		"lockedUntil": types.M{"type": "Date"},
+		"lastError":   types.M{"type": "String"},
+	},
+	"_PushDelivery": types.M{
+		"pushStatus":     types.M{"type": "String"}, // objectId of _PushStatus
+		"pushHash":       types.M{"type": "String"},
+		"installation":   types.M{"type": "String"}, // objectId of _Installation
+		"installationId": types.M{"type": "String"},
+		"deviceType":     types.M{"type": "String"},
+		"deviceToken":    types.M{"type": "String"},
+		"transmitted":    types.M{"type": "Boolean"},
+		"messageId":      types.M{"type": "String"}, // message id returned by the push service
+		"error":          types.M{"type": "String"},
+		"locale":         types.M{"type": "String"},
+		"openedAt":       types.M{"type": "Date"},
+	},
+	"_PushBatch": types.M{
+		"pushStatus": types.M{"type": "String"}, // objectId of _PushStatus, objectId is the batchId
 	},
 }
 
@@ -263,11 +316,42 @@ func (s *Schema) UpdateClass(className string, submittedFields types.M, classLev
 	defer s.dataMutex.Unlock()
 	s.permsMutex.Lock()
 	defer s.permsMutex.Unlock()
-	return types.M{
+	result := types.M{
 		"className":             className,
 		"fields":                utils.DeepCopy(s.data[className]),
 		"classLevelPermissions": utils.DeepCopy(s.perms[className]),
-	}, nil
+	}
+	if liveQuery, ok := schema["liveQuery"].(bool); ok && liveQuery {
+		result["liveQuery"] = true
+	}
+	return result, nil
+}
+
+// SetLiveQuery 设置类是否开启 LiveQuery ，仅支持已存在的非易失类
+func (s *Schema) SetLiveQuery(className string, enabled bool) error {
+	schema, err := s.GetOneSchema(className, false, nil)
+	if err != nil {
+		return err
+	}
+	if schema == nil || len(schema) == 0 || utils.M(schema["fields"]) == nil {
+		return errs.E(errs.InvalidClassName, "Class "+className+" does not exist.")
+	}
+	err = s.dbAdapter.SetClassLiveQuery(className, enabled)
+	if err != nil {
+		return err
+	}
+	s.reloadData(types.M{"clearCache": true})
+	return nil
+}
+
+// HasLiveQuery 类是否开启了 LiveQuery ，从 SchemaCache 中读取
+func (s *Schema) HasLiveQuery(className string) bool {
+	schema, err := s.GetOneSchema(className, false, nil)
+	if err != nil || schema == nil {
+		return false
+	}
+	liveQuery, _ := schema["liveQuery"].(bool)
+	return liveQuery
 }
 
 // deleteField 从类定义中删除指定的字段
@@ -1207,8 +1291,38 @@ func volatileClassesSchemas() []types.M {
 		"classLevelPermissions": types.M{},
 	}
 	jobScheduleSchema := convertSchemaToAdapterSchema(s)
+	s = types.M{
+		"className":             "_ApiKey",
+		"fields":                DefaultColumns["_ApiKey"],
+		"classLevelPermissions": types.M{},
+	}
+	apiKeySchema := convertSchemaToAdapterSchema(s)
+	s = types.M{
+		"className":             "_AuditLog",
+		"fields":                DefaultColumns["_AuditLog"],
+		"classLevelPermissions": types.M{},
+	}
+	auditLogSchema := convertSchemaToAdapterSchema(s)
+	s = types.M{
+		"className":             "_PushJob",
+		"fields":                DefaultColumns["_PushJob"],
+		"classLevelPermissions": types.M{},
+	}
+	pushJobSchema := convertSchemaToAdapterSchema(s)
+	s = types.M{
+		"className":             "_PushDelivery",
+		"fields":                DefaultColumns["_PushDelivery"],
+		"classLevelPermissions": types.M{},
+	}
+	pushDeliverySchema := convertSchemaToAdapterSchema(s)
+	s = types.M{
+		"className":             "_PushBatch",
+		"fields":                DefaultColumns["_PushBatch"],
+		"classLevelPermissions": types.M{},
+	}
+	pushBatchSchema := convertSchemaToAdapterSchema(s)
 
-	results = []types.M{hooksSchema, jobStatusSchema, jobScheduleSchema, pushStatusSchema, globalConfigSchema, audienceSchema}
+	results = []types.M{hooksSchema, jobStatusSchema, jobScheduleSchema, pushStatusSchema, globalConfigSchema, audienceSchema, apiKeySchema, auditLogSchema, pushJobSchema, pushDeliverySchema, pushBatchSchema}
 	return results
 }
 
@@ -1239,6 +1353,9 @@ func injectDefaultSchema(schema types.M) types.M {
 	newSchema["fields"] = newfields
 	newSchema["className"] = schema["className"]
 	newSchema["classLevelPermissions"] = schema["classLevelPermissions"]
+	if liveQuery, ok := schema["liveQuery"].(bool); ok && liveQuery {
+		newSchema["liveQuery"] = true
+	}
 
 	return newSchema
 }
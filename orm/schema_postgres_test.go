@@ -1405,24 +1405,31 @@ func TestPostgres_reloadData(t *testing.T) {
 			"authData":      types.M{"type": "Object"},
 		},
 		"_PushStatus": types.M{
-			"objectId":      types.M{"type": "String"},
-			"updatedAt":     types.M{"type": "Date"},
-			"createdAt":     types.M{"type": "Date"},
-			"ACL":           types.M{"type": "ACL"},
-			"pushTime":      types.M{"type": "String"},
-			"source":        types.M{"type": "String"},
-			"query":         types.M{"type": "String"},
-			"payload":       types.M{"type": "String"},
-			"title":         types.M{"type": "String"},
-			"expiry":        types.M{"type": "Number"},
-			"status":        types.M{"type": "String"},
-			"numSent":       types.M{"type": "Number"},
-			"numFailed":     types.M{"type": "Number"},
-			"pushHash":      types.M{"type": "String"},
-			"errorMessage":  types.M{"type": "Object"},
-			"sentPerType":   types.M{"type": "Object"},
-			"failedPerType": types.M{"type": "Object"},
-			"count":         types.M{"type": "Number"},
+			"objectId":          types.M{"type": "String"},
+			"updatedAt":         types.M{"type": "Date"},
+			"createdAt":         types.M{"type": "Date"},
+			"ACL":               types.M{"type": "ACL"},
+			"pushTime":          types.M{"type": "String"},
+			"source":            types.M{"type": "String"},
+			"query":             types.M{"type": "String"},
+			"payload":           types.M{"type": "String"},
+			"title":             types.M{"type": "String"},
+			"expiry":            types.M{"type": "Number"},
+			"status":            types.M{"type": "String"},
+			"numSent":           types.M{"type": "Number"},
+			"numFailed":         types.M{"type": "Number"},
+			"pushHash":          types.M{"type": "String"},
+			"errorMessage":      types.M{"type": "Object"},
+			"sentPerType":       types.M{"type": "Object"},
+			"failedPerType":     types.M{"type": "Object"},
+			"count":             types.M{"type": "Number"},
+			"numTokensRemoved":  types.M{"type": "Number"},
+			"numTokensReplaced": types.M{"type": "Number"},
+			"numDeadLettered":   types.M{"type": "Number"},
+			"sentPerLocale":     types.M{"type": "Object"},
+			"failedPerLocale":   types.M{"type": "Object"},
+			"numDeferred":       types.M{"type": "Number"},
+			"numOpened":         types.M{"type": "Number"},
 		},
 		"_JobStatus": types.M{
 			"objectId":   types.M{"type": "String"},
@@ -1398,24 +1398,31 @@ func Test_reloadData(t *testing.T) {
 			"authData":      types.M{"type": "Object"},
 		},
 		"_PushStatus": types.M{
-			"objectId":      types.M{"type": "String"},
-			"updatedAt":     types.M{"type": "Date"},
-			"createdAt":     types.M{"type": "Date"},
-			"ACL":           types.M{"type": "ACL"},
-			"pushTime":      types.M{"type": "String"},
-			"source":        types.M{"type": "String"},
-			"query":         types.M{"type": "String"},
-			"payload":       types.M{"type": "String"},
-			"title":         types.M{"type": "String"},
-			"expiry":        types.M{"type": "Number"},
-			"status":        types.M{"type": "String"},
-			"numSent":       types.M{"type": "Number"},
-			"numFailed":     types.M{"type": "Number"},
-			"pushHash":      types.M{"type": "String"},
-			"errorMessage":  types.M{"type": "Object"},
-			"sentPerType":   types.M{"type": "Object"},
-			"failedPerType": types.M{"type": "Object"},
-			"count":         types.M{"type": "Number"},
+			"objectId":          types.M{"type": "String"},
+			"updatedAt":         types.M{"type": "Date"},
+			"createdAt":         types.M{"type": "Date"},
+			"ACL":               types.M{"type": "ACL"},
+			"pushTime":          types.M{"type": "String"},
+			"source":            types.M{"type": "String"},
+			"query":             types.M{"type": "String"},
+			"payload":           types.M{"type": "String"},
+			"title":             types.M{"type": "String"},
+			"expiry":            types.M{"type": "Number"},
+			"status":            types.M{"type": "String"},
+			"numSent":           types.M{"type": "Number"},
+			"numFailed":         types.M{"type": "Number"},
+			"pushHash":          types.M{"type": "String"},
+			"errorMessage":      types.M{"type": "Object"},
+			"sentPerType":       types.M{"type": "Object"},
+			"failedPerType":     types.M{"type": "Object"},
+			"count":             types.M{"type": "Number"},
+			"numTokensRemoved":  types.M{"type": "Number"},
+			"numTokensReplaced": types.M{"type": "Number"},
+			"numDeadLettered":   types.M{"type": "Number"},
+			"sentPerLocale":     types.M{"type": "Object"},
+			"failedPerLocale":   types.M{"type": "Object"},
+			"numDeferred":       types.M{"type": "Number"},
+			"numOpened":         types.M{"type": "Number"},
 		},
 		"_JobStatus": types.M{
 			"objectId":   types.M{"type": "String"},
@@ -2858,25 +2865,32 @@ func Test_volatileClassesSchemas(t *testing.T) {
 		types.M{
 			"className": "_PushStatus",
 			"fields": types.M{
-				"objectId":      types.M{"type": "String"},
-				"createdAt":     types.M{"type": "Date"},
-				"updatedAt":     types.M{"type": "Date"},
-				"_rperm":        types.M{"type": "Array"},
-				"_wperm":        types.M{"type": "Array"},
-				"pushTime":      types.M{"type": "String"},
-				"source":        types.M{"type": "String"},
-				"query":         types.M{"type": "String"},
-				"payload":       types.M{"type": "String"},
-				"title":         types.M{"type": "String"},
-				"expiry":        types.M{"type": "Number"},
-				"status":        types.M{"type": "String"},
-				"numSent":       types.M{"type": "Number"},
-				"numFailed":     types.M{"type": "Number"},
-				"pushHash":      types.M{"type": "String"},
-				"errorMessage":  types.M{"type": "Object"},
-				"sentPerType":   types.M{"type": "Object"},
-				"failedPerType": types.M{"type": "Object"},
-				"count":         types.M{"type": "Number"},
+				"objectId":          types.M{"type": "String"},
+				"createdAt":         types.M{"type": "Date"},
+				"updatedAt":         types.M{"type": "Date"},
+				"_rperm":            types.M{"type": "Array"},
+				"_wperm":            types.M{"type": "Array"},
+				"pushTime":          types.M{"type": "String"},
+				"source":            types.M{"type": "String"},
+				"query":             types.M{"type": "String"},
+				"payload":           types.M{"type": "String"},
+				"title":             types.M{"type": "String"},
+				"expiry":            types.M{"type": "Number"},
+				"status":            types.M{"type": "String"},
+				"numSent":           types.M{"type": "Number"},
+				"numFailed":         types.M{"type": "Number"},
+				"pushHash":          types.M{"type": "String"},
+				"errorMessage":      types.M{"type": "Object"},
+				"sentPerType":       types.M{"type": "Object"},
+				"failedPerType":     types.M{"type": "Object"},
+				"count":             types.M{"type": "Number"},
+				"numTokensRemoved":  types.M{"type": "Number"},
+				"numTokensReplaced": types.M{"type": "Number"},
+				"numDeadLettered":   types.M{"type": "Number"},
+				"sentPerLocale":     types.M{"type": "Object"},
+				"failedPerLocale":   types.M{"type": "Object"},
+				"numDeferred":       types.M{"type": "Number"},
+				"numOpened":         types.M{"type": "Number"},
 			},
 			"classLevelPermissions": types.M{},
 		},
@@ -3004,6 +3018,35 @@ func Test_injectDefaultSchema(t *testing.T) {
 	if reflect.DeepEqual(expect, result) == false {
 		t.Error("expect:", expect, "result:", result)
 	}
+	/************************************************************/
+	schema = types.M{
+		"className": "post",
+		"liveQuery": true,
+	}
+	result = injectDefaultSchema(schema)
+	expect = types.M{
+		"className": "post",
+		"fields": types.M{
+			"objectId":  types.M{"type": "String"},
+			"createdAt": types.M{"type": "Date"},
+			"updatedAt": types.M{"type": "Date"},
+			"ACL":       types.M{"type": "ACL"},
+		},
+		"classLevelPermissions": nil,
+		"liveQuery":             true,
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	schema = types.M{
+		"className": "post",
+		"liveQuery": false,
+	}
+	result = injectDefaultSchema(schema)
+	if _, ok := result["liveQuery"]; ok {
+		t.Error("expect:", "no liveQuery", "result:", result)
+	}
 }
 
 func Test_convertSchemaToAdapterSchema(t *testing.T) {
@@ -3233,24 +3276,31 @@ func Test_Load(t *testing.T) {
 	schama = Load(adapter, schemaCache, nil)
 	expectData = types.M{
 		"_PushStatus": types.M{
-			"objectId":      types.M{"type": "String"},
-			"updatedAt":     types.M{"type": "Date"},
-			"createdAt":     types.M{"type": "Date"},
-			"ACL":           types.M{"type": "ACL"},
-			"pushTime":      types.M{"type": "String"},
-			"source":        types.M{"type": "String"},
-			"query":         types.M{"type": "String"},
-			"payload":       types.M{"type": "String"},
-			"title":         types.M{"type": "String"},
-			"expiry":        types.M{"type": "Number"},
-			"status":        types.M{"type": "String"},
-			"numSent":       types.M{"type": "Number"},
-			"numFailed":     types.M{"type": "Number"},
-			"pushHash":      types.M{"type": "String"},
-			"errorMessage":  types.M{"type": "Object"},
-			"sentPerType":   types.M{"type": "Object"},
-			"failedPerType": types.M{"type": "Object"},
-			"count":         types.M{"type": "Number"},
+			"objectId":          types.M{"type": "String"},
+			"updatedAt":         types.M{"type": "Date"},
+			"createdAt":         types.M{"type": "Date"},
+			"ACL":               types.M{"type": "ACL"},
+			"pushTime":          types.M{"type": "String"},
+			"source":            types.M{"type": "String"},
+			"query":             types.M{"type": "String"},
+			"payload":           types.M{"type": "String"},
+			"title":             types.M{"type": "String"},
+			"expiry":            types.M{"type": "Number"},
+			"status":            types.M{"type": "String"},
+			"numSent":           types.M{"type": "Number"},
+			"numFailed":         types.M{"type": "Number"},
+			"pushHash":          types.M{"type": "String"},
+			"errorMessage":      types.M{"type": "Object"},
+			"sentPerType":       types.M{"type": "Object"},
+			"failedPerType":     types.M{"type": "Object"},
+			"count":             types.M{"type": "Number"},
+			"numTokensRemoved":  types.M{"type": "Number"},
+			"numTokensReplaced": types.M{"type": "Number"},
+			"numDeadLettered":   types.M{"type": "Number"},
+			"sentPerLocale":     types.M{"type": "Object"},
+			"failedPerLocale":   types.M{"type": "Object"},
+			"numDeferred":       types.M{"type": "Number"},
+			"numOpened":         types.M{"type": "Number"},
 		},
 		"_JobStatus": types.M{
 			"objectId":   types.M{"type": "String"},
@@ -3312,24 +3362,31 @@ func Test_Load(t *testing.T) {
 			"ACL":       types.M{"type": "ACL"},
 		},
 		"_PushStatus": types.M{
-			"objectId":      types.M{"type": "String"},
-			"updatedAt":     types.M{"type": "Date"},
-			"createdAt":     types.M{"type": "Date"},
-			"ACL":           types.M{"type": "ACL"},
-			"pushTime":      types.M{"type": "String"},
-			"source":        types.M{"type": "String"},
-			"query":         types.M{"type": "String"},
-			"payload":       types.M{"type": "String"},
-			"title":         types.M{"type": "String"},
-			"expiry":        types.M{"type": "Number"},
-			"status":        types.M{"type": "String"},
-			"numSent":       types.M{"type": "Number"},
-			"numFailed":     types.M{"type": "Number"},
-			"pushHash":      types.M{"type": "String"},
-			"errorMessage":  types.M{"type": "Object"},
-			"sentPerType":   types.M{"type": "Object"},
-			"failedPerType": types.M{"type": "Object"},
-			"count":         types.M{"type": "Number"},
+			"objectId":          types.M{"type": "String"},
+			"updatedAt":         types.M{"type": "Date"},
+			"createdAt":         types.M{"type": "Date"},
+			"ACL":               types.M{"type": "ACL"},
+			"pushTime":          types.M{"type": "String"},
+			"source":            types.M{"type": "String"},
+			"query":             types.M{"type": "String"},
+			"payload":           types.M{"type": "String"},
+			"title":             types.M{"type": "String"},
+			"expiry":            types.M{"type": "Number"},
+			"status":            types.M{"type": "String"},
+			"numSent":           types.M{"type": "Number"},
+			"numFailed":         types.M{"type": "Number"},
+			"pushHash":          types.M{"type": "String"},
+			"errorMessage":      types.M{"type": "Object"},
+			"sentPerType":       types.M{"type": "Object"},
+			"failedPerType":     types.M{"type": "Object"},
+			"count":             types.M{"type": "Number"},
+			"numTokensRemoved":  types.M{"type": "Number"},
+			"numTokensReplaced": types.M{"type": "Number"},
+			"numDeadLettered":   types.M{"type": "Number"},
+			"sentPerLocale":     types.M{"type": "Object"},
+			"failedPerLocale":   types.M{"type": "Object"},
+			"numDeferred":       types.M{"type": "Number"},
+			"numOpened":         types.M{"type": "Number"},
 		},
 		"_JobStatus": types.M{
 			"objectId":   types.M{"type": "String"},
@@ -1,11 +1,23 @@
 package push
 
-import "github.com/JuShangEnergy/framework/livequery/pubsub"
+import (
+	"net/url"
+	"sync"
+
+	"github.com/JuShangEnergy/framework/livequery/pubsub"
+)
 
 var emitter = pubsub.NewEventEmitter()
 var subscriptions = map[string]pubsub.HandlerType{}
+var subscriptionsMutex sync.Mutex
 
 func unsubscribe(channel string) {
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+	removeSubscription(channel)
+}
+
+func removeSubscription(channel string) {
 	if subscriptions[channel] == nil {
 		return
 	}
@@ -31,7 +43,9 @@ type Consumer struct {
 
 // Subscribe ...
 func (c *Consumer) Subscribe(channel string) {
-	unsubscribe(channel)
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+	removeSubscription(channel)
 	var handler = func(args ...string) {
 		allArgs := []string{channel}
 		allArgs = append(allArgs, args...)
@@ -51,18 +65,40 @@ func (c *Consumer) On(channel string, listener pubsub.HandlerType) {
 	c.EventEmitter.On(channel, listener)
 }
 
-// CreatePublisher ...
-func CreatePublisher() pubsub.Publisher {
-	return &Publisher{
-		emitter: emitter,
+// CreatePublisher 创建推送队列的发布者
+// queueType 为空时使用进程内的 EventEmitter ，否则使用 pubsub 中注册的实现，如 RedisStreams 、 NATS
+func CreatePublisher(queueType, queueURL, queueConfig string) pubsub.Publisher {
+	if queueType == "" {
+		return &Publisher{
+			emitter: emitter,
+		}
 	}
+	return pubsub.CreatePublisher(queueType, queueURL, queueConfig)
 }
 
-// CreateSubscriber ...
-func CreateSubscriber() pubsub.Subscriber {
-	c := &Consumer{
-		emitter: emitter,
+// CreateSubscriber 创建推送队列的订阅者，参数同 CreatePublisher
+func CreateSubscriber(queueType, queueURL, queueConfig string) pubsub.Subscriber {
+	if queueType == "" {
+		c := &Consumer{
+			emitter: emitter,
+		}
+		c.Init()
+		return c
+	}
+	return pubsub.CreateSubscriber(queueType, queueURL, queueConfig)
+}
+
+// queueConfigWithGroup 未设置 group 时使用 channel 作为 group
+// 推送任务只需处理一次，多个节点使用相同的 group 才能分摊任务，而不是每个节点都发送一遍
+// 仅 RedisStreams 与 NATS 支持 group
+func queueConfigWithGroup(queueType, queueConfig, channel string) string {
+	if queueType != "RedisStreams" && queueType != "NATS" {
+		return queueConfig
+	}
+	values, err := url.ParseQuery(queueConfig)
+	if err != nil || values.Get("group") != "" {
+		return queueConfig
 	}
-	c.Init()
-	return c
+	values.Set("group", channel)
+	return values.Encode()
 }
@@ -3,10 +3,12 @@ package push
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
+	"time"
 
-	"github.com/JuShangEnergy/framework/livequery/pubsub"
 	"github.com/JuShangEnergy/framework/rest"
 	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
 )
 
 const (
@@ -14,71 +16,117 @@ const (
 	defaultBatchSize = 100
 )
 
-// pushQueue ...
+// pushQueue 把推送拆分为批次加入任务队列
 type pushQueue struct {
-	parsePublisher pubsub.Publisher
-	channel        string
-	batchSize      int
+	jobs      jobQueue
+	batchSize int
 }
 
-func newPushQueue(channel string, batchSize int) *pushQueue {
-	if channel == "" {
-		channel = pushChannel
-	}
+func newPushQueue(jobs jobQueue, batchSize int) *pushQueue {
 	if batchSize == 0 {
 		batchSize = defaultBatchSize
 	}
 	return &pushQueue{
-		parsePublisher: CreatePublisher(),
-		channel:        channel,
-		batchSize:      batchSize,
+		jobs:      jobs,
+		batchSize: batchSize,
 	}
 }
 
+// enqueue 加入推送任务时按 objectId 记录全部设备，每个批次只包含其中的一段 objectId
+// 推送期间清除失效的 deviceToken 或新增设备不会影响其他批次，避免按 skip 分页时遗漏设备
 func (q *pushQueue) enqueue(body, where types.M, auth *rest.Auth, status *pushStatus) error {
-	limit := q.batchSize
-
 	where = ApplyDeviceTokenExists(where)
 
-	// Order by objectId so no impact on the DB
-	order := "objectId"
-	options := types.M{
-		"limit": 0,
-		"count": true,
-	}
-	result, err := rest.Find(auth, "_Installation", where, options, nil)
+	batches, count, err := q.snapshot(auth, where)
 	if err != nil {
 		return err
 	}
 
-	count := 0
-	if c, ok := result["count"].(int); ok {
-		count = c
-	}
-
 	if count == 0 {
 		return errors.New("PushController: no results in query")
 	}
 	status.setRunning(count)
 
-	for skip := 0; skip < count; skip += limit {
+	for i, objectIDs := range batches {
 		query := types.M{
-			"where": where,
-			"limit": limit,
-			"skip":  skip,
-			"order": order,
+			"where": batchWhere(where, objectIDs),
+			"limit": len(objectIDs),
+			"order": "objectId",
 		}
+		// batchId 用于推送状态只统计一次， count 为该批次的设备数
 		pushWorkItem := types.M{
 			"body":       body,
 			"query":      query,
-			"pushStatus": types.M{"objectId": status.objectID},
+			"pushStatus": types.M{"objectId": status.objectID, "pushHash": status.pushHash},
+			"batchId":    status.objectID + ":" + strconv.Itoa(i*q.batchSize),
+			"count":      len(objectIDs),
+			"attempts":   0,
 		}
 		b, err := json.Marshal(pushWorkItem)
 		if err != nil {
 			return err
 		}
-		q.parsePublisher.Publish(q.channel, string(b))
+		if err := q.jobs.enqueue(string(b), time.Time{}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// snapshot 按 objectId 递增分页查询符合条件的设备，返回每个批次的 objectId 列表与设备总数
+func (q *pushQueue) snapshot(auth *rest.Auth, where types.M) ([][]string, int, error) {
+	batches := [][]string{}
+	count := 0
+	lastID := ""
+	for {
+		pageWhere := where
+		if lastID != "" {
+			pageWhere = restrictObjectID(where, types.M{"$gt": lastID})
+		}
+		options := types.M{
+			"keys":  "objectId",
+			"limit": q.batchSize,
+			"order": "objectId",
+		}
+		response, err := rest.Find(auth, "_Installation", pageWhere, options, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		objectIDs := []string{}
+		for _, v := range utils.A(response["results"]) {
+			if objectID := utils.S(utils.M(v)["objectId"]); objectID != "" {
+				objectIDs = append(objectIDs, objectID)
+			}
+		}
+		if len(objectIDs) == 0 {
+			break
+		}
+		batches = append(batches, objectIDs)
+		count += len(objectIDs)
+		lastID = objectIDs[len(objectIDs)-1]
+		if len(objectIDs) < q.batchSize {
+			break
+		}
+	}
+	return batches, count, nil
+}
+
+// batchWhere 生成只包含指定 objectId 的批次查询条件
+func batchWhere(where types.M, objectIDs []string) types.M {
+	ids := types.S{}
+	for _, objectID := range objectIDs {
+		ids = append(ids, objectID)
+	}
+	return restrictObjectID(where, types.M{"$in": ids})
+}
+
+// restrictObjectID 在查询条件中增加 objectId 的限制，原条件中已有 objectId 时使用 $and 合并
+func restrictObjectID(where types.M, constraint types.M) types.M {
+	if _, ok := where["objectId"]; ok {
+		return types.M{"$and": types.S{where, types.M{"objectId": constraint}}}
+	}
+	where = utils.CopyMapM(where)
+	where["objectId"] = constraint
+	return where
+}
@@ -2,9 +2,12 @@ package push
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"strconv"
+	"sync"
+	"time"
 
-	"github.com/JuShangEnergy/framework/livequery/pubsub"
 	"github.com/JuShangEnergy/framework/rest"
 	"github.com/JuShangEnergy/framework/types"
 	"github.com/JuShangEnergy/framework/utils"
@@ -36,48 +39,187 @@ func groupByBadge(installations types.S) map[string]types.S {
 	return result
 }
 
+const (
+	defaultMaxAttempts   = 5
+	defaultRetryInterval = 10 * time.Second
+	// maxRetryInterval 重试间隔的上限
+	maxRetryInterval = time.Hour
+	// jobLease 任务的锁定时长，处理任务的节点异常退出后，超过该时长任务会被再次处理
+	jobLease = 5 * time.Minute
+	// pausedPollInterval 推送暂停时，任务延迟该时长后再次检查推送状态
+	pausedPollInterval = 30 * time.Second
+)
+
+// errPushPaused 推送已暂停，任务延迟处理且不计入尝试次数
+var errPushPaused = errors.New("push is paused")
+
+// pushWorker 从任务队列中取出推送任务并发送
+// 任务失败时按指数退避重试，推送服务暂时不可用的设备单独重试，超过 maxAttempts 次后移入死信存储
+// 设置了 quietHours 时，处于免打扰时段的设备延迟到时段结束后发送
+// 已取消的推送不再发送，已暂停的推送每隔 pausedPollInterval 检查一次状态
 type pushWorker struct {
-	subscriber pubsub.Subscriber
-	adapter    pushAdapter
-	channel    string
+	jobs          jobQueue
+	adapter       pushAdapter
+	maxAttempts   int
+	retryInterval time.Duration
+	quietHours    *quietHours
+	// handle 处理推送任务， final 为 true 表示最后一次尝试
+	// 返回 retry 不为 nil 表示其中的设备需要重试，返回 err 表示整个任务需要重试
+	handle func(workItem types.M, final bool) (retry types.M, err error)
+	once   sync.Once
+	done   chan struct{}
 }
 
-func newPushWorker(adapter pushAdapter, channel string) *pushWorker {
-	if channel == "" {
-		channel = pushChannel
+func newPushWorker(adapter pushAdapter, jobs jobQueue, maxAttempts int, retryInterval time.Duration, quiet *quietHours) *pushWorker {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if retryInterval <= 0 {
+		retryInterval = defaultRetryInterval
 	}
-	subscriber := CreateSubscriber()
 	worker := &pushWorker{
-		subscriber: subscriber,
-		adapter:    adapter,
-		channel:    channel,
+		jobs:          jobs,
+		adapter:       adapter,
+		maxAttempts:   maxAttempts,
+		retryInterval: retryInterval,
+		quietHours:    quiet,
+		done:          make(chan struct{}),
+	}
+	worker.handle = worker.run
+	return worker
+}
+
+// start 开始处理任务，重复调用无效
+func (p *pushWorker) start() {
+	p.once.Do(func() {
+		go p.loop()
+	})
+}
+
+// stop 停止处理任务
+func (p *pushWorker) stop() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
 	}
+}
 
-	subscriber.Subscribe(channel)
-	subscriber.On("message", func(args ...string) {
-		if len(args) < 2 {
+func (p *pushWorker) loop() {
+	for {
+		select {
+		case <-p.done:
 			return
+		default:
 		}
-		messageStr := args[1]
-		var workItem types.M
-		err := json.Unmarshal([]byte(messageStr), &workItem)
+		job, err := p.jobs.claim(jobLease)
 		if err != nil {
+			log.Println("claim push job failed:", err)
+			time.Sleep(jobQueuePollInterval)
+			continue
+		}
+		if job != nil {
+			p.process(job)
+		}
+	}
+}
+
+// backoff 第 attempts 次失败后的重试间隔，每次翻倍，不超过 maxRetryInterval
+func (p *pushWorker) backoff(attempts int) time.Duration {
+	interval := p.retryInterval
+	for i := 1; i < attempts && interval < maxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxRetryInterval {
+		interval = maxRetryInterval
+	}
+	return interval
+}
+
+// process 处理一个任务，根据结果完成、重试或移入死信存储
+func (p *pushWorker) process(job *pushJob) {
+	// 已被删除的消息
+	if job.data == "" {
+		p.jobs.complete(job)
+		return
+	}
+	var workItem types.M
+	err := json.Unmarshal([]byte(job.data), &workItem)
+	if err != nil {
+		p.jobs.deadLetter(job, err.Error())
+		return
+	}
+
+	attempts := 0
+	if v, ok := workItem["attempts"].(float64); ok {
+		attempts = int(v)
+	}
+	final := attempts+1 >= p.maxAttempts
+
+	retry, err := p.handle(workItem, final)
+	if err == errPushPaused {
+		p.jobs.retry(job, job.data, time.Now().Add(pausedPollInterval))
+		return
+	}
+	if err != nil {
+		if final {
+			p.jobs.deadLetter(job, err.Error())
 			return
 		}
-		worker.run(workItem)
-	})
+		retry = workItem
+		retry["lastError"] = err.Error()
+	}
+	if retry == nil {
+		p.jobs.complete(job)
+		return
+	}
 
-	return worker
+	retry["attempts"] = attempts + 1
+	data, err := json.Marshal(retry)
+	if err != nil {
+		p.jobs.deadLetter(job, err.Error())
+		return
+	}
+	p.jobs.retry(job, string(data), time.Now().Add(p.backoff(attempts+1)))
 }
 
-func (p *pushWorker) unsubscribe() {
-	p.subscriber.Unsubscribe(p.channel)
+// run 查询任务中的设备并发送，返回需要重试的任务
+// 推送服务暂时不可用的设备生成新的批次重试，最后一次尝试时记为发送失败
+// 最后一次尝试仍然出错时，任务中的设备均记为发送失败
+func (p *pushWorker) run(workItem types.M, final bool) (types.M, error) {
+	status := utils.M(workItem["pushStatus"])
+	pushStatus := newPushStatus(utils.S(status["objectId"]))
+	pushStatus.pushHash = utils.S(status["pushHash"])
+	batchID := utils.S(workItem["batchId"])
+
+	retry, err := p.send(pushStatus, workItem, final)
+	if err != nil && err != errPushPaused && final {
+		if count, ok := workItem["count"].(float64); ok {
+			pushStatus.trackDeadLetter(batchID, int(count))
+		} else {
+			pushStatus.fail(err)
+		}
+	}
+	return retry, err
 }
 
-func (p *pushWorker) run(workItem types.M) error {
+func (p *pushWorker) send(pushStatus *pushStatus, workItem types.M, final bool) (types.M, error) {
 	body := utils.M(workItem["body"])
-	query := utils.M(workItem["query"])
-	status := utils.M(workItem["pushStatus"])
+	query := utils.CopyMapM(utils.M(workItem["query"]))
+	batchID := utils.S(workItem["batchId"])
+
+	// 任务重复投递时，已统计的批次不再发送
+	// 每个批次发送前检查推送状态，已取消的推送跳过，已暂停的推送延迟处理
+	state, tracked, err := pushStatus.batchState(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if tracked || state == "cancelled" {
+		return nil, nil
+	}
+	if state == "paused" {
+		return nil, errPushPaused
+	}
 
 	auth := rest.Master()
 	where := ApplyDeviceTokenExists(utils.M(query["where"]))
@@ -85,24 +227,144 @@ func (p *pushWorker) run(workItem types.M) error {
 
 	response, err := rest.Find(auth, "_Installation", where, query, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	installations := utils.A(response["results"])
+
+	// 处于免打扰时段的设备生成新的批次，在时段结束后发送
+	// 时段结束时推送已过期的设备不再延迟，记为发送失败
+	deferred := 0
+	expired := []types.M{}
+	if p.quietHours != nil {
+		var groups map[time.Time]types.S
+		installations, groups = p.quietHours.deferInstallations(installations, time.Now())
+		expired = expiredResults(expireDeferred(groups, toInt64(body["expiration_time"])))
+		for until, ins := range groups {
+			deferBatchID := ""
+			if batchID != "" {
+				deferBatchID = batchID + ":" + strconv.FormatInt(until.Unix(), 10)
+			}
+			item := narrowWorkItem(workItem, installationTokens(ins), deferBatchID)
+			item["attempts"] = 0
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.jobs.enqueue(string(data), until); err != nil {
+				return nil, err
+			}
+			deferred += len(ins)
+		}
+	}
+
+	results := []types.M{}
+	if len(installations) > 0 {
+		results = p.sendToAdapter(body, installations, pushStatus.objectID)
+		attachInstallations(results, installations)
+		pushStatus.trackDeviceTokens(cleanupDeviceTokens(results))
+	}
+	results = append(results, expired...)
+
+	sent, tokens := splitTransientResults(results, final)
+
+	settled := len(results) - len(tokens)
+	if count, ok := workItem["count"].(float64); ok {
+		settled = int(count) - len(tokens) - deferred
+	}
+	err = pushStatus.trackSent(batchID, sent, settled, deferred)
+	if err != nil {
+		return nil, err
 	}
-	if utils.HasResults(response) == false {
-		return nil
+	if len(tokens) == 0 {
+		return nil, nil
 	}
-	results := utils.A(response["results"])
 
-	return p.sendToAdapter(body, results, status)
+	// 仅重试推送服务暂时不可用的设备
+	retryBatchID := ""
+	if batchID != "" {
+		attempts := 0
+		if v, ok := workItem["attempts"].(float64); ok {
+			attempts = int(v)
+		}
+		retryBatchID = batchID + "." + strconv.Itoa(attempts+1)
+	}
+	return narrowWorkItem(workItem, tokens, retryBatchID), nil
 }
 
-func (p *pushWorker) sendToAdapter(body types.M, installations types.S, status types.M) error {
-	pushStatus := newPushStatus(utils.S(status["objectId"]))
+// narrowWorkItem 生成只包含指定设备的新批次
+func narrowWorkItem(workItem types.M, tokens []string, batchID string) types.M {
+	where := utils.CopyMapM(utils.M(utils.M(workItem["query"])["where"]))
+	if where == nil {
+		where = types.M{}
+	}
+	where["deviceToken"] = types.M{"$in": tokens}
+	item := utils.CopyMapM(workItem)
+	item["query"] = types.M{
+		"where": where,
+		"limit": len(tokens),
+		"order": "objectId",
+	}
+	item["count"] = len(tokens)
+	if batchID != "" {
+		item["batchId"] = batchID
+	}
+	return item
+}
+
+// installationTokens 返回设备的 deviceToken 列表
+func installationTokens(installations types.S) []string {
+	tokens := []string{}
+	for _, v := range installations {
+		if installation := utils.M(v); installation != nil {
+			tokens = append(tokens, utils.S(installation["deviceToken"]))
+		}
+	}
+	return tokens
+}
+
+// splitTransientResults 拆分推送结果，返回需要统计的结果与需要重试的 deviceToken
+// final 为 true 时不再重试，全部结果均需要统计
+func splitTransientResults(results []types.M, final bool) ([]types.M, []string) {
+	if final {
+		return results, nil
+	}
+	sent := []types.M{}
+	tokens := []string{}
+	for _, result := range results {
+		if isTransientResult(result) {
+			if device := utils.M(result["device"]); device != nil && utils.S(device["deviceToken"]) != "" {
+				tokens = append(tokens, utils.S(device["deviceToken"]))
+				continue
+			}
+		}
+		sent = append(sent, result)
+	}
+	return sent, tokens
+}
+
+// sendToAdapter 发送推送并返回结果
+// 设置了本地化 alert 或 title 的推送按设备语言分组发送，结果中的 locale 为匹配的语言
+// 角标自增的推送按角标分组发送
+func (p *pushWorker) sendToAdapter(body types.M, installations types.S, objectID string) []types.M {
+	if locales := getLocalesFromPush(body); len(locales) > 0 {
+		results := []types.M{}
+		bodies := bodiesPerLocales(body, locales)
+		for locale, ins := range groupByLocaleIdentifier(installations, locales) {
+			for _, result := range p.sendToAdapter(bodies[locale], ins, objectID) {
+				if result != nil {
+					result["locale"] = locale
+				}
+				results = append(results, result)
+			}
+		}
+		return results
+	}
 
 	if isPushIncrementing(body) == false {
-		results := p.adapter.send(body, installations, pushStatus.objectID)
-		return pushStatus.trackSent(results)
+		return p.adapter.send(body, installations, objectID)
 	}
 
+	results := []types.M{}
 	badgeInstallationsMap := groupByBadge(installations)
 
 	for badge, ins := range badgeInstallationsMap {
@@ -124,11 +386,8 @@ func (p *pushWorker) sendToAdapter(body types.M, installations types.S, status t
 
 		payload["data"] = data
 
-		err := p.sendToAdapter(payload, ins, types.M{"objectId": pushStatus.objectID})
-		if err != nil {
-			return err
-		}
+		results = append(results, p.sendToAdapter(payload, ins, objectID)...)
 	}
 
-	return nil
+	return results
 }
@@ -0,0 +1,340 @@
+package push
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+	// apnsTokenLifetime provider token 的有效期， APNs 要求 20 至 60 分钟内更新
+	apnsTokenLifetime = 50 * time.Minute
+	// apnsConcurrency 同时发送的最大请求数， HTTP/2 下共用同一连接
+	apnsConcurrency = 20
+	// apnsTimeout 单个请求的超时时间
+	apnsTimeout = 30 * time.Second
+)
+
+// apnsPushAdapter 通过 HTTP/2 直接向 APNs 发送推送，使用 .p8 私钥生成的 JWT 作为 provider token
+type apnsPushAdapter struct {
+	validPushTypes []string
+	host           string
+	topic          string
+	keyID          string
+	teamID         string
+	key            *ecdsa.PrivateKey
+	client         *http.Client
+	mutex          sync.Mutex
+	token          string
+	tokenIssuedAt  time.Time
+}
+
+func newAPNsPush() (*apnsPushAdapter, error) {
+	key, err := ioutil.ReadFile(config.TConfig.APNSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	host := apnsSandboxHost
+	if config.TConfig.APNSProduction {
+		host = apnsProductionHost
+	}
+	client := &http.Client{
+		Transport: &http.Transport{ForceAttemptHTTP2: true},
+		Timeout:   apnsTimeout,
+	}
+	return newAPNsAdapter(host, config.TConfig.APNSTopic, config.TConfig.APNSKeyID, config.TConfig.APNSTeamID, key, client)
+}
+
+// newAPNsAdapter 创建 APNs 推送模块， key 为 .p8 文件内容
+func newAPNsAdapter(host, topic, keyID, teamID string, key []byte, client *http.Client) (*apnsPushAdapter, error) {
+	privateKey, err := parseAPNsKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &apnsPushAdapter{
+		validPushTypes: []string{"ios", "osx", "tvos"},
+		host:           host,
+		topic:          topic,
+		keyID:          keyID,
+		teamID:         teamID,
+		key:            privateKey,
+		client:         client,
+	}, nil
+}
+
+// parseAPNsKey 解析 PKCS#8 格式的 .p8 私钥
+func parseAPNsKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("APNs key is not a valid PEM file")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(*ecdsa.PrivateKey)
+	if ok == false {
+		return nil, errors.New("APNs key is not an ECDSA private key")
+	}
+	return privateKey, nil
+}
+
+// providerToken 返回当前有效的 provider token ，过期后重新生成
+func (a *apnsPushAdapter) providerToken() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && time.Since(a.tokenIssuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+	now := time.Now()
+	token, err := signES256(a.key, types.M{"alg": "ES256", "kid": a.keyID}, types.M{"iss": a.teamID, "iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	a.tokenIssuedAt = now
+	return token, nil
+}
+
+// resetToken APNs 返回 ExpiredProviderToken 时，下次发送前重新生成 token
+func (a *apnsPushAdapter) resetToken() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = ""
+}
+
+// signES256 生成 ES256 签名的 JWT ，签名为 r 与 s 各 32 字节拼接
+func signES256(key *ecdsa.PrivateKey, header, claims types.M) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// apnsNotification 由 body 生成 APNs 的 payload 与请求头
+// data 中的 priority 、 pushType 、 collapse_id 用于设置请求头，不会出现在 payload 中
+func apnsNotification(body types.M) (types.M, http.Header) {
+	pushData := utils.M(body["data"])
+	if pushData == nil {
+		pushData = types.M{}
+	}
+	payload := types.M{}
+	aps := types.M{}
+	alert := types.M{}
+	header := http.Header{}
+	for key, v := range pushData {
+		switch key {
+		case "alert":
+			if m := utils.M(v); m != nil {
+				for k, vv := range m {
+					alert[k] = vv
+				}
+			} else {
+				alert["body"] = utils.S(v)
+			}
+		case "title", "subtitle":
+			alert[key] = utils.S(v)
+		case "badge":
+			aps["badge"] = v
+		case "sound", "category":
+			aps[key] = v
+		case "threadId":
+			aps["thread-id"] = v
+		case "content-available", "mutable-content":
+			aps[key] = 1
+		case "priority":
+			header.Set("apns-priority", strconv.FormatInt(toInt64(v), 10))
+		case "pushType":
+			header.Set("apns-push-type", utils.S(v))
+		case "collapse_id", "collapseId":
+			header.Set("apns-collapse-id", utils.S(v))
+		default:
+			payload[key] = v
+		}
+	}
+	if len(alert) > 0 {
+		aps["alert"] = alert
+	}
+	payload["aps"] = aps
+
+	// 仅包含 content-available 的消息为静默推送，需要使用 background 类型与 5 级优先级
+	background := aps["content-available"] != nil && aps["alert"] == nil && aps["badge"] == nil && aps["sound"] == nil
+	if header.Get("apns-push-type") == "" {
+		if background {
+			header.Set("apns-push-type", "background")
+		} else {
+			header.Set("apns-push-type", "alert")
+		}
+	}
+	if header.Get("apns-priority") == "" {
+		if background {
+			header.Set("apns-priority", "5")
+		} else {
+			header.Set("apns-priority", "10")
+		}
+	}
+	// expiration_time 单位为毫秒， APNs 需要的单位为秒
+	if t := toInt64(body["expiration_time"]); t > 0 {
+		header.Set("apns-expiration", strconv.FormatInt(t/1000, 10))
+	}
+	return payload, header
+}
+
+// toInt64 转换数字，经过推送队列后 int64 会变为 float64
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// sendToDevice 向单个设备发送推送，返回 trackSent 需要的结果
+// 发送失败时 response 中包含 APNs 返回的 status 与 reason ，设备已失效时还包含 timestamp
+func (a *apnsPushAdapter) sendToDevice(device types.M, payload []byte, header http.Header) types.M {
+	result := types.M{
+		"device":      device,
+		"transmitted": false,
+	}
+	fail := func(err error) types.M {
+		result["response"] = types.M{"error": err.Error(), "reason": unavailable}
+		return result
+	}
+
+	token, err := a.providerToken()
+	if err != nil {
+		return fail(err)
+	}
+	request, err := http.NewRequest(http.MethodPost, a.host+"/3/device/"+utils.S(device["deviceToken"]), bytes.NewReader(payload))
+	if err != nil {
+		return fail(err)
+	}
+	for key := range header {
+		request.Header.Set(key, header.Get(key))
+	}
+	topic := a.topic
+	if appIdentifier := utils.S(device["appIdentifier"]); appIdentifier != "" {
+		topic = appIdentifier
+	}
+	if header.Get("apns-push-type") == "voip" {
+		topic += ".voip"
+	}
+	request.Header.Set("apns-topic", topic)
+	request.Header.Set("authorization", "bearer "+token)
+	request.Header.Set("content-type", "application/json")
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return fail(err)
+	}
+	defer response.Body.Close()
+	data, _ := ioutil.ReadAll(response.Body)
+
+	res := types.M{
+		"status":  response.StatusCode,
+		"apns-id": response.Header.Get("apns-id"),
+	}
+	result["response"] = res
+	if response.StatusCode == http.StatusOK {
+		result["transmitted"] = true
+		return result
+	}
+	var reason struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	json.Unmarshal(data, &reason)
+	res["reason"] = reason.Reason
+	res["error"] = reason.Reason
+	if reason.Reason == "" {
+		res["error"] = http.StatusText(response.StatusCode)
+	}
+	if reason.Timestamp > 0 {
+		res["timestamp"] = reason.Timestamp
+	}
+	if reason.Reason == "ExpiredProviderToken" {
+		a.resetToken()
+	}
+	return result
+}
+
+func (a *apnsPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	deviceMap := classifyInstallations(installations, a.validPushTypes)
+	devices := []types.M{}
+	for _, pushType := range a.validPushTypes {
+		devices = append(devices, deviceMap[pushType]...)
+	}
+	results := make([]types.M, len(devices))
+	if len(devices) == 0 {
+		return results
+	}
+
+	notification, header := apnsNotification(body)
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		for i, device := range devices {
+			results[i] = types.M{
+				"device":      device,
+				"transmitted": false,
+				"response":    types.M{"error": err.Error()},
+			}
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, apnsConcurrency)
+	for i, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, device types.M) {
+			defer wg.Done()
+			results[i] = a.sendToDevice(device, payload, header)
+			<-sem
+		}(i, device)
+	}
+	wg.Wait()
+	return results
+}
+
+func (a *apnsPushAdapter) getValidPushTypes() []string {
+	return a.validPushTypes
+}
@@ -0,0 +1,236 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// verifyES256 校验 ES256 签名的 JWT
+func verifyES256(key *ecdsa.PublicKey, token string) bool {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || len(signature) != 64 {
+		return false
+	}
+	hash := sha256.Sum256([]byte(token[:i]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(key, hash[:], r, s)
+}
+
+// apnsStub 本地的 APNs HTTP/2 服务，按设备 token 返回不同的结果
+type apnsStub struct {
+	server   *httptest.Server
+	key      *ecdsa.PrivateKey
+	mutex    sync.Mutex
+	requests map[string]*http.Request
+	payloads map[string]types.M
+}
+
+func newAPNsStub(t *testing.T) *apnsStub {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &apnsStub{
+		key:      key,
+		requests: map[string]*http.Request{},
+		payloads: map[string]types.M{},
+	}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.serve))
+	s.server.EnableHTTP2 = true
+	s.server.StartTLS()
+	return s
+}
+
+func (s *apnsStub) serve(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/3/device/")
+	data, _ := ioutil.ReadAll(r.Body)
+	payload := types.M{}
+	json.Unmarshal(data, &payload)
+	s.mutex.Lock()
+	s.requests[token] = r
+	s.payloads[token] = payload
+	s.mutex.Unlock()
+
+	if r.ProtoMajor != 2 {
+		w.WriteHeader(http.StatusHTTPVersionNotSupported)
+		return
+	}
+	jwt := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+	if verifyES256(&s.key.PublicKey, jwt) == false {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+		return
+	}
+	switch token {
+	case "bad":
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+	case "gone":
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"reason":"Unregistered","timestamp":1500000000000}`))
+	default:
+		w.Header().Set("apns-id", "id-"+token)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *apnsStub) adapter(t *testing.T) *apnsPushAdapter {
+	der, err := x509.MarshalPKCS8PrivateKey(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	a, err := newAPNsAdapter(s.server.URL, "com.example.app", "KEYID", "TEAMID", p8, s.server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func Test_apnsSend(t *testing.T) {
+	stub := newAPNsStub(t)
+	defer stub.server.Close()
+	a := stub.adapter(t)
+	body := types.M{
+		"expiration_time": float64(1500000000000),
+		"data": types.M{
+			"alert":       "hello",
+			"badge":       1,
+			"collapse_id": "c1",
+			"key":         "value",
+		},
+	}
+	installations := types.S{
+		types.M{"deviceType": "ios", "deviceToken": "ok"},
+		types.M{"deviceType": "ios", "deviceToken": "bad"},
+		types.M{"deviceType": "ios", "deviceToken": "gone", "appIdentifier": "com.example.other"},
+		types.M{"deviceType": "android", "deviceToken": "android"},
+	}
+	results := a.send(body, installations, "")
+	/************************************************************/
+	// 每个 iOS 设备一条结果，包含 APNs 的 status 与 reason
+	expect := []types.M{
+		{
+			"device":      types.M{"deviceType": "ios", "deviceToken": "ok", "appIdentifier": nil},
+			"transmitted": true,
+			"response":    types.M{"status": 200, "apns-id": "id-ok"},
+		},
+		{
+			"device":      types.M{"deviceType": "ios", "deviceToken": "bad", "appIdentifier": nil},
+			"transmitted": false,
+			"response":    types.M{"status": 400, "apns-id": "", "reason": "BadDeviceToken", "error": "BadDeviceToken"},
+		},
+		{
+			"device":      types.M{"deviceType": "ios", "deviceToken": "gone", "appIdentifier": "com.example.other"},
+			"transmitted": false,
+			"response":    types.M{"status": 410, "apns-id": "", "reason": "Unregistered", "error": "Unregistered", "timestamp": int64(1500000000000)},
+		},
+	}
+	if reflect.DeepEqual(expect, results) == false {
+		t.Error("expect:", expect, "result:", results)
+	}
+	/************************************************************/
+	// 请求头与 payload
+	r := stub.requests["ok"]
+	header := map[string]string{
+		"apns-topic":       r.Header.Get("apns-topic"),
+		"apns-push-type":   r.Header.Get("apns-push-type"),
+		"apns-priority":    r.Header.Get("apns-priority"),
+		"apns-expiration":  r.Header.Get("apns-expiration"),
+		"apns-collapse-id": r.Header.Get("apns-collapse-id"),
+	}
+	expectHeader := map[string]string{
+		"apns-topic":       "com.example.app",
+		"apns-push-type":   "alert",
+		"apns-priority":    "10",
+		"apns-expiration":  "1500000000",
+		"apns-collapse-id": "c1",
+	}
+	if reflect.DeepEqual(expectHeader, header) == false {
+		t.Error("expect:", expectHeader, "result:", header)
+	}
+	expectPayload := types.M{
+		"aps": map[string]interface{}{"alert": map[string]interface{}{"body": "hello"}, "badge": float64(1)},
+		"key": "value",
+	}
+	if reflect.DeepEqual(expectPayload, stub.payloads["ok"]) == false {
+		t.Error("expect:", expectPayload, "result:", stub.payloads["ok"])
+	}
+	if topic := stub.requests["gone"].Header.Get("apns-topic"); topic != "com.example.other" {
+		t.Error("expect:", "com.example.other", "result:", topic)
+	}
+	if _, ok := stub.requests["android"]; ok {
+		t.Error("expect:", "android not sent", "result:", ok)
+	}
+}
+
+func Test_apnsNotification(t *testing.T) {
+	var body, payload, expectPayload types.M
+	var header http.Header
+	/************************************************************/
+	// 仅包含 content-available 时为静默推送
+	body = types.M{"data": types.M{"content-available": 1, "key": "value"}}
+	payload, header = apnsNotification(body)
+	expectPayload = types.M{"aps": types.M{"content-available": 1}, "key": "value"}
+	if reflect.DeepEqual(expectPayload, payload) == false {
+		t.Error("expect:", expectPayload, "result:", payload)
+	}
+	if header.Get("apns-push-type") != "background" || header.Get("apns-priority") != "5" {
+		t.Error("expect:", "background 5", "result:", header)
+	}
+	/************************************************************/
+	body = types.M{"data": types.M{"alert": types.M{"body": "hello", "loc-key": "k"}, "title": "t", "sound": "a.caf", "threadId": "g1", "priority": 5, "pushType": "voip"}}
+	payload, header = apnsNotification(body)
+	expectPayload = types.M{"aps": types.M{"alert": types.M{"body": "hello", "loc-key": "k", "title": "t"}, "sound": "a.caf", "thread-id": "g1"}}
+	if reflect.DeepEqual(expectPayload, payload) == false {
+		t.Error("expect:", expectPayload, "result:", payload)
+	}
+	if header.Get("apns-push-type") != "voip" || header.Get("apns-priority") != "5" {
+		t.Error("expect:", "voip 5", "result:", header)
+	}
+}
+
+func Test_providerToken(t *testing.T) {
+	stub := newAPNsStub(t)
+	defer stub.server.Close()
+	a := stub.adapter(t)
+	token, err := a.providerToken()
+	if err != nil || verifyES256(&stub.key.PublicKey, token) == false {
+		t.Error("expect:", "valid token", "result:", token, err)
+	}
+	parts := strings.Split(token, ".")
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if string(header) != `{"alg":"ES256","kid":"KEYID"}` {
+		t.Error("expect:", `{"alg":"ES256","kid":"KEYID"}`, "result:", string(header))
+	}
+	/************************************************************/
+	// 有效期内复用 token ，重置后重新生成
+	if cached, _ := a.providerToken(); cached != token {
+		t.Error("expect:", token, "result:", cached)
+	}
+	a.resetToken()
+	if a.token != "" {
+		t.Error("expect:", "", "result:", a.token)
+	}
+}
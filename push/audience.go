@@ -0,0 +1,79 @@
+package push
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/rest"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const audienceCollection = "_Audience"
+
+// AudienceWhere 获取推送受众 _Audience 中保存的查询条件， query 字段为 JSON 格式的 where
+func AudienceWhere(audienceID string) (types.M, error) {
+	if audienceID == "" {
+		return nil, errs.E(errs.PushMisconfigured, "audience_id is required.")
+	}
+	results, err := orm.TomatoDBController.Find(audienceCollection, types.M{"objectId": audienceID}, types.M{})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errs.E(errs.ObjectNotFound, "Audience not found.")
+	}
+	audience := utils.M(results[0])
+	return parseAudienceQuery(utils.S(audience["query"]))
+}
+
+// parseAudienceQuery 解析受众的查询条件，为空时推送到全部设备
+func parseAudienceQuery(query string) (types.M, error) {
+	where := types.M{}
+	if query == "" {
+		return where, nil
+	}
+	if err := json.Unmarshal([]byte(query), &where); err != nil {
+		return nil, errs.E(errs.InvalidQuery, "Audience query is not valid JSON.")
+	}
+	if where == nil {
+		where = types.M{}
+	}
+	return where, nil
+}
+
+// CountInstallations 统计查询条件能推送到的设备数，仅统计有 deviceToken 的设备
+func CountInstallations(auth *rest.Auth, where types.M) (int, error) {
+	where = ApplyDeviceTokenExists(where)
+	options := types.M{
+		"limit": 0,
+		"count": true,
+	}
+	result, err := rest.Find(auth, "_Installation", where, options, nil)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	if c, ok := result["count"].(int); ok {
+		count = c
+	}
+	return count, nil
+}
+
+// trackAudienceUsage 推送后更新受众的最后使用时间与使用次数
+func trackAudienceUsage(audienceID string) error {
+	update := types.M{
+		"lastUsed": types.M{
+			"__type": "Date",
+			"iso":    utils.TimetoString(time.Now().UTC()),
+		},
+		"timesUsed": types.M{
+			"__op":   "Increment",
+			"amount": 1,
+		},
+	}
+	_, err := orm.TomatoDBController.Update(audienceCollection, types.M{"objectId": audienceID}, update, types.M{}, false)
+	return err
+}
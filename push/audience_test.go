@@ -0,0 +1,46 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_parseAudienceQuery(t *testing.T) {
+	var query string
+	var result, expect types.M
+	var err error
+	/************************************************************/
+	query = `{"deviceType":{"$in":["ios","android"]},"channels":"news"}`
+	result, err = parseAudienceQuery(query)
+	expect = types.M{
+		"deviceType": map[string]interface{}{"$in": []interface{}{"ios", "android"}},
+		"channels":   "news",
+	}
+	if err != nil || reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result, err)
+	}
+	/************************************************************/
+	// 查询条件为空时推送到全部设备
+	query = ""
+	result, err = parseAudienceQuery(query)
+	expect = types.M{}
+	if err != nil || reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result, err)
+	}
+	/************************************************************/
+	query = "null"
+	result, err = parseAudienceQuery(query)
+	expect = types.M{}
+	if err != nil || reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result, err)
+	}
+	/************************************************************/
+	query = `{"deviceType":`
+	result, err = parseAudienceQuery(query)
+	if errs.GetErrorCode(err) != errs.InvalidQuery {
+		t.Error("expect:", errs.InvalidQuery, "result:", err)
+	}
+}
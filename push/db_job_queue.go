@@ -0,0 +1,124 @@
+package push
+
+import (
+	"time"
+
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const pushJobCollection = "_PushJob"
+
+// dbJobClaimSize claim 每次查询的候选任务数量
+const dbJobClaimSize = 10
+
+// dbJobQueue 保存在数据库 _PushJob 表中的推送任务队列
+// status 为 pending 的任务在 runAt 与 lockedUntil 都已过期时可被取出，
+// 取出时通过条件更新 lockedUntil 锁定任务，多个节点不会同时处理同一任务
+// 超过最大重试次数的任务 status 设置为 dead ，保留在表中作为死信
+type dbJobQueue struct {
+	channel string
+	db      *orm.DBController
+}
+
+func newDBJobQueue(channel string) *dbJobQueue {
+	return &dbJobQueue{
+		channel: channel,
+		db:      orm.TomatoDBController,
+	}
+}
+
+func dateValue(t time.Time) types.M {
+	return types.M{
+		"__type": "Date",
+		"iso":    utils.TimetoString(t.UTC()),
+	}
+}
+
+func (q *dbJobQueue) enqueue(data string, runAt time.Time) error {
+	now := time.Now()
+	if runAt.IsZero() {
+		runAt = now
+	}
+	object := types.M{
+		"objectId":    utils.CreateObjectID(),
+		"createdAt":   utils.TimetoString(now.UTC()),
+		"updatedAt":   utils.TimetoString(now.UTC()),
+		"channel":     q.channel,
+		"data":        data,
+		"status":      "pending",
+		"runAt":       dateValue(runAt),
+		"lockedUntil": dateValue(now),
+		// lockdown!
+		"ACL": types.M{},
+	}
+	return q.db.Create(pushJobCollection, object, types.M{})
+}
+
+func (q *dbJobQueue) claim(lease time.Duration) (*pushJob, error) {
+	now := time.Now()
+	where := types.M{
+		"channel":     q.channel,
+		"status":      "pending",
+		"runAt":       types.M{"$lte": dateValue(now)},
+		"lockedUntil": types.M{"$lte": dateValue(now)},
+	}
+	options := types.M{
+		"sort":  map[string]interface{}{"runAt": 1},
+		"limit": dbJobClaimSize,
+	}
+	results, err := q.db.Find(pushJobCollection, where, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		object := utils.M(r)
+		if object == nil {
+			continue
+		}
+		// 条件中包含 lockedUntil ，任务已被其他节点锁定时更新失败
+		lockWhere := utils.CopyMapM(where)
+		lockWhere["objectId"] = object["objectId"]
+		update := types.M{
+			"lockedUntil": dateValue(now.Add(lease)),
+			"updatedAt":   utils.TimetoString(now.UTC()),
+		}
+		_, err := q.db.Update(pushJobCollection, lockWhere, update, types.M{}, true)
+		if err != nil {
+			if errs.GetErrorCode(err) == errs.ObjectNotFound {
+				continue
+			}
+			return nil, err
+		}
+		return &pushJob{id: utils.S(object["objectId"]), data: utils.S(object["data"])}, nil
+	}
+	time.Sleep(jobQueuePollInterval)
+	return nil, nil
+}
+
+func (q *dbJobQueue) complete(job *pushJob) error {
+	return q.db.Destroy(pushJobCollection, types.M{"objectId": job.id}, types.M{})
+}
+
+func (q *dbJobQueue) retry(job *pushJob, data string, runAt time.Time) error {
+	update := types.M{
+		"data":        data,
+		"runAt":       dateValue(runAt),
+		"lockedUntil": dateValue(runAt),
+		"updatedAt":   utils.TimetoString(time.Now().UTC()),
+	}
+	_, err := q.db.Update(pushJobCollection, types.M{"objectId": job.id}, update, types.M{}, true)
+	return err
+}
+
+func (q *dbJobQueue) deadLetter(job *pushJob, reason string) error {
+	update := types.M{
+		"status":    "dead",
+		"lastError": reason,
+		"updatedAt": utils.TimetoString(time.Now().UTC()),
+	}
+	_, err := q.db.Update(pushJobCollection, types.M{"objectId": job.id}, update, types.M{}, true)
+	return err
+}
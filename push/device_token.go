@@ -0,0 +1,139 @@
+package push
+
+import (
+	"time"
+
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// permanentPushErrors 表示 deviceToken 已永久失效的错误，再次推送也不会成功
+var permanentPushErrors = map[string]bool{
+	"NotRegistered":       true, // FCM ：应用已卸载或 token 已注销
+	"InvalidRegistration": true, // FCM ：token 格式错误
+	"Unregistered":        true, // APNs ：设备已不再接收该 topic 的推送
+	"BadDeviceToken":      true, // APNs ：token 无效或与环境不匹配
+	"NotFound":            true, // Web Push ：订阅不存在
+	"Gone":                true, // Web Push ：订阅已过期或已取消
+}
+
+// transientPushErrors 表示推送服务暂时不可用的错误，稍后重试可能成功
+// 请求未到达推送服务（如网络错误）时 reason 统一为 Unavailable
+var transientPushErrors = map[string]bool{
+	"Unavailable":               true, // FCM ：服务暂时不可用
+	"InternalServerError":       true, // FCM 、 APNs ：服务内部错误
+	"DeviceMessageRateExceeded": true, // FCM ：单个设备的消息频率过高
+	"TooManyRequests":           true, // APNs ：同一设备的请求过多
+	"ServiceUnavailable":        true, // APNs ：服务不可用
+	"Shutdown":                  true, // APNs ：服务正在关闭
+	"ExpiredProviderToken":      true, // APNs ：鉴权 token 已过期，重试时使用重新生成的 token
+}
+
+// unavailable 请求未到达推送服务时使用的 reason
+const unavailable = "Unavailable"
+
+// resultReason 返回推送结果中的错误原因，优先使用 reason ，其次使用 error
+func resultReason(result types.M) string {
+	response := result["response"]
+	reason := responseValue(response, "reason")
+	if reason == "" {
+		reason = responseValue(response, "error")
+	}
+	return reason
+}
+
+// isTransientResult 判断推送失败是否为临时性错误，临时性错误的设备会在稍后重试
+func isTransientResult(result types.M) bool {
+	if result == nil {
+		return false
+	}
+	if transmitted, _ := result["transmitted"].(bool); transmitted {
+		return false
+	}
+	return transientPushErrors[resultReason(result)]
+}
+
+// responseValue 读取推送结果 response 中的字段， response 可能为 map[string]string 或 types.M
+func responseValue(response interface{}, key string) string {
+	switch r := response.(type) {
+	case map[string]string:
+		return r[key]
+	case types.M:
+		return utils.S(r[key])
+	case map[string]interface{}:
+		return utils.S(r[key])
+	}
+	return ""
+}
+
+// classifyResult 判断推送结果中的 deviceToken 是否需要处理
+// 返回 remove 为 true 表示 token 已永久失效，需要清除
+// 返回 canonicalToken 不为空表示推送服务返回了新的 token （如 FCM 的 registration_id ），需要替换
+func classifyResult(result types.M) (remove bool, canonicalToken string) {
+	if result == nil {
+		return false, ""
+	}
+	response := result["response"]
+	if transmitted, _ := result["transmitted"].(bool); transmitted == false {
+		return permanentPushErrors[resultReason(result)], ""
+	}
+	device := utils.M(result["device"])
+	canonicalToken = responseValue(response, "registration_id")
+	if device == nil || canonicalToken == utils.S(device["deviceToken"]) {
+		return false, ""
+	}
+	return false, canonicalToken
+}
+
+// cleanupDeviceTokens 清除已失效的 deviceToken ，并使用推送服务返回的新 token 替换旧 token
+// 清除 deviceToken 后该设备不会再出现在推送查询中，返回清除与替换的数量
+func cleanupDeviceTokens(results []types.M) (removed, replaced int) {
+	for _, result := range results {
+		where, update, remove := deviceTokenUpdate(result)
+		if where == nil {
+			continue
+		}
+		_, err := orm.TomatoDBController.Update("_Installation", where, update, types.M{}, true)
+		if err != nil {
+			continue
+		}
+		if remove {
+			removed++
+		} else {
+			replaced++
+		}
+	}
+	return
+}
+
+// deviceTokenUpdate 生成清除或替换 deviceToken 的查询条件与更新内容，不需要处理时 where 为 nil
+// 只更新推送结果对应的设备，同时限制 deviceToken 未变，避免覆盖推送期间重新注册的 token
+func deviceTokenUpdate(result types.M) (where, update types.M, remove bool) {
+	remove, canonicalToken := classifyResult(result)
+	if remove == false && canonicalToken == "" {
+		return nil, nil, false
+	}
+	objectID := utils.S(result["installation"])
+	if objectID == "" {
+		return nil, nil, false
+	}
+	device := utils.M(result["device"])
+	where = types.M{
+		"objectId":    objectID,
+		"deviceToken": device["deviceToken"],
+	}
+	update = types.M{
+		"updatedAt": utils.TimetoString(time.Now().UTC()),
+	}
+	if remove {
+		update["deviceToken"] = types.M{"__op": "Delete"}
+		// Web Push 的订阅密钥随 endpoint 一起失效
+		if utils.S(device["deviceType"]) == "web" {
+			update["webPushKeys"] = types.M{"__op": "Delete"}
+		}
+	} else {
+		update["deviceToken"] = canonicalToken
+	}
+	return where, update, remove
+}
@@ -0,0 +1,186 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_classifyResult(t *testing.T) {
+	var result types.M
+	var remove bool
+	var canonicalToken string
+	/************************************************************/
+	// FCM 返回 NotRegistered
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    map[string]string{"error": "NotRegistered"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != true || canonicalToken != "" {
+		t.Error("expect:", true, "", "result:", remove, canonicalToken)
+	}
+	/************************************************************/
+	// APNs 返回 Unregistered
+	result = types.M{
+		"device":      types.M{"deviceType": "ios", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    types.M{"status": 410, "reason": "Unregistered", "error": "Unregistered"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != true || canonicalToken != "" {
+		t.Error("expect:", true, "", "result:", remove, canonicalToken)
+	}
+	/************************************************************/
+	// 临时错误不清除
+	result = types.M{
+		"device":      types.M{"deviceType": "ios", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    types.M{"status": 429, "reason": "TooManyRequests", "error": "TooManyRequests"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != false || canonicalToken != "" {
+		t.Error("expect:", false, "", "result:", remove, canonicalToken)
+	}
+	/************************************************************/
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    map[string]string{"error": "connection refused"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != false || canonicalToken != "" {
+		t.Error("expect:", false, "", "result:", remove, canonicalToken)
+	}
+	/************************************************************/
+	// FCM 返回 canonical registration id
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": true,
+		"response":    map[string]string{"message_id": "1", "registration_id": "b"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != false || canonicalToken != "b" {
+		t.Error("expect:", false, "b", "result:", remove, canonicalToken)
+	}
+	/************************************************************/
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": true,
+		"response":    map[string]string{"message_id": "1"},
+	}
+	remove, canonicalToken = classifyResult(result)
+	if remove != false || canonicalToken != "" {
+		t.Error("expect:", false, "", "result:", remove, canonicalToken)
+	}
+}
+
+func Test_isTransientResult(t *testing.T) {
+	var result types.M
+	/************************************************************/
+	result = types.M{
+		"device":      types.M{"deviceType": "ios", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    types.M{"status": 503, "reason": "ServiceUnavailable", "error": "ServiceUnavailable"},
+	}
+	if isTransientResult(result) != true {
+		t.Error("expect:", true, "result:", false)
+	}
+	/************************************************************/
+	// 鉴权 token 过期，重新生成后重试
+	result = types.M{
+		"device":      types.M{"deviceType": "ios", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    types.M{"status": 403, "reason": "ExpiredProviderToken", "error": "ExpiredProviderToken"},
+	}
+	if isTransientResult(result) != true {
+		t.Error("expect:", true, "result:", false)
+	}
+	/************************************************************/
+	// 请求未到达推送服务
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    map[string]string{"error": "connection refused", "reason": unavailable},
+	}
+	if isTransientResult(result) != true {
+		t.Error("expect:", true, "result:", false)
+	}
+	/************************************************************/
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    map[string]string{"error": "NotRegistered"},
+	}
+	if isTransientResult(result) != false {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": true,
+		"response":    map[string]string{"message_id": "1"},
+	}
+	if isTransientResult(result) != false {
+		t.Error("expect:", false, "result:", true)
+	}
+}
+
+func Test_deviceTokenUpdate(t *testing.T) {
+	var result types.M
+	var where, update types.M
+	var remove bool
+	/************************************************************/
+	// 按设备的 objectId 清除失效的 token
+	result = types.M{
+		"device":       types.M{"deviceType": "web", "deviceToken": "a"},
+		"installation": "1",
+		"transmitted":  false,
+		"response":     map[string]string{"error": "Gone"},
+	}
+	where, update, remove = deviceTokenUpdate(result)
+	expect := types.M{"objectId": "1", "deviceToken": "a"}
+	if reflect.DeepEqual(expect, where) == false || remove != true {
+		t.Error("expect:", expect, true, "result:", where, remove)
+	}
+	if reflect.DeepEqual(types.M{"__op": "Delete"}, update["deviceToken"]) == false || update["webPushKeys"] == nil {
+		t.Error("expect:", "delete deviceToken and webPushKeys", "result:", update)
+	}
+	/************************************************************/
+	// 替换为 canonical token
+	result = types.M{
+		"device":       types.M{"deviceType": "android", "deviceToken": "a"},
+		"installation": "1",
+		"transmitted":  true,
+		"response":     map[string]string{"message_id": "1", "registration_id": "b"},
+	}
+	where, update, remove = deviceTokenUpdate(result)
+	if reflect.DeepEqual(expect, where) == false || remove != false || update["deviceToken"] != "b" {
+		t.Error("expect:", expect, false, "b", "result:", where, remove, update)
+	}
+	/************************************************************/
+	// 找不到对应的设备时不处理
+	result = types.M{
+		"device":      types.M{"deviceType": "android", "deviceToken": "a"},
+		"transmitted": false,
+		"response":    map[string]string{"error": "NotRegistered"},
+	}
+	where, _, _ = deviceTokenUpdate(result)
+	if where != nil {
+		t.Error("expect:", nil, "result:", where)
+	}
+	/************************************************************/
+	// 推送成功时不处理
+	result = types.M{
+		"device":       types.M{"deviceType": "android", "deviceToken": "a"},
+		"installation": "1",
+		"transmitted":  true,
+		"response":     map[string]string{"message_id": "1"},
+	}
+	where, _, _ = deviceTokenUpdate(result)
+	if where != nil {
+		t.Error("expect:", nil, "result:", where)
+	}
+}
@@ -0,0 +1,37 @@
+package push
+
+import (
+	"testing"
+)
+
+func Test_queueConfigWithGroup(t *testing.T) {
+	var queueType, queueConfig, channel string
+	var result, expect string
+	/************************************************************/
+	queueType = "NATS"
+	queueConfig = "user=abc"
+	channel = "parse-server-push"
+	result = queueConfigWithGroup(queueType, queueConfig, channel)
+	expect = "group=parse-server-push&user=abc"
+	if result != expect {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	queueType = "RedisStreams"
+	queueConfig = "group=push"
+	channel = "parse-server-push"
+	result = queueConfigWithGroup(queueType, queueConfig, channel)
+	expect = "group=push"
+	if result != expect {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	queueType = "Redis"
+	queueConfig = "password"
+	channel = "parse-server-push"
+	result = queueConfigWithGroup(queueType, queueConfig, channel)
+	expect = "password"
+	if result != expect {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
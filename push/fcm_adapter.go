@@ -53,7 +53,7 @@ func (f *fcmPushAdapter) send(body types.M, installations types.S, pushStatus st
 				result := types.M{
 					"device":      device,
 					"transmitted": false,
-					"response":    map[string]string{"error": err.Error()},
+					"response":    map[string]string{"error": err.Error(), "reason": unavailable},
 				}
 				results = append(results, result)
 			}
@@ -223,6 +223,10 @@ func classifyInstallations(installations types.S, validPushTypes []string) map[s
 					"deviceType":    deviceType,
 					"appIdentifier": dev["appIdentifier"],
 				}
+				// Web Push 加密推送内容需要订阅的 p256dh 与 auth
+				if keys := utils.M(dev["webPushKeys"]); keys != nil {
+					device["webPushKeys"] = keys
+				}
 				devices = append(devices, device)
 				deviceMap[tp] = devices
 			}
@@ -0,0 +1,144 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	hmsAuthURL = "https://oauth-login.cloud.huawei.com/oauth2/v3/token"
+	hmsPushURL = "https://push-api.cloud.huawei.com/v1/%s/messages:send"
+	// hmsBatchSize 每次请求的最大 token 数
+	hmsBatchSize = 1000
+
+	hmsSuccess        = "80000000" // 全部发送成功
+	hmsPartialSuccess = "80100000" // 部分 token 发送成功， msg 中包含无效的 token
+	hmsTokenExpired   = "80200003" // access_token 已过期
+	hmsAllTokenFailed = "80300007" // 全部 token 无效
+)
+
+// hmsPushAdapter 华为 HMS Push Kit 推送，设备的 pushType 为 hms
+// access_token 通过 client_credentials 方式获取，过期前自动刷新
+type hmsPushAdapter struct {
+	validPushTypes []string
+	appID          string
+	appSecret      string
+	authURL        string
+	pushURL        string
+	batchSize      int
+	client         *http.Client
+	token          *accessToken
+}
+
+func newHMSPush() *hmsPushAdapter {
+	return newHMSAdapter(config.TConfig.HMSAppID, config.TConfig.HMSAppSecret, hmsAuthURL, fmt.Sprintf(hmsPushURL, config.TConfig.HMSAppID), newVendorClient())
+}
+
+func newHMSAdapter(appID, appSecret, authURL, pushURL string, client *http.Client) *hmsPushAdapter {
+	h := &hmsPushAdapter{
+		validPushTypes: []string{"hms"},
+		appID:          appID,
+		appSecret:      appSecret,
+		authURL:        authURL,
+		pushURL:        pushURL,
+		batchSize:      hmsBatchSize,
+		client:         client,
+	}
+	h.token = &accessToken{refresh: h.refreshToken}
+	return h
+}
+
+func (h *hmsPushAdapter) refreshToken() (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {h.appID},
+		"client_secret": {h.appSecret},
+	}
+	result, _, err := vendorRequest(h.client, h.authURL, nil, form, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	token := utils.S(result["access_token"])
+	if token == "" {
+		return "", 0, errors.New("HMS auth failed: " + utils.S(result["error_description"]))
+	}
+	expiresIn, _ := result["expires_in"].(float64)
+	return token, time.Duration(expiresIn) * time.Second, nil
+}
+
+// sendBatch 发送一批 token ， access_token 过期时刷新后重试一次
+func (h *hmsPushAdapter) sendBatch(tokens []string, n vendorNotification) (types.M, map[string]bool, error) {
+	android := types.M{
+		"notification": types.M{
+			"title":        n.title,
+			"body":         n.content,
+			"click_action": types.M{"type": 3}, // 打开应用首页
+		},
+	}
+	if n.ttl > 0 {
+		android["ttl"] = fmt.Sprintf("%ds", n.ttl)
+	}
+	message := types.M{
+		"token":   tokens,
+		"android": android,
+	}
+	if len(n.extras) > 0 {
+		data, _ := json.Marshal(n.extras)
+		message["data"] = string(data)
+	}
+	body := types.M{
+		"validate_only": false,
+		"message":       message,
+	}
+
+	for retry := 0; ; retry++ {
+		token, err := h.token.get()
+		if err != nil {
+			return nil, nil, err
+		}
+		header := http.Header{"Authorization": {"Bearer " + token}}
+		result, status, err := vendorRequest(h.client, h.pushURL, header, nil, body)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := utils.S(result["code"])
+		if (code == hmsTokenExpired || status == http.StatusUnauthorized) && retry == 0 {
+			h.token.reset()
+			continue
+		}
+		switch code {
+		case hmsSuccess:
+			return result, nil, nil
+		case hmsPartialSuccess:
+			// msg 格式为 {"success":1,"failure":1,"illegal_tokens":["..."]}
+			var msg struct {
+				IllegalTokens []string `json:"illegal_tokens"`
+			}
+			json.Unmarshal([]byte(utils.S(result["msg"])), &msg)
+			return result, tokenSet(msg.IllegalTokens), nil
+		case hmsAllTokenFailed:
+			return result, tokenSet(tokens), nil
+		}
+		return nil, nil, errors.New("HMS push failed: " + code + " " + utils.S(result["msg"]))
+	}
+}
+
+func (h *hmsPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	devices := classifyInstallations(installations, h.validPushTypes)["hms"]
+	n := newVendorNotification(body)
+	return sendByBatch(devices, h.batchSize, func(tokens []string) (types.M, map[string]bool, error) {
+		return h.sendBatch(tokens, n)
+	})
+}
+
+func (h *hmsPushAdapter) getValidPushTypes() []string {
+	return h.validPushTypes
+}
@@ -0,0 +1,112 @@
+package push
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// resultSummary 把推送结果简化为 deviceToken 与发送状态，便于比较
+func resultSummary(results []types.M) []string {
+	summary := []string{}
+	for _, result := range results {
+		device := result["device"].(types.M)
+		status := "sent"
+		if result["transmitted"] != true {
+			status = "failed"
+			if remove, _ := classifyResult(result); remove {
+				status = "invalid"
+			}
+		}
+		summary = append(summary, device["deviceToken"].(string)+":"+status)
+	}
+	return summary
+}
+
+func Test_hmsSend(t *testing.T) {
+	var mutex sync.Mutex
+	authCount := 0
+	batches := [][]string{}
+	expired := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "app" || r.Form.Get("client_secret") != "secret" {
+				w.Write([]byte(`{"error":1101,"error_description":"invalid client"}`))
+				return
+			}
+			authCount++
+			w.Write([]byte(`{"access_token":"token` + strconv.Itoa(authCount) + `","expires_in":3600}`))
+		case "/send":
+			if r.Header.Get("Authorization") != "Bearer token"+strconv.Itoa(authCount) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code":"80200001","msg":"Oauth authentication error"}`))
+				return
+			}
+			// 第一次请求时返回 token 已过期
+			if expired {
+				expired = false
+				w.Write([]byte(`{"code":"80200003","msg":"Oauth Token expired"}`))
+				return
+			}
+			data, _ := ioutil.ReadAll(r.Body)
+			var body struct {
+				Message struct {
+					Token []string `json:"token"`
+				} `json:"message"`
+			}
+			json.Unmarshal(data, &body)
+			batches = append(batches, body.Message.Token)
+			for _, token := range body.Message.Token {
+				if token == "bad" {
+					w.Write([]byte(`{"code":"80100000","msg":"{\"success\":1,\"failure\":1,\"illegal_tokens\":[\"bad\"]}"}`))
+					return
+				}
+			}
+			w.Write([]byte(`{"code":"80000000","msg":"Success"}`))
+		}
+	}))
+	defer server.Close()
+
+	h := newHMSAdapter("app", "secret", server.URL+"/token", server.URL+"/send", server.Client())
+	h.batchSize = 2
+	installations := types.S{
+		types.M{"deviceType": "android", "pushType": "hms", "deviceToken": "a"},
+		types.M{"deviceType": "android", "pushType": "hms", "deviceToken": "bad"},
+		types.M{"deviceType": "android", "pushType": "hms", "deviceToken": "c"},
+		types.M{"deviceType": "android", "deviceToken": "fcm"},
+	}
+	body := types.M{"data": types.M{"alert": "hello", "title": "t"}}
+	results := h.send(body, installations, "")
+	/************************************************************/
+	// 按 batchSize 分批，无效的 token 单独标记， token 过期后刷新重试
+	expect := []string{"a:sent", "bad:invalid", "c:sent"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	expectBatches := [][]string{{"a", "bad"}, {"c"}}
+	if reflect.DeepEqual(expectBatches, batches) == false {
+		t.Error("expect:", expectBatches, "result:", batches)
+	}
+	if authCount != 2 {
+		t.Error("expect:", 2, "result:", authCount)
+	}
+	/************************************************************/
+	// 鉴权失败时全部标记为失败
+	h = newHMSAdapter("app", "wrong", server.URL+"/token", server.URL+"/send", server.Client())
+	results = h.send(body, installations[:1], "")
+	expect = []string{"a:failed"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
@@ -0,0 +1,139 @@
+package push
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/pubsub"
+)
+
+// pushJob 推送队列中的一个任务， data 为 JSON 格式的推送任务
+type pushJob struct {
+	id   string // 任务在队列中的 ID ，由队列实现使用
+	data string
+}
+
+// jobQueue 推送任务队列
+// 任务至少被处理一次： claim 取出的任务在 complete 、 retry 或 deadLetter 之前不会从队列中删除，
+// 处理任务的节点异常退出时，租期 lease 过后任务会被其他节点再次取出
+type jobQueue interface {
+	// enqueue 添加任务， runAt 之后才能被取出，为零值时立即可被取出
+	enqueue(data string, runAt time.Time) error
+	// claim 取出一个到期的任务并锁定 lease 时长，没有任务时等待一段时间后返回 nil
+	claim(lease time.Duration) (*pushJob, error)
+	// complete 任务处理完成，从队列中删除
+	complete(job *pushJob) error
+	// retry 任务处理失败， runAt 之后使用更新后的 data 重新处理
+	retry(job *pushJob, data string, runAt time.Time) error
+	// deadLetter 任务超过最大重试次数，移入死信存储
+	deadLetter(job *pushJob, reason string) error
+}
+
+// newJobQueue 创建推送任务队列
+// Database 与 RedisStreams 为持久化队列，推送任务保存在数据库或 Redis 中，节点重启后不会丢失
+// 其他类型使用 pubsub 发送任务，重试任务保存在内存中
+func newJobQueue(queueType, queueURL, queueConfig, channel string) jobQueue {
+	if channel == "" {
+		channel = pushChannel
+	}
+	switch queueType {
+	case "Database":
+		return newDBJobQueue(channel)
+	case "RedisStreams":
+		return newRedisJobQueue(queueURL, queueConfig, channel)
+	}
+	return newPubSubJobQueue(queueType, queueURL, queueConfig, channel)
+}
+
+// jobQueuePollInterval 没有任务时 claim 的等待时间
+const jobQueuePollInterval = time.Second
+
+// pubSubJobQueue 使用 pubsub 发送任务的队列
+// 收到的任务保存在内存中，节点退出时未处理的任务会丢失
+type pubSubJobQueue struct {
+	publisher pubsub.Publisher
+	channel   string
+	subscribe func()
+	once      sync.Once
+	mutex     sync.Mutex
+	pending   []string
+	notify    chan struct{}
+}
+
+func newPubSubJobQueue(queueType, queueURL, queueConfig, channel string) *pubSubJobQueue {
+	q := &pubSubJobQueue{
+		publisher: CreatePublisher(queueType, queueURL, queueConfig),
+		channel:   channel,
+		notify:    make(chan struct{}, 1),
+	}
+	// 仅在处理任务的节点中订阅，避免只发送任务的节点在 group 中分走任务
+	q.subscribe = func() {
+		subscriber := CreateSubscriber(queueType, queueURL, queueConfigWithGroup(queueType, queueConfig, channel))
+		subscriber.Subscribe(channel)
+		subscriber.On("message", func(args ...string) {
+			if len(args) < 2 {
+				return
+			}
+			q.push(args[1])
+		})
+	}
+	return q
+}
+
+func (q *pubSubJobQueue) push(data string) {
+	q.mutex.Lock()
+	q.pending = append(q.pending, data)
+	q.mutex.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue runAt 未到时在内存中等待，到期后发布
+func (q *pubSubJobQueue) enqueue(data string, runAt time.Time) error {
+	if delay := time.Until(runAt); delay > 0 {
+		time.AfterFunc(delay, func() {
+			q.publisher.Publish(q.channel, data)
+		})
+		return nil
+	}
+	q.publisher.Publish(q.channel, data)
+	return nil
+}
+
+func (q *pubSubJobQueue) claim(lease time.Duration) (*pushJob, error) {
+	q.once.Do(q.subscribe)
+	timeout := time.After(jobQueuePollInterval)
+	for {
+		q.mutex.Lock()
+		if len(q.pending) > 0 {
+			data := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mutex.Unlock()
+			return &pushJob{data: data}, nil
+		}
+		q.mutex.Unlock()
+		select {
+		case <-q.notify:
+		case <-timeout:
+			return nil, nil
+		}
+	}
+}
+
+func (q *pubSubJobQueue) complete(job *pushJob) error {
+	return nil
+}
+
+// retry 到期后重新发布任务，可能由其他节点处理
+func (q *pubSubJobQueue) retry(job *pushJob, data string, runAt time.Time) error {
+	return q.enqueue(data, runAt)
+}
+
+// deadLetter 非持久化队列不保存死信，仅输出日志，推送状态中记录了失败的设备数
+func (q *pubSubJobQueue) deadLetter(job *pushJob, reason string) error {
+	log.Println("push job dead-lettered:", reason, job.data)
+	return nil
+}
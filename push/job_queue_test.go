@@ -0,0 +1,45 @@
+package push
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func Test_pubSubJobQueue(t *testing.T) {
+	q := newPubSubJobQueue("", "", "", "test-push-jobs")
+	/************************************************************/
+	// 没有任务时返回 nil
+	job, err := q.claim(time.Minute)
+	if job != nil || err != nil {
+		t.Error("expect:", nil, "result:", job, err)
+	}
+	/************************************************************/
+	q.enqueue("a", time.Time{})
+	q.enqueue("b", time.Time{})
+	result := []string{}
+	for i := 0; i < 2; i++ {
+		job, err = q.claim(time.Minute)
+		if job == nil || err != nil {
+			t.Fatal("expect:", "job", "result:", job, err)
+		}
+		result = append(result, job.data)
+		q.complete(job)
+	}
+	// 进程内的 EventEmitter 在不同的 goroutine 中分发消息，不保证顺序
+	sort.Strings(result)
+	if reflect.DeepEqual([]string{"a", "b"}, result) == false {
+		t.Error("expect:", []string{"a", "b"}, "result:", result)
+	}
+	/************************************************************/
+	// 重试的任务到期后重新发布
+	start := time.Now()
+	q.retry(&pushJob{data: "a"}, "a2", start.Add(100*time.Millisecond))
+	job, err = q.claim(time.Minute)
+	if job == nil || job.data != "a2" || err != nil {
+		t.Error("expect:", "a2", "result:", job, err)
+	} else if time.Since(start) < 100*time.Millisecond {
+		t.Error("expect:", 100*time.Millisecond, "result:", time.Since(start))
+	}
+}
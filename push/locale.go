@@ -0,0 +1,102 @@
+package push
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// defaultLocaleKey 没有匹配到语言的设备使用默认的 alert 与 title
+const defaultLocaleKey = "default"
+
+// localeKeyPattern 匹配 data 中的本地化字段，如 alert-zh-CN 、 title-en
+var localeKeyPattern = regexp.MustCompile(`^(alert|title)-([A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*)$`)
+
+// normalizeLocale 统一语言标识的格式，如 zh_CN 转换为 zh-cn
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
+
+// getLocalesFromPush 获取推送内容中设置了本地化 alert 或 title 的语言，按名称排序
+func getLocalesFromPush(body types.M) []string {
+	data := utils.M(body["data"])
+	if data == nil {
+		return nil
+	}
+	set := map[string]bool{}
+	for key := range data {
+		if match := localeKeyPattern.FindStringSubmatch(key); match != nil {
+			set[match[2]] = true
+		}
+	}
+	locales := []string{}
+	for locale := range set {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// bodiesPerLocales 生成每种语言的推送内容，本地化的 alert 与 title 替换默认值，
+// 并删除全部本地化字段， default 为未匹配到语言的设备使用的推送内容
+func bodiesPerLocales(body types.M, locales []string) map[string]types.M {
+	bodies := map[string]types.M{}
+	for _, locale := range append([]string{defaultLocaleKey}, locales...) {
+		payload := utils.CopyMapM(body)
+		data := types.M{}
+		for key, v := range utils.M(body["data"]) {
+			if localeKeyPattern.MatchString(key) == false {
+				data[key] = v
+			}
+		}
+		if locale != defaultLocaleKey {
+			source := utils.M(body["data"])
+			if alert, ok := source["alert-"+locale]; ok {
+				data["alert"] = alert
+			}
+			if title, ok := source["title-"+locale]; ok {
+				data["title"] = title
+			}
+		}
+		payload["data"] = data
+		bodies[locale] = payload
+	}
+	return bodies
+}
+
+// matchLocale 为设备的 localeIdentifier 选择最匹配的语言
+// 优先完全匹配，其次逐级去掉后缀匹配，如 zh-Hans-CN 依次匹配 zh-Hans-CN 、 zh-Hans 、 zh
+// 没有匹配的语言时返回 default
+func matchLocale(localeIdentifier string, locales []string) string {
+	normalized := map[string]string{}
+	for _, locale := range locales {
+		normalized[normalizeLocale(locale)] = locale
+	}
+	candidate := normalizeLocale(localeIdentifier)
+	for candidate != "" {
+		if locale, ok := normalized[candidate]; ok {
+			return locale
+		}
+		index := strings.LastIndex(candidate, "-")
+		if index < 0 {
+			break
+		}
+		candidate = candidate[:index]
+	}
+	return defaultLocaleKey
+}
+
+// groupByLocaleIdentifier 按最匹配的语言对设备分组，与 groupByBadge 类似
+func groupByLocaleIdentifier(installations types.S, locales []string) map[string]types.S {
+	result := map[string]types.S{}
+	for _, v := range installations {
+		if installation := utils.M(v); installation != nil {
+			locale := matchLocale(utils.S(installation["localeIdentifier"]), locales)
+			result[locale] = append(result[locale], v)
+		}
+	}
+	return result
+}
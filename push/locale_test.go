@@ -0,0 +1,95 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_getLocalesFromPush(t *testing.T) {
+	var body types.M
+	var result, expect []string
+	/************************************************************/
+	body = types.M{
+		"data": types.M{
+			"alert":       "hello",
+			"alert-zh-CN": "你好",
+			"title-zh-CN": "标题",
+			"alert-en":    "hello",
+			"title-fr":    "titre",
+			"alert-":      "invalid",
+			"badge":       1,
+		},
+	}
+	result = getLocalesFromPush(body)
+	expect = []string{"en", "fr", "zh-CN"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	body = types.M{"data": types.M{"alert": "hello"}}
+	result = getLocalesFromPush(body)
+	expect = []string{}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_bodiesPerLocales(t *testing.T) {
+	body := types.M{
+		"where": types.M{},
+		"data": types.M{
+			"alert":       "hello",
+			"title":       "title",
+			"alert-zh-CN": "你好",
+			"title-zh-CN": "标题",
+			"alert-en":    "hi",
+			"badge":       1,
+		},
+	}
+	result := bodiesPerLocales(body, []string{"en", "zh-CN"})
+	expect := map[string]types.M{
+		"default": types.M{
+			"where": types.M{},
+			"data":  types.M{"alert": "hello", "title": "title", "badge": 1},
+		},
+		"en": types.M{
+			"where": types.M{},
+			"data":  types.M{"alert": "hi", "title": "title", "badge": 1},
+		},
+		"zh-CN": types.M{
+			"where": types.M{},
+			"data":  types.M{"alert": "你好", "title": "标题", "badge": 1},
+		},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	// 原推送内容不变
+	if len(body["data"].(types.M)) != 6 {
+		t.Error("expect:", 6, "result:", len(body["data"].(types.M)))
+	}
+}
+
+func Test_groupByLocaleIdentifier(t *testing.T) {
+	installations := types.S{
+		types.M{"deviceToken": "a", "localeIdentifier": "zh-CN"},
+		types.M{"deviceToken": "b", "localeIdentifier": "zh_TW"},
+		types.M{"deviceToken": "c", "localeIdentifier": "zh-Hans-CN"},
+		types.M{"deviceToken": "d", "localeIdentifier": "en-US"},
+		types.M{"deviceToken": "e", "localeIdentifier": "fr"},
+		types.M{"deviceToken": "f"},
+		types.M{"deviceToken": "g", "localeIdentifier": "ZH-cn"},
+	}
+	result := groupByLocaleIdentifier(installations, []string{"en", "zh", "zh-CN"})
+	expect := map[string]types.S{
+		"zh-CN":   types.S{installations[0], installations[6]},
+		"zh":      types.S{installations[1], installations[2]},
+		"en":      types.S{installations[3]},
+		"default": types.S{installations[4], installations[5]},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
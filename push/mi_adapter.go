@@ -0,0 +1,90 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	miPushURL = "https://api.xmpush.xiaomi.com/v3/message/regid"
+	// miBatchSize 每次请求的最大 regId 数
+	miBatchSize = 1000
+)
+
+// miPushAdapter 小米推送，设备的 pushType 为 mi
+// 小米推送使用 AppSecret 直接鉴权，不需要刷新 token
+type miPushAdapter struct {
+	validPushTypes []string
+	appSecret      string
+	packageName    string
+	pushURL        string
+	batchSize      int
+	client         *http.Client
+}
+
+func newMiPush() *miPushAdapter {
+	return newMiAdapter(config.TConfig.MiAppSecret, config.TConfig.MiPackageName, miPushURL, newVendorClient())
+}
+
+func newMiAdapter(appSecret, packageName, pushURL string, client *http.Client) *miPushAdapter {
+	return &miPushAdapter{
+		validPushTypes: []string{"mi"},
+		appSecret:      appSecret,
+		packageName:    packageName,
+		pushURL:        pushURL,
+		batchSize:      miBatchSize,
+		client:         client,
+	}
+}
+
+// sendBatch 发送一批 regId ，响应的 data.bad_regids 中为无效的 regId
+func (m *miPushAdapter) sendBatch(tokens []string, n vendorNotification) (types.M, map[string]bool, error) {
+	form := url.Values{
+		"registration_id":         {strings.Join(tokens, ",")},
+		"restricted_package_name": {m.packageName},
+		"title":                   {n.title},
+		"description":             {n.content},
+		"pass_through":            {"0"},
+		"notify_type":             {"-1"},
+	}
+	if len(n.extras) > 0 {
+		data, _ := json.Marshal(n.extras)
+		form.Set("payload", string(data))
+	}
+	if n.ttl > 0 {
+		form.Set("time_to_live", strconv.FormatInt(n.ttl*1000, 10))
+	}
+	header := http.Header{"Authorization": {"key=" + m.appSecret}}
+	result, _, err := vendorRequest(m.client, m.pushURL, header, form, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if utils.S(result["result"]) != "ok" {
+		return nil, nil, errors.New("Mi push failed: " + utils.S(result["reason"]))
+	}
+	invalid := map[string]bool{}
+	if data := utils.M(result["data"]); data != nil {
+		invalid = tokenSet(strings.Split(utils.S(data["bad_regids"]), ","))
+	}
+	return result, invalid, nil
+}
+
+func (m *miPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	devices := classifyInstallations(installations, m.validPushTypes)["mi"]
+	n := newVendorNotification(body)
+	return sendByBatch(devices, m.batchSize, func(tokens []string) (types.M, map[string]bool, error) {
+		return m.sendBatch(tokens, n)
+	})
+}
+
+func (m *miPushAdapter) getValidPushTypes() []string {
+	return m.validPushTypes
+}
@@ -0,0 +1,79 @@
+package push
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_miSend(t *testing.T) {
+	var mutex sync.Mutex
+	batches := []string{}
+	forms := []map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.Header.Get("Authorization") != "key=secret" {
+			w.Write([]byte(`{"result":"error","code":22000,"reason":"illegal access"}`))
+			return
+		}
+		r.ParseForm()
+		regIDs := r.Form.Get("registration_id")
+		batches = append(batches, regIDs)
+		forms = append(forms, map[string]string{
+			"title":                   r.Form.Get("title"),
+			"description":             r.Form.Get("description"),
+			"payload":                 r.Form.Get("payload"),
+			"restricted_package_name": r.Form.Get("restricted_package_name"),
+		})
+		badRegIDs := []string{}
+		for _, id := range strings.Split(regIDs, ",") {
+			if strings.HasPrefix(id, "bad") {
+				badRegIDs = append(badRegIDs, id)
+			}
+		}
+		w.Write([]byte(`{"result":"ok","code":0,"data":{"id":"m1","bad_regids":"` + strings.Join(badRegIDs, ",") + `"}}`))
+	}))
+	defer server.Close()
+
+	m := newMiAdapter("secret", "com.example.app", server.URL, server.Client())
+	m.batchSize = 2
+	installations := types.S{
+		types.M{"deviceType": "android", "pushType": "mi", "deviceToken": "a"},
+		types.M{"deviceType": "android", "pushType": "mi", "deviceToken": "bad1"},
+		types.M{"deviceType": "android", "pushType": "mi", "deviceToken": "bad2"},
+		types.M{"deviceType": "android", "pushType": "hms", "deviceToken": "hms"},
+	}
+	body := types.M{"data": types.M{"alert": "hello", "title": "t", "key": "v"}}
+	results := m.send(body, installations, "")
+	/************************************************************/
+	expect := []string{"a:sent", "bad1:invalid", "bad2:invalid"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	expectBatches := []string{"a,bad1", "bad2"}
+	if reflect.DeepEqual(expectBatches, batches) == false {
+		t.Error("expect:", expectBatches, "result:", batches)
+	}
+	expectForm := map[string]string{
+		"title":                   "t",
+		"description":             "hello",
+		"payload":                 `{"key":"v"}`,
+		"restricted_package_name": "com.example.app",
+	}
+	if reflect.DeepEqual(expectForm, forms[0]) == false {
+		t.Error("expect:", expectForm, "result:", forms[0])
+	}
+	/************************************************************/
+	m = newMiAdapter("wrong", "com.example.app", server.URL, server.Client())
+	results = m.send(body, installations[:1], "")
+	expect = []string{"a:failed"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
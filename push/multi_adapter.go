@@ -0,0 +1,86 @@
+package push
+
+import (
+	"sync"
+
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// multiPushAdapter 组合多个推送模块，一次推送同时分发到各个模块
+type multiPushAdapter struct {
+	adapters       []pushAdapter
+	validPushTypes []string
+}
+
+func newMultiPush(adapters []pushAdapter) *multiPushAdapter {
+	m := &multiPushAdapter{
+		adapters:       adapters,
+		validPushTypes: []string{},
+	}
+	seen := map[string]bool{}
+	for _, adapter := range adapters {
+		for _, pushType := range adapter.getValidPushTypes() {
+			if seen[pushType] == false {
+				seen[pushType] = true
+				m.validPushTypes = append(m.validPushTypes, pushType)
+			}
+		}
+	}
+	return m
+}
+
+// dispatchInstallations 为每个设备选择推送模块，规则与 classifyInstallations 一致：
+// 优先按 pushType 匹配，其次按 deviceType 匹配，多个模块均支持时选择靠前的模块
+// 返回的列表与 adapters 一一对应
+func dispatchInstallations(installations types.S, adapters []pushAdapter) []types.S {
+	byPushType := map[string]int{}
+	for i := len(adapters) - 1; i >= 0; i-- {
+		for _, pushType := range adapters[i].getValidPushTypes() {
+			byPushType[pushType] = i
+		}
+	}
+	groups := make([]types.S, len(adapters))
+	for _, installation := range installations {
+		device := utils.M(installation)
+		if device == nil {
+			continue
+		}
+		i, ok := byPushType[utils.S(device["pushType"])]
+		if ok == false {
+			i, ok = byPushType[utils.S(device["deviceType"])]
+		}
+		if ok {
+			groups[i] = append(groups[i], installation)
+		}
+	}
+	return groups
+}
+
+// send 同时向各个模块发送，结果按模块顺序合并
+func (m *multiPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	groups := dispatchInstallations(installations, m.adapters)
+	results := make([][]types.M, len(m.adapters))
+	var wg sync.WaitGroup
+	for i, adapter := range m.adapters {
+		if len(groups[i]) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, adapter pushAdapter) {
+			defer wg.Done()
+			results[i] = adapter.send(body, groups[i], pushStatus)
+		}(i, adapter)
+	}
+	wg.Wait()
+
+	merged := []types.M{}
+	for _, r := range results {
+		merged = append(merged, r...)
+	}
+	return merged
+}
+
+func (m *multiPushAdapter) getValidPushTypes() []string {
+	return m.validPushTypes
+}
@@ -0,0 +1,70 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// recordPushAdapter 记录收到的设备，全部返回发送成功
+type recordPushAdapter struct {
+	validPushTypes []string
+	tokens         []string
+}
+
+func (r *recordPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	results := []types.M{}
+	for _, devices := range classifyInstallations(installations, r.validPushTypes) {
+		for _, device := range devices {
+			r.tokens = append(r.tokens, device["deviceToken"].(string))
+			results = append(results, types.M{"device": device, "transmitted": true})
+		}
+	}
+	return results
+}
+
+func (r *recordPushAdapter) getValidPushTypes() []string {
+	return r.validPushTypes
+}
+
+func Test_multiPushAdapter(t *testing.T) {
+	apns := &recordPushAdapter{validPushTypes: []string{"ios"}}
+	fcm := &recordPushAdapter{validPushTypes: []string{"ios", "android"}}
+	hms := &recordPushAdapter{validPushTypes: []string{"hms"}}
+	mi := &recordPushAdapter{validPushTypes: []string{"mi"}}
+	m := newMultiPush([]pushAdapter{apns, fcm, hms, mi})
+	installations := types.S{
+		types.M{"deviceType": "ios", "deviceToken": "ios1"},
+		types.M{"deviceType": "android", "deviceToken": "android1"},
+		types.M{"deviceType": "android", "pushType": "hms", "deviceToken": "hms1"},
+		types.M{"deviceType": "android", "pushType": "mi", "deviceToken": "mi1"},
+		types.M{"deviceType": "android", "pushType": "oppo", "deviceToken": "android2"},
+		types.M{"deviceType": "winphone", "deviceToken": "win1"},
+	}
+	results := m.send(types.M{}, installations, "")
+	/************************************************************/
+	// 优先按 pushType 分发，其次按 deviceType ，多个模块均支持时选择靠前的模块
+	if len(results) != 5 {
+		t.Error("expect:", 5, "result:", len(results))
+	}
+	expect := map[string][]string{
+		"apns": {"ios1"},
+		"fcm":  {"android1", "android2"},
+		"hms":  {"hms1"},
+		"mi":   {"mi1"},
+	}
+	result := map[string][]string{
+		"apns": apns.tokens,
+		"fcm":  fcm.tokens,
+		"hms":  hms.tokens,
+		"mi":   mi.tokens,
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	expectTypes := []string{"ios", "android", "hms", "mi"}
+	if reflect.DeepEqual(expectTypes, m.getValidPushTypes()) == false {
+		t.Error("expect:", expectTypes, "result:", m.getValidPushTypes())
+	}
+}
@@ -0,0 +1,152 @@
+package push
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	oppoHost = "https://api.push.oppomobile.com"
+	// oppoBatchSize 批量单推每次请求的最大消息数
+	oppoBatchSize = 1000
+	// oppoTokenLifetime auth_token 的有效期
+	oppoTokenLifetime = 24 * time.Hour
+
+	oppoSuccess             = 0     // 请求成功
+	oppoInvalidAuthToken    = 11    // auth_token 无效或已过期
+	oppoInvalidRegistration = 10000 // registration_id 无效
+)
+
+// oppoPushAdapter OPPO 推送，设备的 pushType 为 oppo
+// auth_token 由 app_key 与 master_secret 签名获取，有效期 24 小时，过期前自动刷新
+type oppoPushAdapter struct {
+	validPushTypes []string
+	appKey         string
+	masterSecret   string
+	host           string
+	batchSize      int
+	client         *http.Client
+	token          *accessToken
+}
+
+func newOPPOPush() *oppoPushAdapter {
+	return newOPPOAdapter(config.TConfig.OPPOAppKey, config.TConfig.OPPOMasterSecret, oppoHost, newVendorClient())
+}
+
+func newOPPOAdapter(appKey, masterSecret, host string, client *http.Client) *oppoPushAdapter {
+	o := &oppoPushAdapter{
+		validPushTypes: []string{"oppo"},
+		appKey:         appKey,
+		masterSecret:   masterSecret,
+		host:           host,
+		batchSize:      oppoBatchSize,
+		client:         client,
+	}
+	o.token = &accessToken{refresh: o.refreshToken}
+	return o
+}
+
+// refreshToken 获取 auth_token ， sign 为 sha256(app_key + timestamp + master_secret)
+func (o *oppoPushAdapter) refreshToken() (string, time.Duration, error) {
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	sum := sha256.Sum256([]byte(o.appKey + timestamp + o.masterSecret))
+	form := url.Values{
+		"app_key":   {o.appKey},
+		"sign":      {hex.EncodeToString(sum[:])},
+		"timestamp": {timestamp},
+	}
+	result, _, err := vendorRequest(o.client, o.host+"/server/v1/auth", nil, form, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	data := utils.M(result["data"])
+	if oppoCode(result) != oppoSuccess || data == nil || utils.S(data["auth_token"]) == "" {
+		return "", 0, errors.New("OPPO auth failed: " + utils.S(result["message"]))
+	}
+	return utils.S(data["auth_token"]), oppoTokenLifetime, nil
+}
+
+func oppoCode(result types.M) int {
+	code, ok := result["code"].(float64)
+	if ok == false {
+		return -1
+	}
+	return int(code)
+}
+
+// sendBatch 使用批量单推接口发送，每个 registration_id 单独返回结果， auth_token 无效时刷新后重试一次
+func (o *oppoPushAdapter) sendBatch(tokens []string, n vendorNotification) (types.M, map[string]bool, error) {
+	notification := types.M{
+		"title":   n.title,
+		"content": n.content,
+	}
+	if len(n.extras) > 0 {
+		data, _ := json.Marshal(n.extras)
+		notification["action_parameters"] = string(data)
+	}
+	if n.ttl > 0 {
+		notification["off_line_ttl"] = n.ttl
+	}
+	messages := []types.M{}
+	for _, token := range tokens {
+		messages = append(messages, types.M{
+			"target_type":  2, // registration_id
+			"target_value": token,
+			"notification": notification,
+		})
+	}
+	data, _ := json.Marshal(messages)
+	form := url.Values{"messages": {string(data)}}
+
+	for retry := 0; ; retry++ {
+		token, err := o.token.get()
+		if err != nil {
+			return nil, nil, err
+		}
+		header := http.Header{"auth_token": {token}}
+		result, _, err := vendorRequest(o.client, o.host+"/server/v1/message/notification/unicast_batch", header, form, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := oppoCode(result)
+		if code == oppoInvalidAuthToken && retry == 0 {
+			o.token.reset()
+			continue
+		}
+		if code != oppoSuccess {
+			return nil, nil, errors.New("OPPO push failed: " + utils.S(result["message"]))
+		}
+		// data 格式为 [{"messageId":"...","registrationId":"..."},{"registrationId":"...","errorCode":10000,"errorMessage":"..."}]
+		invalid := map[string]bool{}
+		for _, r := range utils.A(result["data"]) {
+			if item := utils.M(r); item != nil {
+				if errorCode, ok := item["errorCode"].(float64); ok && int(errorCode) == oppoInvalidRegistration {
+					invalid[utils.S(item["registrationId"])] = true
+				}
+			}
+		}
+		return result, invalid, nil
+	}
+}
+
+func (o *oppoPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	devices := classifyInstallations(installations, o.validPushTypes)["oppo"]
+	n := newVendorNotification(body)
+	return sendByBatch(devices, o.batchSize, func(tokens []string) (types.M, map[string]bool, error) {
+		return o.sendBatch(tokens, n)
+	})
+}
+
+func (o *oppoPushAdapter) getValidPushTypes() []string {
+	return o.validPushTypes
+}
@@ -0,0 +1,90 @@
+package push
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_oppoSend(t *testing.T) {
+	var mutex sync.Mutex
+	authCount := 0
+	batches := [][]string{}
+	expired := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/server/v1/auth":
+			sum := sha256.Sum256([]byte(r.Form.Get("app_key") + r.Form.Get("timestamp") + "secret"))
+			if r.Form.Get("sign") != hex.EncodeToString(sum[:]) {
+				w.Write([]byte(`{"code":12,"message":"Invalid Sign"}`))
+				return
+			}
+			authCount++
+			w.Write([]byte(`{"code":0,"message":"Success","data":{"auth_token":"token` + strconv.Itoa(authCount) + `"}}`))
+		case "/server/v1/message/notification/unicast_batch":
+			if r.Header.Get("auth_token") != "token"+strconv.Itoa(authCount) || expired {
+				expired = false
+				w.Write([]byte(`{"code":11,"message":"Invalid AuthToken"}`))
+				return
+			}
+			messages := []types.M{}
+			json.Unmarshal([]byte(r.Form.Get("messages")), &messages)
+			tokens := []string{}
+			data := []types.M{}
+			for _, message := range messages {
+				token := message["target_value"].(string)
+				tokens = append(tokens, token)
+				if token == "bad" {
+					data = append(data, types.M{"registrationId": token, "errorCode": 10000, "errorMessage": "Invalid RegistrationId"})
+				} else {
+					data = append(data, types.M{"registrationId": token, "messageId": "m-" + token})
+				}
+			}
+			batches = append(batches, tokens)
+			response, _ := json.Marshal(types.M{"code": 0, "message": "Success", "data": data})
+			w.Write(response)
+		}
+	}))
+	defer server.Close()
+
+	o := newOPPOAdapter("key", "secret", server.URL, server.Client())
+	o.batchSize = 2
+	installations := types.S{
+		types.M{"deviceType": "android", "pushType": "oppo", "deviceToken": "a"},
+		types.M{"deviceType": "android", "pushType": "oppo", "deviceToken": "bad"},
+		types.M{"deviceType": "android", "pushType": "oppo", "deviceToken": "c"},
+	}
+	body := types.M{"data": types.M{"alert": "hello"}}
+	results := o.send(body, installations, "")
+	/************************************************************/
+	// auth_token 无效时刷新后重试
+	expect := []string{"a:sent", "bad:invalid", "c:sent"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	expectBatches := [][]string{{"a", "bad"}, {"c"}}
+	if reflect.DeepEqual(expectBatches, batches) == false {
+		t.Error("expect:", expectBatches, "result:", batches)
+	}
+	if authCount != 2 {
+		t.Error("expect:", 2, "result:", authCount)
+	}
+	/************************************************************/
+	o = newOPPOAdapter("key", "wrong", server.URL, server.Client())
+	results = o.send(body, installations[:1], "")
+	expect = []string{"a:failed"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
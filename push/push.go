@@ -18,25 +18,92 @@ var queue *pushQueue
 var worker *pushWorker
 
 // init 初始化推送模块
-// 当前仅有模拟的推送模块，
-// 后续添加 APNS、GCM、以及其他第三方推送模块
+// 支持 FCM 、 UMeng 、 APNs 、 HMS 、 Mi 、 OPPO 、 vivo 、 WebPush 以及模拟的推送模块
+// 配置多个模块时使用 | 隔开，一次推送按设备的 pushType 与 deviceType 分发到各个模块
+// PushRateLimits 中配置了发送速率的模块按速率分批发送
 func init() {
-	a := config.TConfig.PushAdapter
-	if a == "tomato" {
-		adapter = newTomatoPush()
-	} else if a == "FCM" {
-		adapter = newFCMPush()
-	} else if a == "UMeng" {
-		adapter = newUMengPush()
+	adapters := []pushAdapter{}
+	rateLimits := parseRateLimits(config.TConfig.PushRateLimits)
+	for _, name := range strings.Split(config.TConfig.PushAdapter, "|") {
+		name = strings.TrimSpace(name)
+		a := newPushAdapter(name)
+		if a == nil {
+			continue
+		}
+		if rate := rateLimits[name]; rate > 0 {
+			a = newRateLimitedAdapter(name, a, rate)
+		}
+		adapters = append(adapters, a)
+	}
+	if len(adapters) == 1 {
+		adapter = adapters[0]
+	} else if len(adapters) > 1 {
+		adapter = newMultiPush(adapters)
 	} else {
 		adapter = nil
 	}
 
-	worker = newPushWorker(adapter, config.TConfig.PushChannel)
-	queue = newPushQueue(config.TConfig.PushChannel, config.TConfig.PushBatchSize)
+	c := config.TConfig
+	jobs := newJobQueue(c.PushQueueType, c.PushQueueURL, c.PushQueueConfig, c.PushChannel)
+	// PushQuietHours 的格式在 config.Validate 中校验
+	quiet, _ := parseQuietHours(c.PushQuietHours)
+	worker = newPushWorker(adapter, jobs, c.PushMaxAttempts, time.Duration(c.PushRetryInterval)*time.Second, quiet)
+	queue = newPushQueue(jobs, c.PushBatchSize)
+	if c.PushWorker && adapter != nil {
+		worker.start()
+	}
+}
+
+// StartWorker 在当前进程中处理推送任务
+// PushWorker 为 false 时 API 进程只负责加入任务，可在单独的进程中调用该方法处理任务
+func StartWorker() error {
+	if adapter == nil {
+		return errs.E(errs.PushMisconfigured, "Missing push configuration")
+	}
+	worker.start()
+	return nil
+}
+
+// StopWorker 停止处理推送任务，正在处理的任务完成后退出
+func StopWorker() {
+	worker.stop()
+}
+
+// newPushAdapter 创建指定名称的推送模块，名称不支持时返回 nil
+func newPushAdapter(a string) pushAdapter {
+	switch a {
+	case "tomato":
+		return newTomatoPush()
+	case "FCM":
+		return newFCMPush()
+	case "UMeng":
+		return newUMengPush()
+	case "APNs":
+		apns, err := newAPNsPush()
+		if err != nil {
+			panic("newAPNsPush: " + err.Error())
+		}
+		return apns
+	case "HMS":
+		return newHMSPush()
+	case "Mi":
+		return newMiPush()
+	case "OPPO":
+		return newOPPOPush()
+	case "vivo":
+		return newVivoPush()
+	case "WebPush":
+		webPush, err := newWebPush()
+		if err != nil {
+			panic("newWebPush: " + err.Error())
+		}
+		return webPush
+	}
+	return nil
 }
 
 // SendPush 发送推送消息
+// body 中有 audience_id 时， where 为受众的查询条件，推送后更新受众的使用时间与次数
 func SendPush(body types.M, where types.M, auth *rest.Auth, onPushStatusSaved func(string)) error {
 	if adapter == nil {
 		return errs.E(errs.PushMisconfigured, "Missing push configuration")
@@ -125,9 +192,36 @@ func SendPush(body types.M, where types.M, auth *rest.Auth, onPushStatusSaved fu
 
 	if err != nil {
 		status.fail(err)
+		return err
 	}
 
-	return err
+	if audienceID := utils.S(body["audience_id"]); audienceID != "" {
+		trackAudienceUsage(audienceID)
+	}
+
+	return nil
+}
+
+// CancelPush 取消已计划或正在发送的推送，处理任务时跳过已取消的推送，已统计的数据保持不变
+func CancelPush(pushStatusID string) error {
+	return changePushStatus(pushStatusID, []string{"scheduled", "pending", "running", "paused"}, "cancelled")
+}
+
+// PausePush 暂停正在发送的推送，已暂停推送的任务延迟处理
+func PausePush(pushStatusID string) error {
+	return changePushStatus(pushStatusID, []string{"running"}, "paused")
+}
+
+// ResumePush 恢复已暂停的推送
+func ResumePush(pushStatusID string) error {
+	return changePushStatus(pushStatusID, []string{"paused"}, "running")
+}
+
+func changePushStatus(pushStatusID string, from []string, to string) error {
+	if pushStatusID == "" {
+		return errs.E(errs.MissingObjectID, "objectId is required.")
+	}
+	return newPushStatus(pushStatusID).transition(from, to)
 }
 
 // getExpirationTime 把过期时间转换为以毫秒为单位的 Unix 时间
@@ -0,0 +1,158 @@
+package push
+
+import (
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const pushDeliveryCollection = "_PushDelivery"
+
+// messageIDKeys 各推送服务响应中的消息 ID 字段
+// APNs 为 apns-id ， FCM 为 message_id ， HMS 为 requestId ， vivo 为 taskId ，友盟为 msg_id 或 task_id ， Web Push 为 location
+var messageIDKeys = []string{"apns-id", "message_id", "requestId", "taskId", "msg_id", "task_id", "location"}
+
+// resultMessageID 读取推送结果中推送服务返回的消息 ID
+// 小米的响应格式为 {"data":{"id":"..."}} ， OPPO 的响应格式为 {"data":[{"messageId":"...","registrationId":"..."}]}
+func resultMessageID(result types.M) string {
+	response := result["response"]
+	for _, key := range messageIDKeys {
+		if id := responseValue(response, key); id != "" {
+			return id
+		}
+	}
+	r, ok := response.(types.M)
+	if ok == false {
+		r, _ = response.(map[string]interface{})
+	}
+	if data := utils.M(r["data"]); data != nil {
+		return utils.S(data["id"])
+	}
+	deviceToken := utils.S(utils.M(result["device"])["deviceToken"])
+	for _, v := range utils.A(r["data"]) {
+		if item := utils.M(v); item != nil && utils.S(item["registrationId"]) == deviceToken {
+			return utils.S(item["messageId"])
+		}
+	}
+	return ""
+}
+
+// attachInstallations 在推送结果中记录设备对应的 _Installation ，用于写入推送记录
+func attachInstallations(results []types.M, installations types.S) {
+	byToken := map[string]types.M{}
+	for _, v := range installations {
+		if installation := utils.M(v); installation != nil {
+			byToken[utils.S(installation["deviceToken"])] = installation
+		}
+	}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		device := utils.M(result["device"])
+		if installation := byToken[utils.S(device["deviceToken"])]; installation != nil {
+			result["installation"] = installation["objectId"]
+			result["installationId"] = installation["installationId"]
+		}
+	}
+}
+
+// pushDeliveries 把推送结果转换为 _PushDelivery 中的记录
+func pushDeliveries(pushStatusID, pushHash string, results []types.M) []types.M {
+	now := utils.TimetoString(time.Now().UTC())
+	deliveries := []types.M{}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		device := utils.M(result["device"])
+		if device == nil {
+			continue
+		}
+		transmitted, _ := result["transmitted"].(bool)
+		delivery := types.M{
+			"objectId":    utils.CreateObjectID(),
+			"createdAt":   now,
+			"updatedAt":   now,
+			"pushStatus":  pushStatusID,
+			"pushHash":    pushHash,
+			"deviceType":  utils.S(device["deviceType"]),
+			"deviceToken": utils.S(device["deviceToken"]),
+			"transmitted": transmitted,
+			// lockdown!
+			"ACL": types.M{},
+		}
+		if v := utils.S(result["installation"]); v != "" {
+			delivery["installation"] = v
+		}
+		if v := utils.S(result["installationId"]); v != "" {
+			delivery["installationId"] = v
+		}
+		if v := utils.S(result["locale"]); v != "" {
+			delivery["locale"] = v
+		}
+		if transmitted {
+			if id := resultMessageID(result); id != "" {
+				delivery["messageId"] = id
+			}
+		} else if reason := resultReason(result); reason != "" {
+			delivery["error"] = reason
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// TrackAppOpened 记录通过推送打开应用， body 为 AppOpened 事件
+// 按 push_hash 与设备的 installationId 找到最近一次未打开的推送记录，设置 openedAt 并增加 _PushStatus 的 numOpened
+func TrackAppOpened(body types.M, installationID string) error {
+	if config.TConfig.PushDeliveryLog == false || body == nil {
+		return nil
+	}
+	pushHash := utils.S(body["push_hash"])
+	if pushHash == "" || installationID == "" {
+		return nil
+	}
+	where := types.M{
+		"pushHash":       pushHash,
+		"installationId": installationID,
+		"openedAt":       types.M{"$exists": false},
+	}
+	options := types.M{
+		"sort":  map[string]interface{}{"createdAt": -1},
+		"limit": 1,
+	}
+	db := orm.TomatoDBController
+	results, err := db.Find(pushDeliveryCollection, where, options)
+	if err != nil || len(results) == 0 {
+		return err
+	}
+	delivery := utils.M(results[0])
+	now := utils.TimetoString(time.Now().UTC())
+	where = types.M{
+		"objectId": delivery["objectId"],
+		"openedAt": types.M{"$exists": false},
+	}
+	update := types.M{
+		"openedAt":  types.M{"__type": "Date", "iso": now},
+		"updatedAt": now,
+	}
+	_, err = db.Update(pushDeliveryCollection, where, update, types.M{}, false)
+	if err != nil {
+		// 同时收到的重复事件已记录
+		if errs.GetErrorCode(err) == errs.ObjectNotFound {
+			return nil
+		}
+		return err
+	}
+	update = types.M{
+		"numOpened": types.M{"__op": "Increment", "amount": 1},
+		"updatedAt": now,
+	}
+	_, err = db.Update(pushStatusCollection, types.M{"objectId": delivery["pushStatus"]}, update, types.M{}, false)
+	return err
+}
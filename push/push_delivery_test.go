@@ -0,0 +1,106 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_resultMessageID(t *testing.T) {
+	var result types.M
+	var id string
+	/************************************************************/
+	// APNs
+	result = types.M{"transmitted": true, "response": types.M{"apns-id": "apns1"}}
+	id = resultMessageID(result)
+	if id != "apns1" {
+		t.Error("expect:", "apns1", "result:", id)
+	}
+	/************************************************************/
+	// FCM
+	result = types.M{"transmitted": true, "response": map[string]string{"message_id": "fcm1"}}
+	id = resultMessageID(result)
+	if id != "fcm1" {
+		t.Error("expect:", "fcm1", "result:", id)
+	}
+	/************************************************************/
+	// 小米
+	result = types.M{"transmitted": true, "response": types.M{"result": "ok", "data": types.M{"id": "mi1"}}}
+	id = resultMessageID(result)
+	if id != "mi1" {
+		t.Error("expect:", "mi1", "result:", id)
+	}
+	/************************************************************/
+	// OPPO 按 registrationId 匹配
+	result = types.M{
+		"device":      types.M{"deviceToken": "b"},
+		"transmitted": true,
+		"response": types.M{"data": []interface{}{
+			map[string]interface{}{"messageId": "oppo1", "registrationId": "a"},
+			map[string]interface{}{"messageId": "oppo2", "registrationId": "b"},
+		}},
+	}
+	id = resultMessageID(result)
+	if id != "oppo2" {
+		t.Error("expect:", "oppo2", "result:", id)
+	}
+	/************************************************************/
+	result = types.M{"transmitted": true}
+	id = resultMessageID(result)
+	if id != "" {
+		t.Error("expect:", "", "result:", id)
+	}
+}
+
+func Test_pushDeliveries(t *testing.T) {
+	installations := types.S{
+		types.M{"objectId": "i1", "installationId": "ins1", "deviceToken": "a"},
+		types.M{"objectId": "i2", "deviceToken": "b"},
+	}
+	results := []types.M{
+		types.M{"device": types.M{"deviceType": "ios", "deviceToken": "a"}, "transmitted": true, "response": types.M{"apns-id": "apns1"}, "locale": "zh-CN"},
+		types.M{"device": types.M{"deviceType": "android", "deviceToken": "b"}, "transmitted": false, "response": map[string]string{"error": "NotRegistered"}},
+		types.M{"transmitted": true},
+	}
+	attachInstallations(results, installations)
+	deliveries := pushDeliveries("status1", "hash1", results)
+	if len(deliveries) != 2 {
+		t.Fatal("expect:", 2, "result:", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if delivery["objectId"] == "" || delivery["createdAt"] == nil || delivery["updatedAt"] == nil {
+			t.Error("expect:", "objectId and createdAt", "result:", delivery)
+		}
+		delete(delivery, "objectId")
+		delete(delivery, "createdAt")
+		delete(delivery, "updatedAt")
+	}
+	expect := []types.M{
+		types.M{
+			"pushStatus":     "status1",
+			"pushHash":       "hash1",
+			"installation":   "i1",
+			"installationId": "ins1",
+			"deviceType":     "ios",
+			"deviceToken":    "a",
+			"transmitted":    true,
+			"messageId":      "apns1",
+			"locale":         "zh-CN",
+			"ACL":            types.M{},
+		},
+		types.M{
+			"pushStatus":   "status1",
+			"pushHash":     "hash1",
+			"installation": "i2",
+			"deviceType":   "android",
+			"deviceToken":  "b",
+			"transmitted":  false,
+			"error":        "NotRegistered",
+			"ACL":          types.M{},
+		},
+	}
+	if reflect.DeepEqual(expect, deliveries) == false {
+		t.Error("expect:", expect, "result:", deliveries)
+	}
+}
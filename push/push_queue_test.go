@@ -0,0 +1,39 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_batchWhere(t *testing.T) {
+	var where, result, expect types.M
+	/************************************************************/
+	where = types.M{"deviceType": "ios", "deviceToken": types.M{"$exists": true}}
+	result = batchWhere(where, []string{"1", "2"})
+	expect = types.M{
+		"deviceType":  "ios",
+		"deviceToken": types.M{"$exists": true},
+		"objectId":    types.M{"$in": types.S{"1", "2"}},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if _, ok := where["objectId"]; ok {
+		t.Error("expect:", "where unchanged", "result:", where)
+	}
+	/************************************************************/
+	// 原条件中已有 objectId 时使用 $and 合并
+	where = types.M{"objectId": types.M{"$ne": "3"}}
+	result = batchWhere(where, []string{"1"})
+	expect = types.M{
+		"$and": types.S{
+			types.M{"objectId": types.M{"$ne": "3"}},
+			types.M{"objectId": types.M{"$in": types.S{"1"}}},
+		},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
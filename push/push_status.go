@@ -5,15 +5,21 @@ import (
 	"time"
 
 	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/orm"
 	"github.com/JuShangEnergy/framework/types"
 	"github.com/JuShangEnergy/framework/utils"
 )
 
-const pushStatusCollection = "_PushStatus"
+const (
+	pushStatusCollection = "_PushStatus"
+	// pushBatchCollection 已统计的批次，objectId 为 batchId
+	pushBatchCollection = "_PushBatch"
+)
 
 type pushStatus struct {
 	objectID string
+	pushHash string
 	db       *orm.DBController
 }
 
@@ -62,6 +68,7 @@ func (p *pushStatus) setInitial(body, where, options types.M) error {
 		alert, _ := json.Marshal(v)
 		pushHash = utils.MD5Hash(string(alert))
 	}
+	p.pushHash = pushHash
 
 	object := types.M{
 		"objectId":  p.objectID,
@@ -102,9 +109,17 @@ func (p *pushStatus) setRunning(count int) {
 //		"device":{
 //			"deviceType":"ios"
 //		},
-//		"transmitted":true
+//		"transmitted":true,
+//		"locale":"zh-CN"
 //	}
-func (p *pushStatus) trackSent(results []types.M) error {
+//
+// locale 为本地化推送中设备匹配的语言，按语言统计发送数据
+//
+// batchID 不为空时只统计一次，任务重复投递时不会重复计数
+// settled 为本次处理完成的设备数，从 count 中减去， deferred 为因免打扰时段延迟发送的设备数
+//
+// 开启 PushDeliveryLog 时，在 _PushDelivery 中记录每个设备的推送结果
+func (p *pushStatus) trackSent(batchID string, results []types.M, settled, deferred int) error {
 	update := types.M{}
 	numSent := 0
 	numFailed := 0
@@ -121,16 +136,22 @@ func (p *pushStatus) trackSent(results []types.M) error {
 			continue
 		}
 		deviceType := utils.S(device["deviceType"])
+		locale := utils.S(result["locale"])
 		// 统计发送数据
 		if result["transmitted"] != nil && result["transmitted"].(bool) {
 			numSent++
 			incrementOp(update, `sentPerType.`+deviceType, 1)
+			if locale != "" {
+				incrementOp(update, `sentPerLocale.`+locale, 1)
+			}
 		} else {
 			numFailed++
 			incrementOp(update, `failedPerType.`+deviceType, 1)
+			if locale != "" {
+				incrementOp(update, `failedPerLocale.`+locale, 1)
+			}
 		}
 	}
-	incrementOp(update, "count", -len(results))
 
 	if numSent > 0 {
 		update["numSent"] = types.M{
@@ -144,15 +165,81 @@ func (p *pushStatus) trackSent(results []types.M) error {
 			"amount": numFailed,
 		}
 	}
-	update["updatedAt"] = utils.TimetoString(time.Now().UTC())
+	if deferred > 0 {
+		update["numDeferred"] = types.M{
+			"__op":   "Increment",
+			"amount": deferred,
+		}
+	}
+
+	tracked, err := p.track(batchID, update, settled)
+	if err != nil || tracked == false {
+		return err
+	}
+	if config.TConfig.PushDeliveryLog {
+		p.trackDeliveries(results)
+	}
+	return nil
+}
+
+// trackDeliveries 按批次写入推送记录，写入失败不影响推送统计
+func (p *pushStatus) trackDeliveries(results []types.M) {
+	p.db.CreateObjects(pushDeliveryCollection, pushDeliveries(p.objectID, p.pushHash, results))
+}
+
+// trackDeadLetter 任务超过最大重试次数，任务中的 settled 个设备均记为发送失败
+func (p *pushStatus) trackDeadLetter(batchID string, settled int) error {
+	update := types.M{}
+	if settled > 0 {
+		update["numFailed"] = types.M{
+			"__op":   "Increment",
+			"amount": settled,
+		}
+		update["numDeadLettered"] = types.M{
+			"__op":   "Increment",
+			"amount": settled,
+		}
+	}
+	_, err := p.track(batchID, update, settled)
+	return err
+}
+
+// track 更新统计数据并从 count 中减去 settled ， count 为 0 时推送完成
+// batchID 不为空时，先在 _PushBatch 中写入该批次，保证同一批次只更新一次，该批次已统计过时返回 false
+// 批次记录保存在单独的表中，避免 _PushStatus 随批次数增长
+func (p *pushStatus) track(batchID string, update types.M, settled int) (bool, error) {
+	incrementOp(update, "count", -settled)
+	now := utils.TimetoString(time.Now().UTC())
+	update["updatedAt"] = now
+
+	if batchID != "" {
+		batch := types.M{
+			"objectId":   batchID,
+			"createdAt":  now,
+			"updatedAt":  now,
+			"pushStatus": p.objectID,
+			// lockdown!
+			"ACL": types.M{},
+		}
+		if err := p.db.Create(pushBatchCollection, batch, types.M{}); err != nil {
+			// 该批次已统计过
+			if errs.GetErrorCode(err) == errs.DuplicateValue {
+				return false, nil
+			}
+			return false, err
+		}
+	}
 
 	where := types.M{
 		"objectId": p.objectID,
 	}
-
 	res, err := p.db.Update(pushStatusCollection, where, update, types.M{}, false)
 	if err != nil {
-		return err
+		// 统计失败时删除批次记录，任务重试时重新统计
+		if batchID != "" {
+			p.db.Destroy(pushBatchCollection, types.M{"objectId": batchID}, types.M{})
+		}
+		return false, err
 	}
 	if res != nil {
 		if c, ok := res["count"].(float64); ok && c == 0 {
@@ -161,13 +248,90 @@ func (p *pushStatus) trackSent(results []types.M) error {
 			p.complete()
 		}
 	}
-	return nil
+	return true, nil
+}
+
+// batchState 返回推送当前的状态，以及批次是否已统计，已统计的批次不需要再次发送
+func (p *pushStatus) batchState(batchID string) (string, bool, error) {
+	results, err := p.db.Find(pushStatusCollection, types.M{"objectId": p.objectID}, types.M{})
+	if err != nil {
+		return "", false, err
+	}
+	if len(results) == 0 {
+		return "", false, nil
+	}
+	status := utils.S(utils.M(results[0])["status"])
+	if batchID == "" {
+		return status, false, nil
+	}
+	batches, err := p.db.Find(pushBatchCollection, types.M{"objectId": batchID}, types.M{"limit": 1})
+	if err != nil {
+		return "", false, err
+	}
+	return status, len(batches) > 0, nil
+}
+
+// transition 把推送状态从 from 中的任一状态改为 to ，已统计的数据保持不变
+// 推送不存在时返回 ObjectNotFound ，当前状态不允许修改时返回 OperationForbidden
+func (p *pushStatus) transition(from []string, to string) error {
+	states := types.S{}
+	for _, s := range from {
+		states = append(states, s)
+	}
+	where := types.M{
+		"objectId": p.objectID,
+		"status":   types.M{"$in": states},
+	}
+	update := types.M{
+		"status":    to,
+		"updatedAt": utils.TimetoString(time.Now().UTC()),
+	}
+	_, err := p.db.Update(pushStatusCollection, where, update, types.M{}, false)
+	if err == nil || errs.GetErrorCode(err) != errs.ObjectNotFound {
+		return err
+	}
+	results, err := p.db.Find(pushStatusCollection, types.M{"objectId": p.objectID}, types.M{})
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return errs.E(errs.ObjectNotFound, "Push status not found.")
+	}
+	status := utils.S(utils.M(results[0])["status"])
+	return errs.E(errs.OperationForbidden, "Can not change push status from "+status+" to "+to+".")
+}
+
+// trackDeviceTokens 记录推送后清除的失效 deviceToken 数量与替换为新 token 的数量
+func (p *pushStatus) trackDeviceTokens(removed, replaced int) {
+	if removed == 0 && replaced == 0 {
+		return
+	}
+	update := types.M{}
+	if removed > 0 {
+		update["numTokensRemoved"] = types.M{
+			"__op":   "Increment",
+			"amount": removed,
+		}
+	}
+	if replaced > 0 {
+		update["numTokensReplaced"] = types.M{
+			"__op":   "Increment",
+			"amount": replaced,
+		}
+	}
+	update["updatedAt"] = utils.TimetoString(time.Now().UTC())
+
+	where := types.M{
+		"objectId": p.objectID,
+	}
+	p.db.Update(pushStatusCollection, where, update, types.M{}, false)
 }
 
-// complete 推送完成
+// complete 推送完成，已取消的推送保持 cancelled
 func (p *pushStatus) complete() {
 	where := types.M{
 		"objectId": p.objectID,
+		"status":   types.M{"$ne": "cancelled"},
 	}
 	update := types.M{
 		"status":    "succeeded",
@@ -177,7 +341,7 @@ func (p *pushStatus) complete() {
 	p.db.Update(pushStatusCollection, where, update, types.M{}, false)
 }
 
-// fail 处理推送失败的情况
+// fail 处理推送失败的情况，已取消的推送保持 cancelled
 func (p *pushStatus) fail(err error) {
 	update := types.M{
 		"errorMessage": err.Error(),
@@ -186,6 +350,7 @@ func (p *pushStatus) fail(err error) {
 	}
 	where := types.M{
 		"objectId": p.objectID,
+		"status":   types.M{"$ne": "cancelled"},
 	}
 	p.db.Update(pushStatusCollection, where, update, types.M{}, false)
 }
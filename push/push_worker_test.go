@@ -0,0 +1,199 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// memoryJobQueue 进程内的任务队列，记录任务的处理结果
+type memoryJobQueue struct {
+	mutex       sync.Mutex
+	jobs        []*pushJob
+	enqueuedAts []time.Time
+	completed   []string
+	retried     []string
+	runAts      []time.Time
+	dead        []string
+	reasons     []string
+}
+
+func (q *memoryJobQueue) enqueue(data string, runAt time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.jobs = append(q.jobs, &pushJob{data: data})
+	q.enqueuedAts = append(q.enqueuedAts, runAt)
+	return nil
+}
+
+func (q *memoryJobQueue) claim(lease time.Duration) (*pushJob, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.jobs) == 0 {
+		return nil, nil
+	}
+	job := q.jobs[0]
+	q.jobs = q.jobs[1:]
+	return job, nil
+}
+
+func (q *memoryJobQueue) complete(job *pushJob) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.completed = append(q.completed, job.data)
+	return nil
+}
+
+func (q *memoryJobQueue) retry(job *pushJob, data string, runAt time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.retried = append(q.retried, data)
+	q.runAts = append(q.runAts, runAt)
+	return nil
+}
+
+func (q *memoryJobQueue) deadLetter(job *pushJob, reason string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.dead = append(q.dead, job.data)
+	q.reasons = append(q.reasons, reason)
+	return nil
+}
+
+func Test_pushWorkerProcess(t *testing.T) {
+	var q *memoryJobQueue
+	var w *pushWorker
+	var item types.M
+	var finals []bool
+	/************************************************************/
+	// 处理成功
+	q = &memoryJobQueue{}
+	w = newPushWorker(nil, q, 3, time.Second, nil)
+	w.handle = func(workItem types.M, final bool) (types.M, error) {
+		return nil, nil
+	}
+	w.process(&pushJob{data: `{"batchId":"a:0","attempts":0}`})
+	if reflect.DeepEqual([]string{`{"batchId":"a:0","attempts":0}`}, q.completed) == false {
+		t.Error("expect:", `{"batchId":"a:0","attempts":0}`, "result:", q.completed)
+	}
+	/************************************************************/
+	// 出错后按指数退避重试，达到最大尝试次数后移入死信存储
+	q = &memoryJobQueue{}
+	w = newPushWorker(nil, q, 3, time.Second, nil)
+	finals = []bool{}
+	w.handle = func(workItem types.M, final bool) (types.M, error) {
+		finals = append(finals, final)
+		return nil, errors.New("db unavailable")
+	}
+	data := `{"batchId":"a:0","attempts":0}`
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		w.process(&pushJob{data: data})
+		if i < 2 {
+			if len(q.retried) != i+1 {
+				t.Fatal("expect:", i+1, "result:", len(q.retried))
+			}
+			data = q.retried[i]
+			json.Unmarshal([]byte(data), &item)
+			expect := types.M{"batchId": "a:0", "attempts": float64(i + 1), "lastError": "db unavailable"}
+			if reflect.DeepEqual(expect, item) == false {
+				t.Error("expect:", expect, "result:", item)
+			}
+			delay := q.runAts[i].Sub(start)
+			if delay < w.backoff(i+1) || delay > w.backoff(i+1)+time.Second {
+				t.Error("expect:", w.backoff(i+1), "result:", delay)
+			}
+		}
+	}
+	if reflect.DeepEqual([]bool{false, false, true}, finals) == false {
+		t.Error("expect:", []bool{false, false, true}, "result:", finals)
+	}
+	if len(q.dead) != 1 || q.dead[0] != data || q.reasons[0] != "db unavailable" {
+		t.Error("expect:", data, "result:", q.dead, q.reasons)
+	}
+	if len(q.completed) != 0 {
+		t.Error("expect:", 0, "result:", len(q.completed))
+	}
+	/************************************************************/
+	// 部分设备需要重试
+	q = &memoryJobQueue{}
+	w = newPushWorker(nil, q, 3, time.Second, nil)
+	w.handle = func(workItem types.M, final bool) (types.M, error) {
+		return types.M{"batchId": "a:0.1", "count": 1}, nil
+	}
+	w.process(&pushJob{data: `{"batchId":"a:0","count":2}`})
+	item = types.M{}
+	if len(q.retried) == 1 {
+		json.Unmarshal([]byte(q.retried[0]), &item)
+	}
+	expect := types.M{"batchId": "a:0.1", "count": float64(1), "attempts": float64(1)}
+	if reflect.DeepEqual(expect, item) == false {
+		t.Error("expect:", expect, "result:", item)
+	}
+	/************************************************************/
+	// 推送已暂停，任务延迟处理且不计入尝试次数，最后一次尝试也不会移入死信存储
+	q = &memoryJobQueue{}
+	w = newPushWorker(nil, q, 1, time.Second, nil)
+	w.handle = func(workItem types.M, final bool) (types.M, error) {
+		return nil, errPushPaused
+	}
+	start := time.Now()
+	w.process(&pushJob{data: `{"batchId":"a:0","attempts":0}`})
+	if reflect.DeepEqual([]string{`{"batchId":"a:0","attempts":0}`}, q.retried) == false {
+		t.Error("expect:", `{"batchId":"a:0","attempts":0}`, "result:", q.retried)
+	}
+	if len(q.runAts) == 1 && q.runAts[0].Sub(start) < pausedPollInterval {
+		t.Error("expect:", pausedPollInterval, "result:", q.runAts[0].Sub(start))
+	}
+	if len(q.dead) != 0 {
+		t.Error("expect:", 0, "result:", len(q.dead))
+	}
+	/************************************************************/
+	// 无法解析的任务直接移入死信存储
+	q = &memoryJobQueue{}
+	w = newPushWorker(nil, q, 3, time.Second, nil)
+	w.process(&pushJob{data: `{`})
+	if len(q.dead) != 1 {
+		t.Error("expect:", 1, "result:", len(q.dead))
+	}
+}
+
+func Test_pushWorkerBackoff(t *testing.T) {
+	w := newPushWorker(nil, &memoryJobQueue{}, 0, 0, nil)
+	result := []time.Duration{w.backoff(1), w.backoff(2), w.backoff(3), w.backoff(20)}
+	expect := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Hour}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if w.maxAttempts != defaultMaxAttempts {
+		t.Error("expect:", defaultMaxAttempts, "result:", w.maxAttempts)
+	}
+}
+
+func Test_splitTransientResults(t *testing.T) {
+	results := []types.M{
+		types.M{"device": types.M{"deviceType": "ios", "deviceToken": "a"}, "transmitted": true},
+		types.M{"device": types.M{"deviceType": "ios", "deviceToken": "b"}, "transmitted": false, "response": types.M{"status": 429, "reason": "TooManyRequests"}},
+		types.M{"device": types.M{"deviceType": "ios", "deviceToken": "c"}, "transmitted": false, "response": types.M{"status": 410, "reason": "Unregistered"}},
+		types.M{"device": types.M{"deviceType": "android", "deviceToken": "d"}, "transmitted": false, "response": map[string]string{"error": "dial tcp: timeout", "reason": unavailable}},
+	}
+	/************************************************************/
+	sent, tokens := splitTransientResults(results, false)
+	if reflect.DeepEqual([]types.M{results[0], results[2]}, sent) == false {
+		t.Error("expect:", []types.M{results[0], results[2]}, "result:", sent)
+	}
+	if reflect.DeepEqual([]string{"b", "d"}, tokens) == false {
+		t.Error("expect:", []string{"b", "d"}, "result:", tokens)
+	}
+	/************************************************************/
+	// 最后一次尝试时全部统计
+	sent, tokens = splitTransientResults(results, true)
+	if reflect.DeepEqual(results, sent) == false || len(tokens) != 0 {
+		t.Error("expect:", results, "result:", sent, tokens)
+	}
+}
@@ -0,0 +1,136 @@
+package push
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// quietHours 免打扰时段，按设备所在时区计算，结束时间早于开始时间表示跨过零点，如 22:00-08:00
+type quietHours struct {
+	start int // 开始时间，为当天的第几分钟
+	end   int // 结束时间，为当天的第几分钟
+}
+
+// parseQuietHours 解析免打扰时段，格式为 22:00-08:00 ，为空时返回 nil
+func parseQuietHours(s string) (*quietHours, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var startHour, startMinute, endHour, endMinute int
+	_, err := fmt.Sscanf(s, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+	if err != nil {
+		return nil, errors.New("invalid quiet hours: " + s)
+	}
+	for _, v := range []int{startHour, endHour} {
+		if v < 0 || v > 23 {
+			return nil, errors.New("invalid quiet hours: " + s)
+		}
+	}
+	for _, v := range []int{startMinute, endMinute} {
+		if v < 0 || v > 59 {
+			return nil, errors.New("invalid quiet hours: " + s)
+		}
+	}
+	q := &quietHours{
+		start: startHour*60 + startMinute,
+		end:   endHour*60 + endMinute,
+	}
+	if q.start == q.end {
+		return nil, errors.New("invalid quiet hours: " + s)
+	}
+	return q, nil
+}
+
+// until 判断 now 在 loc 时区是否处于免打扰时段，是则返回时段的结束时间，否则返回零值
+func (q *quietHours) until(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	end := func(days int) time.Time {
+		return midnight.AddDate(0, 0, days).Add(time.Duration(q.end) * time.Minute)
+	}
+	if q.start < q.end {
+		if minute >= q.start && minute < q.end {
+			return end(0)
+		}
+		return time.Time{}
+	}
+	// 跨过零点
+	if minute >= q.start {
+		return end(1)
+	}
+	if minute < q.end {
+		return end(0)
+	}
+	return time.Time{}
+}
+
+// deferInstallations 拆分处于免打扰时段的设备，按时段结束时间分组
+// 没有 timeZone 或 timeZone 无法识别的设备立即发送
+func (q *quietHours) deferInstallations(installations types.S, now time.Time) (types.S, map[time.Time]types.S) {
+	sendNow := types.S{}
+	deferred := map[time.Time]types.S{}
+	locations := map[string]*time.Location{}
+	for _, v := range installations {
+		installation := utils.M(v)
+		timeZone := ""
+		if installation != nil {
+			timeZone = utils.S(installation["timeZone"])
+		}
+		if timeZone == "" {
+			sendNow = append(sendNow, v)
+			continue
+		}
+		loc, ok := locations[timeZone]
+		if ok == false {
+			loc, _ = time.LoadLocation(timeZone)
+			locations[timeZone] = loc
+		}
+		if loc == nil {
+			sendNow = append(sendNow, v)
+			continue
+		}
+		until := q.until(now, loc)
+		if until.IsZero() {
+			sendNow = append(sendNow, v)
+			continue
+		}
+		until = until.UTC()
+		deferred[until] = append(deferred[until], v)
+	}
+	return sendNow, deferred
+}
+
+// expireDeferred 移除时段结束时推送已过期的分组，返回这些分组中的设备
+// expiration 为推送的 expiration_time ，单位为毫秒，不大于 0 时表示不过期
+func expireDeferred(deferred map[time.Time]types.S, expiration int64) types.S {
+	expired := types.S{}
+	if expiration <= 0 {
+		return expired
+	}
+	for until, ins := range deferred {
+		if until.UnixNano()/int64(time.Millisecond) > expiration {
+			expired = append(expired, ins...)
+			delete(deferred, until)
+		}
+	}
+	return expired
+}
+
+// expiredResults 生成过期设备的发送结果，计为发送失败
+func expiredResults(installations types.S) []types.M {
+	results := []types.M{}
+	for _, v := range installations {
+		results = append(results, types.M{
+			"device":      types.M(utils.M(v)),
+			"transmitted": false,
+			"response":    types.M{"error": "Push expired before quiet hours end"},
+		})
+	}
+	attachInstallations(results, installations)
+	return results
+}
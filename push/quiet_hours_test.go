@@ -0,0 +1,149 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_parseQuietHours(t *testing.T) {
+	var result *quietHours
+	var err error
+	/************************************************************/
+	result, err = parseQuietHours("")
+	if result != nil || err != nil {
+		t.Error("expect:", nil, "result:", result, err)
+	}
+	/************************************************************/
+	result, err = parseQuietHours("22:00-08:30")
+	if err != nil || reflect.DeepEqual(&quietHours{start: 1320, end: 510}, result) == false {
+		t.Error("expect:", &quietHours{start: 1320, end: 510}, "result:", result, err)
+	}
+	/************************************************************/
+	for _, s := range []string{"22:00", "24:00-08:00", "22:60-08:00", "08:00-08:00", "abc"} {
+		result, err = parseQuietHours(s)
+		if result != nil || err == nil {
+			t.Error("expect:", "error", "result:", s, result)
+		}
+	}
+}
+
+func Test_quietHoursUntil(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	var q *quietHours
+	var now time.Time
+	var result time.Time
+	var expect time.Time
+	/************************************************************/
+	// 跨过零点的时段，开始时间之后到次日结束
+	q = &quietHours{start: 22 * 60, end: 8 * 60}
+	now = time.Date(2026, 1, 1, 23, 30, 0, 0, shanghai)
+	result = q.until(now.UTC(), shanghai)
+	expect = time.Date(2026, 1, 2, 8, 0, 0, 0, shanghai)
+	if result.Equal(expect) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 跨过零点的时段，零点之后到当天结束
+	now = time.Date(2026, 1, 1, 3, 0, 0, 0, shanghai)
+	result = q.until(now, shanghai)
+	expect = time.Date(2026, 1, 1, 8, 0, 0, 0, shanghai)
+	if result.Equal(expect) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 不在时段内
+	now = time.Date(2026, 1, 1, 8, 0, 0, 0, shanghai)
+	result = q.until(now, shanghai)
+	if result.IsZero() == false {
+		t.Error("expect:", time.Time{}, "result:", result)
+	}
+	/************************************************************/
+	// 当天的时段
+	q = &quietHours{start: 12 * 60, end: 14 * 60}
+	now = time.Date(2026, 1, 1, 13, 0, 0, 0, shanghai)
+	result = q.until(now, shanghai)
+	expect = time.Date(2026, 1, 1, 14, 0, 0, 0, shanghai)
+	if result.Equal(expect) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	now = time.Date(2026, 1, 1, 15, 0, 0, 0, shanghai)
+	result = q.until(now, shanghai)
+	if result.IsZero() == false {
+		t.Error("expect:", time.Time{}, "result:", result)
+	}
+}
+
+func Test_deferInstallations(t *testing.T) {
+	q := &quietHours{start: 22 * 60, end: 8 * 60}
+	// 上海 23:00 ，伦敦 15:00
+	now := time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)
+	installations := types.S{
+		types.M{"deviceToken": "a", "timeZone": "Asia/Shanghai"},
+		types.M{"deviceToken": "b", "timeZone": "Europe/London"},
+		types.M{"deviceToken": "c"},
+		types.M{"deviceToken": "d", "timeZone": "Invalid/Zone"},
+		types.M{"deviceToken": "e", "timeZone": "Asia/Shanghai"},
+	}
+	sendNow, deferred := q.deferInstallations(installations, now)
+	expectNow := types.S{installations[1], installations[2], installations[3]}
+	if reflect.DeepEqual(expectNow, sendNow) == false {
+		t.Error("expect:", expectNow, "result:", sendNow)
+	}
+	expectDeferred := map[time.Time]types.S{
+		time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC): types.S{installations[0], installations[4]},
+	}
+	if reflect.DeepEqual(expectDeferred, deferred) == false {
+		t.Error("expect:", expectDeferred, "result:", deferred)
+	}
+}
+
+func Test_expireDeferred(t *testing.T) {
+	until1 := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	until2 := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+	var deferred map[time.Time]types.S
+	var result types.S
+	var expect types.S
+	/************************************************************/
+	// 未设置过期时间
+	deferred = map[time.Time]types.S{until1: types.S{"a"}}
+	result = expireDeferred(deferred, 0)
+	if len(result) != 0 || len(deferred) != 1 {
+		t.Error("expect:", 0, "result:", result, deferred)
+	}
+	/************************************************************/
+	// 时段结束时已过期的分组被移除
+	deferred = map[time.Time]types.S{until1: types.S{"a"}, until2: types.S{"b", "c"}}
+	result = expireDeferred(deferred, until1.Add(time.Hour).UnixNano()/int64(time.Millisecond))
+	expect = types.S{"b", "c"}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if reflect.DeepEqual(map[time.Time]types.S{until1: types.S{"a"}}, deferred) == false {
+		t.Error("expect:", until1, "result:", deferred)
+	}
+}
+
+func Test_expiredResults(t *testing.T) {
+	installations := types.S{
+		types.M{"objectId": "1001", "installationId": "i1", "deviceType": "ios", "deviceToken": "t1"},
+	}
+	result := expiredResults(installations)
+	expect := []types.M{
+		types.M{
+			"device":         installations[0],
+			"transmitted":    false,
+			"response":       types.M{"error": "Push expired before quiet hours end"},
+			"installation":   "1001",
+			"installationId": "i1",
+		},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	if isTransientResult(result[0]) {
+		t.Error("expect:", false, "result:", true)
+	}
+}
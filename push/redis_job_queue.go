@@ -0,0 +1,211 @@
+package push
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JuShangEnergy/framework/livequery/pubsub"
+	"github.com/garyburd/redigo/redis"
+)
+
+var errInvalidJobReply = errors.New("invalid redis stream reply")
+
+// redisJobQueue 使用 Redis Streams 实现的持久化推送任务队列，需要 Redis 6.2 及以上版本
+// 任务保存在名为 channel 的 stream 中，通过消费组分配给各个节点，处理完成后确认并删除
+// 节点异常退出时，未确认的任务在空闲超过 lease 后由 XAUTOCLAIM 转给其他节点
+// 等待重试与延迟执行的任务保存在有序集合 <channel>:retry 中，到期后加入 stream
+// 超过最大重试次数的任务移入 <channel>:dead
+type redisJobQueue struct {
+	p        *redis.Pool
+	channel  string
+	group    string
+	consumer string
+	ready    bool // 消费组是否已创建， claim 仅在 worker 的协程中调用
+}
+
+// newRedisJobQueue 创建队列， queueConfig 格式为 password=abc&group=g&consumer=c
+// group 默认为 channel ，consumer 默认为主机名
+func newRedisJobQueue(queueURL, queueConfig, channel string) *redisJobQueue {
+	values, _ := url.ParseQuery(queueConfig)
+	group := values.Get("group")
+	if group == "" {
+		group = channel
+	}
+	consumer := values.Get("consumer")
+	if consumer == "" {
+		consumer, _ = os.Hostname()
+	}
+	if consumer == "" {
+		consumer = "tomato"
+	}
+	return &redisJobQueue{
+		p:        pubsub.NewRedisPool(queueURL, values.Get("password")),
+		channel:  channel,
+		group:    group,
+		consumer: consumer,
+	}
+}
+
+func (q *redisJobQueue) retryKey() string {
+	return q.channel + ":retry"
+}
+
+func (q *redisJobQueue) deadKey() string {
+	return q.channel + ":dead"
+}
+
+// createGroup 创建消费组，从 stream 的第一条消息开始消费，消费组创建前加入的任务同样会被处理
+func (q *redisJobQueue) createGroup() error {
+	conn := q.p.Get()
+	defer conn.Close()
+	_, err := conn.Do("XGROUP", "CREATE", q.channel, q.group, "0", "MKSTREAM")
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// enqueue runAt 未到的任务先保存在 <channel>:retry 中，到期后加入 stream
+func (q *redisJobQueue) enqueue(data string, runAt time.Time) error {
+	conn := q.p.Get()
+	defer conn.Close()
+	var err error
+	if runAt.After(time.Now()) {
+		_, err = conn.Do("ZADD", q.retryKey(), runAt.UnixNano()/int64(time.Millisecond), data)
+	} else {
+		_, err = conn.Do("XADD", q.channel, "*", "message", data)
+	}
+	return err
+}
+
+func (q *redisJobQueue) claim(lease time.Duration) (*pushJob, error) {
+	if q.ready == false {
+		if err := q.createGroup(); err != nil {
+			return nil, err
+		}
+		q.ready = true
+	}
+
+	conn := q.p.Get()
+	defer conn.Close()
+
+	if err := q.promoteRetries(conn); err != nil {
+		return nil, err
+	}
+
+	// 转移空闲超过 lease 的未确认任务
+	reply, err := redis.Values(conn.Do("XAUTOCLAIM", q.channel, q.group, q.consumer, int64(lease/time.Millisecond), "0-0", "COUNT", 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) < 2 {
+		return nil, errInvalidJobReply
+	}
+	jobs, err := parseJobEntries(reply[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) > 0 {
+		return jobs[0], nil
+	}
+
+	reply, err = redis.Values(conn.Do("XREADGROUP", "GROUP", q.group, q.consumer, "COUNT", 1,
+		"BLOCK", int64(jobQueuePollInterval/time.Millisecond), "STREAMS", q.channel, ">"))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 格式为 [[stream, entries]]
+	for _, s := range reply {
+		values, err := redis.Values(s, nil)
+		if err != nil || len(values) != 2 {
+			return nil, errInvalidJobReply
+		}
+		jobs, err := parseJobEntries(values[1])
+		if err != nil {
+			return nil, err
+		}
+		if len(jobs) > 0 {
+			return jobs[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// promoteRetriesScript 在 Redis 中原子地把到期的重试任务从有序集合移入 stream
+// ZREM 与 XADD 在同一脚本中执行，节点在两者之间退出也不会丢失任务
+var promoteRetriesScript = redis.NewScript(2, `
+local members = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, data in ipairs(members) do
+	redis.call("ZREM", KEYS[1], data)
+	redis.call("XADD", KEYS[2], "*", "message", data)
+end
+return #members
+`)
+
+// promoteRetries 把到期的重试任务重新加入 stream
+func (q *redisJobQueue) promoteRetries(conn redis.Conn) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	_, err := promoteRetriesScript.Do(conn, q.retryKey(), q.channel, now, 10)
+	return err
+}
+
+// finish 在同一事务中确认并删除任务，同时执行 commands 中的命令
+func (q *redisJobQueue) finish(job *pushJob, commands ...[]interface{}) error {
+	conn := q.p.Get()
+	defer conn.Close()
+	conn.Send("MULTI")
+	for _, command := range commands {
+		conn.Send(command[0].(string), command[1:]...)
+	}
+	conn.Send("XACK", q.channel, q.group, job.id)
+	conn.Send("XDEL", q.channel, job.id)
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+func (q *redisJobQueue) complete(job *pushJob) error {
+	return q.finish(job)
+}
+
+func (q *redisJobQueue) retry(job *pushJob, data string, runAt time.Time) error {
+	return q.finish(job, []interface{}{"ZADD", q.retryKey(), runAt.UnixNano() / int64(time.Millisecond), data})
+}
+
+func (q *redisJobQueue) deadLetter(job *pushJob, reason string) error {
+	return q.finish(job, []interface{}{"XADD", q.deadKey(), "*", "message", job.data, "error", reason})
+}
+
+// parseJobEntries 解析 stream 中的消息列表，格式为 [[id, [field, value, ...]], ...]
+// 已被删除的消息 fields 为 nil ，返回的任务 data 为空
+func parseJobEntries(reply interface{}) ([]*pushJob, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	jobs := []*pushJob{}
+	for _, e := range entries {
+		entry, err := redis.Values(e, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry) != 2 {
+			return nil, errInvalidJobReply
+		}
+		id, err := redis.String(entry[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		job := &pushJob{id: id}
+		if fields, err := redis.StringMap(entry[1], nil); err == nil {
+			job.data = fields["message"]
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
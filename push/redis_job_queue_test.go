@@ -0,0 +1,33 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseJobEntries(t *testing.T) {
+	var reply interface{}
+	var result, expect []*pushJob
+	var err error
+	/************************************************************/
+	reply = []interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("message"), []byte(`{"batchId":"a:0"}`)}},
+		[]interface{}{[]byte("2-0"), nil},
+	}
+	result, err = parseJobEntries(reply)
+	expect = []*pushJob{
+		&pushJob{id: "1-0", data: `{"batchId":"a:0"}`},
+		&pushJob{id: "2-0"},
+	}
+	if err != nil || reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result, err)
+	}
+	/************************************************************/
+	reply = []interface{}{
+		[]interface{}{[]byte("1-0")},
+	}
+	result, err = parseJobEntries(reply)
+	if err != errInvalidJobReply {
+		t.Error("expect:", errInvalidJobReply, "result:", err)
+	}
+}
@@ -0,0 +1,61 @@
+package push
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/JuShangEnergy/framework/ratelimit"
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// parseRateLimits 解析各推送模块每秒发送的消息数，格式为 APNs=500&FCM=1000
+func parseRateLimits(config string) map[string]float64 {
+	limits := map[string]float64{}
+	values, err := url.ParseQuery(config)
+	if err != nil {
+		return limits
+	}
+	for name := range values {
+		if rate, err := strconv.ParseFloat(values.Get(name), 64); err == nil && rate > 0 {
+			limits[name] = rate
+		}
+	}
+	return limits
+}
+
+// rateLimitedAdapter 限制推送模块每秒发送的消息数
+// 设备按每秒的发送量分批交给推送模块，令牌桶由 ratelimit 模块提供，
+// 使用 Redis 作为 CacheAdapter 时处理推送任务的各个进程共享发送速率
+type rateLimitedAdapter struct {
+	pushAdapter
+	wait      func(n int)
+	batchSize int
+}
+
+func newRateLimitedAdapter(name string, adapter pushAdapter, rate float64) *rateLimitedAdapter {
+	batchSize := int(rate)
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	// 速率小于 1 时桶容量至少为一个批次，否则永远取不到令牌
+	bucket := ratelimit.NewBucket("push:"+name, rate, math.Max(rate, float64(batchSize)))
+	return &rateLimitedAdapter{
+		pushAdapter: adapter,
+		wait:        bucket.Wait,
+		batchSize:   batchSize,
+	}
+}
+
+func (r *rateLimitedAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	results := []types.M{}
+	for start := 0; start < len(installations); start += r.batchSize {
+		end := start + r.batchSize
+		if end > len(installations) {
+			end = len(installations)
+		}
+		r.wait(end - start)
+		results = append(results, r.pushAdapter.send(body, installations[start:end], pushStatus)...)
+	}
+	return results
+}
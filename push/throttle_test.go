@@ -0,0 +1,60 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_parseRateLimits(t *testing.T) {
+	var result map[string]float64
+	var expect map[string]float64
+	/************************************************************/
+	result = parseRateLimits("")
+	expect = map[string]float64{}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	result = parseRateLimits("APNs=500&FCM=1000")
+	expect = map[string]float64{"APNs": 500, "FCM": 1000}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	/************************************************************/
+	// 忽略无效的速率
+	result = parseRateLimits("APNs=abc&FCM=0&HMS=0.5")
+	expect = map[string]float64{"HMS": 0.5}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_rateLimitedAdapter(t *testing.T) {
+	record := &recordPushAdapter{validPushTypes: []string{"ios"}}
+	r := newRateLimitedAdapter("APNs", record, 2)
+	waits := []int{}
+	r.wait = func(n int) { waits = append(waits, n) }
+	installations := types.S{
+		types.M{"deviceType": "ios", "deviceToken": "a"},
+		types.M{"deviceType": "ios", "deviceToken": "b"},
+		types.M{"deviceType": "ios", "deviceToken": "c"},
+		types.M{"deviceType": "ios", "deviceToken": "d"},
+		types.M{"deviceType": "ios", "deviceToken": "e"},
+	}
+	results := r.send(types.M{}, installations, "")
+	if len(results) != 5 {
+		t.Error("expect:", 5, "result:", len(results))
+	}
+	if reflect.DeepEqual([]string{"a", "b", "c", "d", "e"}, record.tokens) == false {
+		t.Error("expect:", []string{"a", "b", "c", "d", "e"}, "result:", record.tokens)
+	}
+	// 分为 3 批，每批发送前取出对应数量的令牌
+	if reflect.DeepEqual([]int{2, 2, 1}, waits) == false {
+		t.Error("expect:", []int{2, 2, 1}, "result:", waits)
+	}
+	if reflect.DeepEqual([]string{"ios"}, r.getValidPushTypes()) == false {
+		t.Error("expect:", []string{"ios"}, "result:", r.getValidPushTypes())
+	}
+}
@@ -180,7 +180,7 @@ func (u *umengPushAdapter) send(body types.M, installations types.S, pushStatus
 				result := types.M{
 					"device":      device,
 					"transmitted": false,
-					"response":    map[string]string{"error": err.Error()},
+					"response":    map[string]string{"error": err.Error(), "reason": unavailable},
 				}
 				results = append(results, result)
 			}
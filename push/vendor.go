@@ -0,0 +1,226 @@
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	// vendorTimeout 厂商推送接口的请求超时时间
+	vendorTimeout = 30 * time.Second
+	// vendorTokenMargin 鉴权 token 到期前提前刷新的时间
+	vendorTokenMargin = 5 * time.Minute
+	// invalidRegistration 厂商返回 token 无效时统一使用的 reason ，由 cleanupDeviceTokens 清除
+	invalidRegistration = "InvalidRegistration"
+)
+
+// transientVendorError 请求未到达厂商，或厂商返回 5xx 、 429 ，稍后重试可能成功
+// 其他错误（如参数错误、鉴权失败）重试也不会成功，推送直接失败
+type transientVendorError struct {
+	err error
+}
+
+func (e transientVendorError) Error() string {
+	return e.err.Error()
+}
+
+// isTransientVendorStatus 判断厂商返回的状态码是否为临时性错误
+func isTransientVendorStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+func newVendorClient() *http.Client {
+	return &http.Client{Timeout: vendorTimeout}
+}
+
+// accessToken 缓存厂商推送的鉴权 token ，过期前自动刷新
+type accessToken struct {
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+	refresh   func() (token string, expiresIn time.Duration, err error)
+}
+
+// get 返回有效的 token ，不存在或即将过期时重新获取
+func (a *accessToken) get() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && time.Now().Add(vendorTokenMargin).Before(a.expiresAt) {
+		return a.token, nil
+	}
+	token, expiresIn, err := a.refresh()
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	a.expiresAt = time.Now().Add(expiresIn)
+	return token, nil
+}
+
+// reset 厂商返回 token 失效时调用，下次发送前重新获取
+func (a *accessToken) reset() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = ""
+}
+
+// vendorNotification 由 body 生成的通知内容，各厂商共用
+type vendorNotification struct {
+	title   string
+	content string
+	extras  types.M // 自定义数据
+	ttl     int64   // 离线消息的保存时间，单位为秒，为 0 时使用厂商的默认值
+}
+
+// newVendorNotification 从 body.data 中获取通知的标题、内容与自定义数据
+func newVendorNotification(body types.M) vendorNotification {
+	n := vendorNotification{extras: types.M{}}
+	pushData := utils.M(body["data"])
+	for key, v := range pushData {
+		switch key {
+		case "alert":
+			if alert := utils.M(v); alert != nil {
+				n.content = utils.S(alert["body"])
+				if title := utils.S(alert["title"]); title != "" && n.title == "" {
+					n.title = title
+				}
+			} else {
+				n.content = utils.S(v)
+			}
+		case "title":
+			n.title = utils.S(v)
+		case "badge", "sound", "content-available", "mutable-content", "category":
+		default:
+			n.extras[key] = v
+		}
+	}
+	// expiration_time 单位为毫秒
+	if t := toInt64(body["expiration_time"]); t > 0 {
+		n.ttl = t/1000 - time.Now().Unix()
+		if n.ttl <= 0 {
+			n.ttl = 1
+		}
+	}
+	return n
+}
+
+// extrasString 返回字符串类型的自定义数据，部分厂商仅支持 string 类型的值
+func (n vendorNotification) extrasString() map[string]string {
+	extras := map[string]string{}
+	for k, v := range n.extras {
+		if s, ok := v.(string); ok {
+			extras[k] = s
+		} else {
+			data, _ := json.Marshal(v)
+			extras[k] = string(data)
+		}
+	}
+	return extras
+}
+
+// vendorRequest 发送 POST 请求并解析 JSON 响应， form 不为 nil 时以表单格式发送，否则以 JSON 格式发送 body
+// 网络错误与 5xx 、 429 状态码返回 transientVendorError
+func vendorRequest(client *http.Client, requestURL string, header http.Header, form url.Values, body interface{}) (types.M, int, error) {
+	var request *http.Request
+	var err error
+	if form != nil {
+		request, err = http.NewRequest(http.MethodPost, requestURL, strings.NewReader(form.Encode()))
+		if err == nil {
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		var data []byte
+		data, err = json.Marshal(body)
+		if err == nil {
+			request, err = http.NewRequest(http.MethodPost, requestURL, bytes.NewReader(data))
+		}
+		if err == nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	// 部分厂商的请求头包含下划线，如 auth_token ，直接使用原始的 key
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, 0, transientVendorError{err: err}
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, response.StatusCode, transientVendorError{err: err}
+	}
+	if isTransientVendorStatus(response.StatusCode) {
+		return nil, response.StatusCode, transientVendorError{err: errors.New(http.StatusText(response.StatusCode) + ": " + string(data))}
+	}
+	result := types.M{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, response.StatusCode, errors.New(http.StatusText(response.StatusCode) + ": " + string(data))
+	}
+	return result, response.StatusCode, nil
+}
+
+// batchSender 向一批 token 发送推送，返回厂商的响应与其中无效的 token
+type batchSender func(tokens []string) (response types.M, invalid map[string]bool, err error)
+
+// sendByBatch 按 batchSize 分批发送，返回 trackSent 需要的结果
+// 无效的 token 标记为 InvalidRegistration ，由 cleanupDeviceTokens 清除
+// 仅 transientVendorError 标记为 Unavailable 并在稍后重试，其他错误直接失败
+func sendByBatch(devices []types.M, batchSize int, send batchSender) []types.M {
+	results := []types.M{}
+	for start := 0; start < len(devices); start += batchSize {
+		end := start + batchSize
+		if end > len(devices) {
+			end = len(devices)
+		}
+		batch := devices[start:end]
+		tokens := []string{}
+		for _, device := range batch {
+			tokens = append(tokens, utils.S(device["deviceToken"]))
+		}
+		response, invalid, err := send(tokens)
+		for i, device := range batch {
+			result := types.M{
+				"device":      device,
+				"transmitted": false,
+			}
+			if _, ok := err.(transientVendorError); ok {
+				result["response"] = types.M{"error": err.Error(), "reason": unavailable}
+			} else if err != nil {
+				result["response"] = types.M{"error": err.Error()}
+			} else if invalid[tokens[i]] {
+				result["response"] = types.M{"error": invalidRegistration, "reason": invalidRegistration}
+			} else {
+				result["transmitted"] = true
+				result["response"] = response
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// tokenSet 把 token 列表转换为集合
+func tokenSet(tokens []string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range tokens {
+		if token != "" {
+			set[token] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,83 @@
+package push
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_vendorRequest(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"code":"1"}`))
+	}))
+	/************************************************************/
+	for _, d := range []struct {
+		status    int
+		transient bool
+	}{
+		{status: http.StatusOK, transient: false},
+		{status: http.StatusBadRequest, transient: false},
+		{status: http.StatusTooManyRequests, transient: true},
+		{status: http.StatusServiceUnavailable, transient: true},
+	} {
+		status = d.status
+		_, _, err := vendorRequest(newVendorClient(), ts.URL, nil, nil, types.M{})
+		_, ok := err.(transientVendorError)
+		if ok != d.transient {
+			t.Error("expect:", d.transient, "result:", ok, d.status, err)
+		}
+	}
+	/************************************************************/
+	// 请求未到达厂商
+	ts.Close()
+	_, _, err := vendorRequest(newVendorClient(), ts.URL, nil, nil, types.M{})
+	if _, ok := err.(transientVendorError); ok == false {
+		t.Error("expect:", "transientVendorError", "result:", err)
+	}
+}
+
+func Test_sendByBatch(t *testing.T) {
+	devices := []types.M{
+		{"deviceType": "mi", "deviceToken": "a"},
+		{"deviceType": "mi", "deviceToken": "b"},
+	}
+	var err error
+	send := func(tokens []string) (types.M, map[string]bool, error) {
+		if err != nil {
+			return nil, nil, err
+		}
+		return types.M{"code": 0.0}, map[string]bool{"b": true}, nil
+	}
+	/************************************************************/
+	results := sendByBatch(devices, 1, send)
+	expect := []types.M{
+		{"device": devices[0], "transmitted": true, "response": types.M{"code": 0.0}},
+		{"device": devices[1], "transmitted": false, "response": types.M{"error": invalidRegistration, "reason": invalidRegistration}},
+	}
+	if reflect.DeepEqual(expect, results) == false {
+		t.Error("expect:", expect, "result:", results)
+	}
+	/************************************************************/
+	// 临时性错误稍后重试
+	err = transientVendorError{err: errors.New("Service Unavailable")}
+	results = sendByBatch(devices[:1], 1, send)
+	if isTransientResult(results[0]) == false {
+		t.Error("expect:", true, "result:", results[0])
+	}
+	/************************************************************/
+	// 其他错误直接失败
+	err = errors.New("Mi push failed: invalid appSecret")
+	results = sendByBatch(devices[:1], 1, send)
+	expect = []types.M{
+		{"device": devices[0], "transmitted": false, "response": types.M{"error": "Mi push failed: invalid appSecret"}},
+	}
+	if reflect.DeepEqual(expect, results) == false || isTransientResult(results[0]) {
+		t.Error("expect:", expect, "result:", results)
+	}
+}
@@ -0,0 +1,181 @@
+package push
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	vivoHost = "https://api-push.vivo.com.cn"
+	// vivoBatchSize 批量推送每次请求的最大 regId 数
+	vivoBatchSize = 1000
+	// vivoTokenLifetime authToken 的有效期
+	vivoTokenLifetime = 24 * time.Hour
+
+	vivoSuccess          = 0     // 请求成功
+	vivoInvalidAuthToken = 10000 // authToken 无效或已过期
+	vivoInvalidRegID     = 10302 // regId 无效
+)
+
+// vivoPushAdapter vivo 推送，设备的 pushType 为 vivo
+// authToken 由 appId 、 appKey 与 appSecret 签名获取，有效期 24 小时，过期前自动刷新
+// 单个设备使用单推接口，多个设备先保存消息体，再使用批量推送接口
+type vivoPushAdapter struct {
+	validPushTypes []string
+	appID          string
+	appKey         string
+	appSecret      string
+	host           string
+	batchSize      int
+	client         *http.Client
+	token          *accessToken
+}
+
+func newVivoPush() *vivoPushAdapter {
+	return newVivoAdapter(config.TConfig.VivoAppID, config.TConfig.VivoAppKey, config.TConfig.VivoAppSecret, vivoHost, newVendorClient())
+}
+
+func newVivoAdapter(appID, appKey, appSecret, host string, client *http.Client) *vivoPushAdapter {
+	v := &vivoPushAdapter{
+		validPushTypes: []string{"vivo"},
+		appID:          appID,
+		appKey:         appKey,
+		appSecret:      appSecret,
+		host:           host,
+		batchSize:      vivoBatchSize,
+		client:         client,
+	}
+	v.token = &accessToken{refresh: v.refreshToken}
+	return v
+}
+
+// refreshToken 获取 authToken ， sign 为 md5(appId + appKey + timestamp + appSecret)
+func (v *vivoPushAdapter) refreshToken() (string, time.Duration, error) {
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	sum := md5.Sum([]byte(v.appID + v.appKey + strconv.FormatInt(timestamp, 10) + v.appSecret))
+	body := types.M{
+		"appId":     v.appID,
+		"appKey":    v.appKey,
+		"timestamp": timestamp,
+		"sign":      hex.EncodeToString(sum[:]),
+	}
+	result, _, err := vendorRequest(v.client, v.host+"/message/auth", nil, nil, body)
+	if err != nil {
+		return "", 0, err
+	}
+	if vivoCode(result) != vivoSuccess || utils.S(result["authToken"]) == "" {
+		return "", 0, errors.New("vivo auth failed: " + utils.S(result["desc"]))
+	}
+	return utils.S(result["authToken"]), vivoTokenLifetime, nil
+}
+
+func vivoCode(result types.M) int {
+	code, ok := result["result"].(float64)
+	if ok == false {
+		return -1
+	}
+	return int(code)
+}
+
+// request 发送请求， authToken 无效时刷新后重试一次
+func (v *vivoPushAdapter) request(path string, body types.M) (types.M, error) {
+	for retry := 0; ; retry++ {
+		token, err := v.token.get()
+		if err != nil {
+			return nil, err
+		}
+		header := http.Header{"authToken": {token}}
+		result, _, err := vendorRequest(v.client, v.host+path, header, nil, body)
+		if err != nil {
+			return nil, err
+		}
+		if vivoCode(result) == vivoInvalidAuthToken && retry == 0 {
+			v.token.reset()
+			continue
+		}
+		return result, nil
+	}
+}
+
+// message 生成通知消息体， skipType 为 1 表示打开应用首页
+func (v *vivoPushAdapter) message(n vendorNotification) types.M {
+	message := types.M{
+		"notifyType": 4,
+		"title":      n.title,
+		"content":    n.content,
+		"skipType":   1,
+		"requestId":  utils.CreateString(16),
+	}
+	if n.ttl > 0 {
+		message["timeToLive"] = n.ttl
+	}
+	if len(n.extras) > 0 {
+		message["clientCustomMap"] = n.extrasString()
+	}
+	return message
+}
+
+func (v *vivoPushAdapter) sendBatch(tokens []string, n vendorNotification) (types.M, map[string]bool, error) {
+	message := v.message(n)
+	if len(tokens) == 1 {
+		message["regId"] = tokens[0]
+		result, err := v.request("/message/send", message)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch vivoCode(result) {
+		case vivoSuccess:
+			return result, nil, nil
+		case vivoInvalidRegID:
+			return result, tokenSet(tokens), nil
+		}
+		return nil, nil, errors.New("vivo push failed: " + utils.S(result["desc"]))
+	}
+
+	result, err := v.request("/message/saveListPayload", message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vivoCode(result) != vivoSuccess {
+		return nil, nil, errors.New("vivo save payload failed: " + utils.S(result["desc"]))
+	}
+	result, err = v.request("/message/pushToList", types.M{
+		"regIds":    tokens,
+		"taskId":    result["taskId"],
+		"requestId": utils.CreateString(16),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if vivoCode(result) != vivoSuccess {
+		return nil, nil, errors.New("vivo push failed: " + utils.S(result["desc"]))
+	}
+	// invalidUsers 格式为 [{"status":1,"userid":"..."}]
+	invalid := map[string]bool{}
+	for _, u := range utils.A(result["invalidUsers"]) {
+		if user := utils.M(u); user != nil {
+			invalid[utils.S(user["userid"])] = true
+		}
+	}
+	return result, invalid, nil
+}
+
+func (v *vivoPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	devices := classifyInstallations(installations, v.validPushTypes)["vivo"]
+	n := newVendorNotification(body)
+	return sendByBatch(devices, v.batchSize, func(tokens []string) (types.M, map[string]bool, error) {
+		return v.sendBatch(tokens, n)
+	})
+}
+
+func (v *vivoPushAdapter) getValidPushTypes() []string {
+	return v.validPushTypes
+}
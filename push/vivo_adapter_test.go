@@ -0,0 +1,97 @@
+package push
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_vivoSend(t *testing.T) {
+	var mutex sync.Mutex
+	authCount := 0
+	requests := []string{}
+	expired := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		data, _ := ioutil.ReadAll(r.Body)
+		body := types.M{}
+		json.Unmarshal(data, &body)
+		if r.URL.Path == "/message/auth" {
+			timestamp := strconv.FormatInt(int64(body["timestamp"].(float64)), 10)
+			sum := md5.Sum([]byte(fmt.Sprint(body["appId"], body["appKey"], timestamp, "secret")))
+			if body["sign"] != hex.EncodeToString(sum[:]) {
+				w.Write([]byte(`{"result":10050,"desc":"sign error"}`))
+				return
+			}
+			authCount++
+			w.Write([]byte(`{"result":0,"desc":"ok","authToken":"token` + strconv.Itoa(authCount) + `"}`))
+			return
+		}
+		if r.Header.Get("authToken") != "token"+strconv.Itoa(authCount) || expired {
+			expired = false
+			w.Write([]byte(`{"result":10000,"desc":"authToken invalid"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/message/send":
+			requests = append(requests, "send:"+body["regId"].(string))
+			if body["regId"] == "bad" {
+				w.Write([]byte(`{"result":10302,"desc":"regId invalid"}`))
+				return
+			}
+			w.Write([]byte(`{"result":0,"desc":"ok","taskId":"t1"}`))
+		case "/message/saveListPayload":
+			requests = append(requests, "save:"+body["content"].(string))
+			w.Write([]byte(`{"result":0,"desc":"ok","taskId":"t2"}`))
+		case "/message/pushToList":
+			regIDs := []string{}
+			for _, id := range body["regIds"].([]interface{}) {
+				regIDs = append(regIDs, id.(string))
+			}
+			requests = append(requests, fmt.Sprint("list:", body["taskId"], regIDs))
+			w.Write([]byte(`{"result":0,"desc":"ok","invalidUsers":[{"status":1,"userid":"bad2"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	v := newVivoAdapter("id", "key", "secret", server.URL, server.Client())
+	v.batchSize = 2
+	installations := types.S{
+		types.M{"deviceType": "android", "pushType": "vivo", "deviceToken": "a"},
+		types.M{"deviceType": "android", "pushType": "vivo", "deviceToken": "bad2"},
+		types.M{"deviceType": "android", "pushType": "vivo", "deviceToken": "bad"},
+	}
+	body := types.M{"data": types.M{"alert": "hello", "key": 1}}
+	results := v.send(body, installations, "")
+	/************************************************************/
+	// 多个设备使用批量推送，单个设备使用单推， authToken 无效时刷新后重试
+	expect := []string{"a:sent", "bad2:invalid", "bad:invalid"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+	expectRequests := []string{"save:hello", "list:t2[a bad2]", "send:bad"}
+	if reflect.DeepEqual(expectRequests, requests) == false {
+		t.Error("expect:", expectRequests, "result:", requests)
+	}
+	if authCount != 2 {
+		t.Error("expect:", 2, "result:", authCount)
+	}
+	/************************************************************/
+	v = newVivoAdapter("id", "key", "wrong", server.URL, server.Client())
+	results = v.send(body, installations[:1], "")
+	expect = []string{"a:failed"}
+	if result := resultSummary(results); reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
@@ -0,0 +1,309 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+const (
+	// webPushRecordSize 加密内容的记录大小，推送内容只使用一条记录
+	webPushRecordSize = 4096
+	// webPushMaxPayload 推送内容的最大长度，请求体最大为 4096 字节，减去 86 字节的头部、 16 字节的认证标签与 1 字节的分隔符
+	webPushMaxPayload = 3993
+	// webPushDefaultTTL 推送服务保存离线消息的默认时间，单位为秒
+	webPushDefaultTTL = 4 * 7 * 24 * 3600
+	// webPushTokenLifetime VAPID JWT 的有效期，不能超过 24 小时
+	webPushTokenLifetime = 12 * time.Hour
+	// webPushConcurrency 同时发送的最大请求数
+	webPushConcurrency = 20
+)
+
+// webPushAdapter 按 RFC 8030 向浏览器发送 Web Push ，使用 VAPID （ RFC 8292 ）认证，
+// 推送内容按 RFC 8291 使用 aes128gcm 加密，设备的 deviceType 为 web
+// _Installation 的 deviceToken 为订阅的 endpoint ， webPushKeys 为订阅的 {"p256dh":"...","auth":"..."}
+type webPushAdapter struct {
+	validPushTypes []string
+	publicKey      []byte
+	key            *ecdsa.PrivateKey
+	subject        string
+	client         *http.Client
+	mutex          sync.Mutex
+	tokens         map[string]webPushToken
+}
+
+// webPushToken 按推送服务的 origin 缓存的 VAPID JWT
+type webPushToken struct {
+	token    string
+	issuedAt time.Time
+}
+
+func newWebPush() (*webPushAdapter, error) {
+	return newWebPushAdapter(config.TConfig.WebPushPublicKey, config.TConfig.WebPushPrivateKey, config.TConfig.WebPushSubject, newVendorClient())
+}
+
+// newWebPushAdapter 创建 Web Push 推送模块， publicKey 与 privateKey 为 base64url 编码的 VAPID 密钥对
+func newWebPushAdapter(publicKey, privateKey, subject string, client *http.Client) (*webPushAdapter, error) {
+	d, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, errors.New("WebPushPrivateKey is not valid base64url")
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.New("WebPushPrivateKey is not a valid P-256 private key")
+	}
+	pub, err := decodeBase64URL(publicKey)
+	if err != nil || bytes.Equal(pub, priv.PublicKey().Bytes()) == false {
+		return nil, errors.New("WebPushPublicKey does not match WebPushPrivateKey")
+	}
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &webPushAdapter{
+		validPushTypes: []string{"web"},
+		publicKey:      pub,
+		key:            key,
+		subject:        subject,
+		client:         client,
+		tokens:         map[string]webPushToken{},
+	}, nil
+}
+
+// decodeBase64URL 解码 base64url ，兼容带有 = 填充的格式
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// vapidToken 返回推送服务 origin 对应的 VAPID JWT ，过期前重新生成
+func (w *webPushAdapter) vapidToken(audience string) (string, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if t, ok := w.tokens[audience]; ok && time.Since(t.issuedAt) < webPushTokenLifetime/2 {
+		return t.token, nil
+	}
+	now := time.Now()
+	claims := types.M{
+		"aud": audience,
+		"exp": now.Add(webPushTokenLifetime).Unix(),
+		"sub": w.subject,
+	}
+	token, err := signES256(w.key, types.M{"typ": "JWT", "alg": "ES256"}, claims)
+	if err != nil {
+		return "", err
+	}
+	w.tokens[audience] = webPushToken{token: token, issuedAt: now}
+	return token, nil
+}
+
+// hkdfSHA256 RFC 5869 中的 HKDF ， length 不能超过 32
+func hkdfSHA256(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+
+// encryptWebPush 按 RFC 8291 加密推送内容， p256dh 与 auth 为浏览器订阅的公钥与认证密钥
+// salt 与 serverKey 每次推送随机生成，返回 aes128gcm 格式的请求体
+func encryptWebPush(payload, p256dh, auth, salt []byte, serverKey *ecdh.PrivateKey) ([]byte, error) {
+	if len(payload) > webPushMaxPayload {
+		return nil, errors.New("payload is too large")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, errors.New("invalid p256dh")
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("invalid auth")
+	}
+	ecdhSecret, err := serverKey.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := serverKey.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), p256dh...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdfSHA256(auth, ecdhSecret, keyInfo, 32)
+	cek := hkdfSHA256(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfSHA256(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 只有一条记录，以 0x02 作为最后一条记录的分隔符
+	plaintext := append(append([]byte{}, payload...), 2)
+
+	header := make([]byte, 21)
+	copy(header, salt)
+	binary.BigEndian.PutUint32(header[16:20], webPushRecordSize)
+	header[20] = byte(len(asPublic))
+	body := append(header, asPublic...)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// webPushTTL 由 expiration_time 计算推送服务保存离线消息的时间
+func webPushTTL(body types.M) int64 {
+	if n := newVendorNotification(body); n.ttl > 0 {
+		return n.ttl
+	}
+	return webPushDefaultTTL
+}
+
+// sendToDevice 向单个订阅发送推送，返回 trackSent 需要的结果
+// 推送服务返回错误时 reason 为去掉空格的状态描述，如 410 对应 Gone ，订阅已失效的 deviceToken 会被清除
+func (w *webPushAdapter) sendToDevice(device types.M, payload []byte, ttl int64) types.M {
+	result := types.M{
+		"device":      device,
+		"transmitted": false,
+	}
+	fail := func(err error, reason string) types.M {
+		result["response"] = types.M{"error": err.Error(), "reason": reason}
+		return result
+	}
+
+	if len(payload) > webPushMaxPayload {
+		return fail(errors.New("payload is too large"), "PayloadTooLarge")
+	}
+	endpoint := utils.S(device["deviceToken"])
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fail(errors.New("invalid subscription endpoint"), invalidRegistration)
+	}
+	keys := utils.M(device["webPushKeys"])
+	p256dh, err1 := decodeBase64URL(utils.S(keys["p256dh"]))
+	auth, err2 := decodeBase64URL(utils.S(keys["auth"]))
+	if err1 != nil || err2 != nil || len(p256dh) == 0 || len(auth) == 0 {
+		return fail(errors.New("invalid subscription keys"), invalidRegistration)
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return fail(err, unavailable)
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return fail(err, unavailable)
+	}
+	body, err := encryptWebPush(payload, p256dh, auth, salt, serverKey)
+	if err != nil {
+		return fail(err, invalidRegistration)
+	}
+
+	token, err := w.vapidToken(u.Scheme + "://" + u.Host)
+	if err != nil {
+		return fail(err, unavailable)
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fail(err, unavailable)
+	}
+	request.Header.Set("Authorization", "vapid t="+token+", k="+base64.RawURLEncoding.EncodeToString(w.publicKey))
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", strconv.FormatInt(ttl, 10))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fail(err, unavailable)
+	}
+	defer response.Body.Close()
+	data, _ := ioutil.ReadAll(response.Body)
+
+	res := types.M{
+		"status": response.StatusCode,
+	}
+	result["response"] = res
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		// Location 为推送服务中消息的地址
+		res["location"] = response.Header.Get("Location")
+		result["transmitted"] = true
+		return result
+	}
+	res["reason"] = strings.Replace(http.StatusText(response.StatusCode), " ", "", -1)
+	res["error"] = string(data)
+	if len(data) == 0 {
+		res["error"] = http.StatusText(response.StatusCode)
+	}
+	return result
+}
+
+// send 推送内容为 body.data 的 JSON ，由网页的 Service Worker 解析并显示通知
+func (w *webPushAdapter) send(body types.M, installations types.S, pushStatus string) []types.M {
+	devices := classifyInstallations(installations, w.validPushTypes)["web"]
+	results := make([]types.M, len(devices))
+	if len(devices) == 0 {
+		return results
+	}
+
+	data := utils.M(body["data"])
+	if data == nil {
+		data = types.M{}
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		for i, device := range devices {
+			results[i] = types.M{
+				"device":      device,
+				"transmitted": false,
+				"response":    types.M{"error": err.Error()},
+			}
+		}
+		return results
+	}
+	ttl := webPushTTL(body)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webPushConcurrency)
+	for i, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, device types.M) {
+			defer wg.Done()
+			results[i] = w.sendToDevice(device, payload, ttl)
+			<-sem
+		}(i, device)
+	}
+	wg.Wait()
+	return results
+}
+
+func (w *webPushAdapter) getValidPushTypes() []string {
+	return w.validPushTypes
+}
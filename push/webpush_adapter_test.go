@@ -0,0 +1,229 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+// decryptWebPush 浏览器端按 RFC 8291 解密推送内容，用于验证加密结果
+func decryptWebPush(body []byte, uaKey *ecdh.PrivateKey, auth []byte) ([]byte, bool) {
+	if len(body) < 21 {
+		return nil, false
+	}
+	salt := body[:16]
+	idlen := int(body[20])
+	if len(body) < 21+idlen || binary.BigEndian.Uint32(body[16:20]) != webPushRecordSize {
+		return nil, false
+	}
+	asPublicBytes := body[21 : 21+idlen]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return nil, false
+	}
+	ecdhSecret, err := uaKey.ECDH(asPublic)
+	if err != nil {
+		return nil, false
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), uaKey.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdfSHA256(auth, ecdhSecret, keyInfo, 32)
+	cek := hkdfSHA256(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfSHA256(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[21+idlen:], nil)
+	if err != nil || len(plaintext) == 0 || plaintext[len(plaintext)-1] != 2 {
+		return nil, false
+	}
+	return plaintext[:len(plaintext)-1], true
+}
+
+// verifyVAPID 校验 Authorization 头中的 VAPID JWT ，返回 JWT 中的 claims
+func verifyVAPID(authorization string) (types.M, bool) {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ",") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "t=") {
+			token = part[2:]
+		} else if strings.HasPrefix(part, "k=") {
+			key = part[2:]
+		}
+	}
+	pub, err := decodeBase64URL(key)
+	if err != nil || len(pub) != 65 {
+		return nil, false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	signature, err := decodeBase64URL(parts[2])
+	if err != nil || len(signature) != 64 {
+		return nil, false
+	}
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pub[1:33]),
+		Y:     new(big.Int).SetBytes(pub[33:]),
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if ecdsa.Verify(publicKey, hash[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) == false {
+		return nil, false
+	}
+	data, _ := decodeBase64URL(parts[1])
+	claims := types.M{}
+	json.Unmarshal(data, &claims)
+	return claims, true
+}
+
+func Test_encryptWebPush(t *testing.T) {
+	// RFC 8291 附录 A 中的示例
+	decode := func(s string) []byte {
+		b, _ := decodeBase64URL(s)
+		return b
+	}
+	asKey, _ := ecdh.P256().NewPrivateKey(decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	uaPublic := decode("BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+	auth := decode("BTBZMqHH6r4Tts7J_aSIgg")
+	salt := decode("DGv6ra1nlYgDCS1FRnbzlw")
+	result, err := encryptWebPush([]byte("When I grow up, I want to be a watermelon"), uaPublic, auth, salt, asKey)
+	expect := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if err != nil || base64.RawURLEncoding.EncodeToString(result) != expect {
+		t.Error("expect:", expect, "result:", base64.RawURLEncoding.EncodeToString(result), err)
+	}
+	/************************************************************/
+	// 推送内容过长
+	_, err = encryptWebPush(make([]byte, webPushMaxPayload+1), uaPublic, auth, salt, asKey)
+	if err == nil {
+		t.Error("expect:", "payload is too large", "result:", nil)
+	}
+}
+
+func Test_newWebPushAdapter(t *testing.T) {
+	key, _ := ecdh.P256().GenerateKey(rand.Reader)
+	other, _ := ecdh.P256().GenerateKey(rand.Reader)
+	publicKey := base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	privateKey := base64.RawURLEncoding.EncodeToString(key.Bytes())
+	/************************************************************/
+	if _, err := newWebPushAdapter(publicKey, privateKey, "mailto:admin@example.com", http.DefaultClient); err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	/************************************************************/
+	// 公钥与私钥不匹配
+	otherPublicKey := base64.RawURLEncoding.EncodeToString(other.PublicKey().Bytes())
+	if _, err := newWebPushAdapter(otherPublicKey, privateKey, "mailto:admin@example.com", http.DefaultClient); err == nil {
+		t.Error("expect:", "error", "result:", nil)
+	}
+	/************************************************************/
+	if _, err := newWebPushAdapter(publicKey, "abc", "mailto:admin@example.com", http.DefaultClient); err == nil {
+		t.Error("expect:", "error", "result:", nil)
+	}
+}
+
+func Test_webPushSend(t *testing.T) {
+	var mutex sync.Mutex
+	received := map[string]string{}
+	subscriptions := map[string]*ecdh.PrivateKey{}
+	auth := []byte("0123456789abcdef")
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		claims, ok := verifyVAPID(r.Header.Get("Authorization"))
+		if ok == false || claims["aud"] != server.URL || claims["sub"] != "mailto:admin@example.com" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/push/")
+		uaKey := subscriptions[id]
+		if uaKey == nil {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		payload, ok := decryptWebPush(data, uaKey, auth)
+		if ok == false {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received[id] = string(payload)
+		w.Header().Set("Location", server.URL+"/message/"+id)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	vapidKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	w, err := newWebPushAdapter(
+		base64.RawURLEncoding.EncodeToString(vapidKey.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()),
+		"mailto:admin@example.com",
+		server.Client(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installation := func(id string) types.M {
+		uaKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+		if id != "gone" {
+			subscriptions[id] = uaKey
+		}
+		return types.M{
+			"deviceType":  "web",
+			"deviceToken": server.URL + "/push/" + id,
+			"webPushKeys": types.M{
+				"p256dh": base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes()),
+				"auth":   base64.RawURLEncoding.EncodeToString(auth),
+			},
+		}
+	}
+	installations := types.S{
+		installation("a"),
+		installation("gone"),
+		types.M{"deviceType": "web", "deviceToken": server.URL + "/push/nokeys"},
+		types.M{"deviceType": "ios", "deviceToken": "ios1"},
+	}
+	body := types.M{"data": types.M{"alert": "Hello", "url": "/orders"}}
+	results := w.send(body, installations, "")
+
+	expect := []string{
+		server.URL + "/push/a:sent",
+		server.URL + "/push/gone:invalid",
+		server.URL + "/push/nokeys:invalid",
+	}
+	if reflect.DeepEqual(expect, resultSummary(results)) == false {
+		t.Error("expect:", expect, "result:", resultSummary(results))
+	}
+	if received["a"] != `{"alert":"Hello","url":"/orders"}` {
+		t.Error("expect:", `{"alert":"Hello","url":"/orders"}`, "result:", received["a"])
+	}
+	if id := resultMessageID(results[0]); id != server.URL+"/message/a" {
+		t.Error("expect:", server.URL+"/message/a", "result:", id)
+	}
+	if reason := resultReason(results[1]); reason != "Gone" {
+		t.Error("expect:", "Gone", "result:", reason)
+	}
+}
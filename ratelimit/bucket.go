@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+)
+
+// Bucket 与请求限流共用 cache 模块中的令牌桶，用于限制后台任务的发送速率
+// 使用 Redis 作为 CacheAdapter 时多个实例共享同一个令牌桶
+type Bucket struct {
+	key      string
+	rate     float64
+	capacity float64
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// NewBucket 每秒产生 rate 个令牌，最多积累 capacity 个
+func NewBucket(key string, rate, capacity float64) *Bucket {
+	return &Bucket{
+		key:      key,
+		rate:     rate,
+		capacity: capacity,
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// Wait 取出 n 个令牌，令牌不足时等待补充， n 大于 capacity 时按 capacity 计算
+func (b *Bucket) Wait(n int) {
+	cost := math.Min(float64(n), b.capacity)
+	ttl := int64(math.Ceil(b.capacity/b.rate)) + 1
+	for {
+		now := float64(b.now().UnixNano()) / 1e9
+		tokens, allowed := cache.RateLimit.TakeToken(b.key, cost, b.capacity, b.rate, now, ttl)
+		if allowed {
+			return
+		}
+		// 多等待 1 毫秒，避免时间换算为秒时的浮点误差导致令牌仍差一点而再次等待
+		b.sleep(time.Duration((cost-tokens)/b.rate*float64(time.Second)) + time.Millisecond)
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+)
+
+func Test_Bucket(t *testing.T) {
+	cache.InitCache()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sleeps := []time.Duration{}
+	b := NewBucket("push:test", 10, 10)
+	b.now = func() time.Time { return now }
+	b.sleep = func(d time.Duration) {
+		// 时间以浮点秒计算，比较时精确到毫秒
+		sleeps = append(sleeps, d.Round(time.Millisecond))
+		now = now.Add(d)
+	}
+	/************************************************************/
+	// 初始令牌足够，无需等待
+	b.Wait(10)
+	if len(sleeps) != 0 {
+		t.Error("expect:", 0, "result:", sleeps)
+	}
+	/************************************************************/
+	// 令牌不足时等待补充
+	b.Wait(5)
+	b.Wait(10)
+	expect := []time.Duration{501 * time.Millisecond, time.Second}
+	if reflect.DeepEqual(expect, sleeps) == false {
+		t.Error("expect:", expect, "result:", sleeps)
+	}
+	/************************************************************/
+	// 空闲后令牌最多积累 capacity 个
+	now = now.Add(time.Minute)
+	sleeps = []time.Duration{}
+	b.Wait(10)
+	b.Wait(1)
+	expect = []time.Duration{101 * time.Millisecond}
+	if reflect.DeepEqual(expect, sleeps) == false {
+		t.Error("expect:", expect, "result:", sleeps)
+	}
+	/************************************************************/
+	// 超过 capacity 时按 capacity 计算
+	sleeps = []time.Duration{}
+	now = now.Add(time.Minute)
+	b.Wait(20)
+	if len(sleeps) != 0 {
+		t.Error("expect:", 0, "result:", sleeps)
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/rest"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+	"github.com/beego/beego/context"
+)
+
+// Limiter 按规则对请求限流，令牌桶状态保存在 cache 模块中，
+// 使用 Redis 作为 CacheAdapter 时多个实例共享限流计数
+type Limiter struct {
+	rules []*Rule
+}
+
+// NewLimiter ...
+func NewLimiter(rules []*Rule) *Limiter {
+	return &Limiter{rules: rules}
+}
+
+// result 单条规则的限流结果
+type result struct {
+	rule       *Rule
+	allowed    bool
+	remaining  int
+	reset      int
+	retryAfter int
+}
+
+// Filter 返回 beego 过滤器，超出限制时返回 429 ，并在响应头中返回限流信息：
+// X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset、Retry-After
+func (l *Limiter) Filter() func(ctx *context.Context) {
+	return func(ctx *context.Context) {
+		method := ctx.Input.Method()
+		if method == "OPTIONS" {
+			return
+		}
+		// Master 请求不限流
+		if masterKey := ctx.Input.Header("X-Parse-Master-Key"); masterKey != "" && masterKey == config.TConfig.MasterKey {
+			return
+		}
+
+		var limited, tightest *result
+		for i, rule := range l.rules {
+			if rule.match(method, ctx.Input.URL()) == false {
+				continue
+			}
+			key := strconv.Itoa(i) + ":" + rule.Key + ":" + requestKey(ctx, rule.Key)
+			r := l.take(key, rule, time.Now())
+			if r.allowed == false && (limited == nil || r.retryAfter > limited.retryAfter) {
+				limited = r
+			}
+			if tightest == nil || r.remaining < tightest.remaining {
+				tightest = r
+			}
+		}
+		if tightest == nil {
+			return
+		}
+		if limited != nil {
+			tightest = limited
+		}
+		ctx.Output.Header("X-RateLimit-Limit", strconv.Itoa(tightest.rule.Limit))
+		ctx.Output.Header("X-RateLimit-Remaining", strconv.Itoa(tightest.remaining))
+		ctx.Output.Header("X-RateLimit-Reset", strconv.Itoa(tightest.reset))
+		if limited != nil {
+			ctx.Output.Header("Retry-After", strconv.Itoa(limited.retryAfter))
+			ctx.Output.SetStatus(429)
+			ctx.Output.JSON(errs.ErrorMessageToMap(errs.RequestLimitExceeded, "Too many requests."), false, false)
+		}
+	}
+}
+
+// take 从令牌桶中取出一个令牌，令牌桶的读取与更新由 cache 模块原子执行
+func (l *Limiter) take(key string, rule *Rule, now time.Time) *result {
+	capacity := float64(rule.Limit)
+	rate := capacity / float64(rule.Period)
+	tokens, allowed := cache.RateLimit.TakeToken(key, 1, capacity, rate, float64(now.UnixNano())/1e9, int64(rule.Period))
+
+	r := &result{rule: rule, allowed: allowed}
+	if allowed == false {
+		r.retryAfter = int(math.Ceil((1 - tokens) / rate))
+	}
+	r.remaining = int(math.Floor(tokens))
+	r.reset = int(math.Ceil((capacity - tokens) / rate))
+	return r
+}
+
+// requestKey 获取请求在指定限流维度上的标识，无法获取用户或设备时退化为 IP
+func requestKey(ctx *context.Context, key string) string {
+	switch key {
+	case "user":
+		if token := ctx.Input.Header("X-Parse-Session-Token"); token != "" {
+			auth, err := rest.GetAuthForSessionToken(token, "", &types.RequestInfo{})
+			if err == nil && auth.User != nil {
+				return "user:" + utils.S(auth.User["objectId"])
+			}
+		}
+	case "installation":
+		if installationID := ctx.Input.Header("X-Parse-Installation-Id"); installationID != "" {
+			return "installation:" + installationID
+		}
+	}
+	return "ip:" + ctx.Input.IP()
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/beego/beego/context"
+)
+
+func Test_ParseRules(t *testing.T) {
+	var rules []*Rule
+	var err error
+	var expect []*Rule
+	/************************************************************/
+	rules, err = ParseRules("")
+	if err != nil || len(rules) != 0 {
+		t.Error("expect:", 0, "result:", rules, err)
+	}
+	/************************************************************/
+	rules, err = ParseRules(`[{"path":"/v1/login","method":"post","limit":10,"period":60}]`)
+	expect = []*Rule{
+		&Rule{Path: "/v1/login", Method: "POST", Key: "ip", Limit: 10, Period: 60},
+	}
+	if err != nil || reflect.DeepEqual(expect, rules) == false {
+		t.Error("expect:", expect, "result:", rules, err)
+	}
+	/************************************************************/
+	_, err = ParseRules(`[{"key":"device","limit":10,"period":60}]`)
+	if err == nil || err.Error() != "invalid rate limit key: device" {
+		t.Error("expect:", "invalid rate limit key: device", "result:", err)
+	}
+	/************************************************************/
+	_, err = ParseRules(`[{"limit":0,"period":60}]`)
+	if err == nil {
+		t.Error("expect:", "error", "result:", err)
+	}
+}
+
+func Test_match(t *testing.T) {
+	var rule *Rule
+	/************************************************************/
+	rule = &Rule{Path: "/v1/classes/*", Method: "GET"}
+	if rule.match("GET", "/v1/classes/Post/abc") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if rule.match("POST", "/v1/classes/Post") {
+		t.Error("expect:", false, "result:", true)
+	}
+	if rule.match("GET", "/v1/users") {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	rule = &Rule{Class: "Post"}
+	if rule.match("PUT", "/v1/classes/Post/abc") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if rule.match("PUT", "/v1/classes/Comment/abc") {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	rule = &Rule{Function: "hello"}
+	if rule.match("POST", "/v1/functions/hello") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+	if rule.match("POST", "/v1/functions/hello2") {
+		t.Error("expect:", false, "result:", true)
+	}
+	/************************************************************/
+	rule = &Rule{Path: "/v1/login"}
+	if rule.match("GET", "/v1/login/") == false {
+		t.Error("expect:", true, "result:", false)
+	}
+}
+
+func Test_take(t *testing.T) {
+	cache.InitCache()
+	l := NewLimiter(nil)
+	rule := &Rule{Limit: 2, Period: 10}
+	now := time.Now()
+	var r *result
+	/************************************************************/
+	r = l.take("k", rule, now)
+	if r.allowed == false || r.remaining != 1 {
+		t.Error("expect:", 1, "result:", r.remaining, r.allowed)
+	}
+	r = l.take("k", rule, now)
+	if r.allowed == false || r.remaining != 0 {
+		t.Error("expect:", 0, "result:", r.remaining, r.allowed)
+	}
+	r = l.take("k", rule, now)
+	if r.allowed || r.retryAfter != 5 {
+		t.Error("expect:", 5, "result:", r.retryAfter, r.allowed)
+	}
+	// 5 秒后补充一个令牌
+	r = l.take("k", rule, now.Add(5*time.Second))
+	if r.allowed == false || r.remaining != 0 {
+		t.Error("expect:", 0, "result:", r.remaining, r.allowed)
+	}
+	// 其他维度的计数互不影响
+	r = l.take("k2", rule, now)
+	if r.allowed == false || r.remaining != 1 {
+		t.Error("expect:", 1, "result:", r.remaining, r.allowed)
+	}
+}
+
+func Test_Filter(t *testing.T) {
+	cache.InitCache()
+	rules, _ := ParseRules(`[{"path":"/v1/login","limit":1,"period":60}]`)
+	filter := NewLimiter(rules).Filter()
+	serve := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		ctx := context.NewContext()
+		ctx.Reset(w, req)
+		filter(ctx)
+		return w
+	}
+	/************************************************************/
+	w := serve("/v1/login")
+	if w.Code != 200 || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Error("expect:", 200, "result:", w.Code, w.Header())
+	}
+	w = serve("/v1/login")
+	if w.Code != 429 || w.Header().Get("Retry-After") != "60" {
+		t.Error("expect:", 429, "result:", w.Code, w.Header())
+	}
+	w = serve("/v1/users")
+	if w.Code != 200 || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Error("expect:", 200, "result:", w.Code, w.Header())
+	}
+}
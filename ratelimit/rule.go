@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+)
+
+// Rule 限流规则，采用令牌桶算法，桶容量为 Limit ，每 Period 秒补满
+// Path 为路由匹配规则，支持 * 通配，如 /v1/classes/* ，为空表示所有路由
+// Method 为 HTTP 方法，为空或 * 表示所有方法
+// Class 仅匹配 /classes/:className 下指定类的请求
+// Function 仅匹配 /functions/:functionName 下指定云函数的请求
+// Key 为限流维度，可选： ip、user、installation ，默认为 ip
+type Rule struct {
+	Path     string `json:"path"`
+	Method   string `json:"method"`
+	Class    string `json:"class"`
+	Function string `json:"function"`
+	Key      string `json:"key"`
+	Limit    int    `json:"limit"`
+	Period   int    `json:"period"`
+}
+
+// ParseRules 解析 JSON 数组格式的限流规则
+func ParseRules(s string) ([]*Rule, error) {
+	rules := []*Rule{}
+	if strings.TrimSpace(s) == "" {
+		return rules, nil
+	}
+	err := json.Unmarshal([]byte(s), &rules)
+	if err != nil {
+		return nil, errors.New("rate limit rules must be a JSON array: " + err.Error())
+	}
+	for _, r := range rules {
+		if r.Key == "" {
+			r.Key = "ip"
+		}
+		if r.Key != "ip" && r.Key != "user" && r.Key != "installation" {
+			return nil, errors.New("invalid rate limit key: " + r.Key)
+		}
+		if r.Limit <= 0 || r.Period <= 0 {
+			return nil, errors.New("rate limit and period must be greater than 0")
+		}
+		if r.Path != "" {
+			if _, err := path.Match(r.Path, "/"); err != nil {
+				return nil, errors.New("invalid rate limit path: " + r.Path)
+			}
+		}
+		r.Method = strings.ToUpper(r.Method)
+	}
+	return rules, nil
+}
+
+// match 判断请求是否匹配该规则
+func (r *Rule) match(method, urlPath string) bool {
+	if r.Method != "" && r.Method != "*" && r.Method != method {
+		return false
+	}
+	if r.Path != "" && matchPath(r.Path, urlPath) == false {
+		return false
+	}
+	if r.Class != "" && segmentAfter(urlPath, "classes") != r.Class {
+		return false
+	}
+	if r.Function != "" && segmentAfter(urlPath, "functions") != r.Function {
+		return false
+	}
+	return true
+}
+
+// matchPath 匹配路由，结尾的 /* 同时匹配其下的所有子路径
+func matchPath(pattern, urlPath string) bool {
+	urlPath = strings.TrimSuffix(urlPath, "/")
+	if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(urlPath+"/", strings.TrimSuffix(pattern, "*")) {
+		return true
+	}
+	ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), urlPath)
+	return ok
+}
+
+// segmentAfter 返回路由中紧跟在 name 之后的一段，如 /v1/classes/Post/1 中 classes 之后为 Post
+func segmentAfter(urlPath, name string) string {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	for i, s := range segments {
+		if s == name && i+1 < len(segments) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/config"
 	"github.com/JuShangEnergy/framework/errs"
 	"github.com/JuShangEnergy/framework/orm"
@@ -141,6 +142,15 @@ func (a *AccountLockout) setLockoutExpiration() error {
 		}
 		return err
 	}
+	audit.Log(&audit.Entry{
+		Action:    audit.ActionAccountLocked,
+		Actor:     "system",
+		ClassName: "_User",
+		Changes: audit.Diff(nil, types.M{
+			"username":                    a.username,
+			"_account_lockout_expires_at": utils.TimetoString(expiresAt),
+		}),
+	})
 
 	return nil
 }
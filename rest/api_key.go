@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// APIKeyOperations API Key 可授权的操作
+var APIKeyOperations = []string{"find", "get", "create", "update", "delete", "functions", "jobs"}
+
+// apiKeyTouchInterval 更新 lastUsedAt 的最小间隔，避免每次请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// APIKey 受限 API Key 的权限范围
+// Classes 中的 * 表示所有非系统类，系统类需要单独列出， _ApiKey 不允许通过 API Key 访问
+type APIKey struct {
+	ID         string
+	Name       string
+	Classes    []string
+	Operations []string
+}
+
+// Allow 判断是否允许对指定类执行指定操作， functions 与 jobs 操作不需要指定类
+func (k *APIKey) Allow(operation, className string) bool {
+	if utils.StringInSlice(operation, k.Operations) == false {
+		return false
+	}
+	if operation == "functions" || operation == "jobs" {
+		return true
+	}
+	return k.AllowClass(className)
+}
+
+// AllowClass 判断指定类是否在授权范围内
+func (k *APIKey) AllowClass(className string) bool {
+	if className == "_ApiKey" {
+		return false
+	}
+	if utils.StringInSlice(className, k.Classes) {
+		return true
+	}
+	return strings.HasPrefix(className, "_") == false && utils.StringInSlice("*", k.Classes)
+}
+
+// enforceAPIKey 使用 API Key 访问时，校验是否允许对指定类执行 operations 中的任一操作
+func enforceAPIKey(auth *Auth, className string, operations ...string) error {
+	if auth.APIKey == nil {
+		return nil
+	}
+	for _, operation := range operations {
+		if auth.APIKey.Allow(operation, className) {
+			return nil
+		}
+	}
+	msg := "api key " + auth.APIKey.Name + " isn't allowed to perform the " + operations[0] + " operation on the " + className + " collection."
+	return errs.E(errs.OperationForbidden, msg)
+}
+
+// GenerateAPIKey 生成新的 API Key ，返回明文与保存到 _ApiKey 中的哈希值
+func GenerateAPIKey() (string, string) {
+	key := "ak:" + utils.CreateToken()
+	return key, utils.Hash(key)
+}
+
+// ValidateAPIKey 校验 _ApiKey 对象中的权限字段
+func ValidateAPIKey(data types.M) error {
+	if v, ok := data["operations"]; ok {
+		operations := utils.A(v)
+		if operations == nil {
+			return errs.E(errs.InvalidJSON, "operations must be an array")
+		}
+		for _, op := range operations {
+			if utils.StringInSlice(utils.S(op), APIKeyOperations) == false {
+				return errs.E(errs.InvalidJSON, "invalid api key operation: "+utils.S(op))
+			}
+		}
+	}
+	if v, ok := data["classes"]; ok {
+		classes := utils.A(v)
+		if classes == nil {
+			return errs.E(errs.InvalidJSON, "classes must be an array")
+		}
+		for _, c := range classes {
+			if utils.S(c) == "" {
+				return errs.E(errs.InvalidJSON, "classes must be an array of string")
+			}
+		}
+	}
+	if v, ok := data["cidrs"]; ok {
+		cidrs := utils.A(v)
+		if cidrs == nil {
+			return errs.E(errs.InvalidJSON, "cidrs must be an array")
+		}
+		for _, c := range cidrs {
+			if parseCIDR(utils.S(c)) == nil {
+				return errs.E(errs.InvalidJSON, "invalid cidr: "+utils.S(c))
+			}
+		}
+	}
+	return nil
+}
+
+// GetAuthForAPIKey 返回 API Key 对应的权限信息
+// 校验过期时间与来源 IP ，并更新最后使用时间
+// API Key 不具有 Master 权限，仅跳过授权范围内的类的 ACL 校验
+func GetAuthForAPIKey(key, ip, installationID string, info *types.RequestInfo) (*Auth, error) {
+	keyErr := errs.E(errs.OperationForbidden, "invalid api key")
+	keyHash := utils.Hash(key)
+	// 从缓存获取 API Key ，修改或删除 _ApiKey 时清空缓存
+	object := utils.M(cache.APIKey.Get(keyHash))
+	if object == nil {
+		results, err := orm.TomatoDBController.Find("_ApiKey", types.M{"keyHash": keyHash}, types.M{"limit": 1})
+		if err != nil || len(results) != 1 {
+			return nil, keyErr
+		}
+		object = utils.M(results[0])
+		if object == nil {
+			return nil, keyErr
+		}
+		cache.APIKey.Put(keyHash, object, 0)
+	}
+
+	now := time.Now().UTC()
+	if expiresAt, ok := apiKeyDate(object["expiresAt"]); ok && expiresAt.Before(now) {
+		return nil, errs.E(errs.OperationForbidden, "api key is expired")
+	}
+	if cidrs := utils.A(object["cidrs"]); len(cidrs) > 0 {
+		allow := false
+		addr := net.ParseIP(ip)
+		for _, c := range cidrs {
+			if n := parseCIDR(utils.S(c)); n != nil && addr != nil && n.Contains(addr) {
+				allow = true
+				break
+			}
+		}
+		if allow == false {
+			return nil, errs.E(errs.OperationForbidden, "api key is not allowed from this ip")
+		}
+	}
+
+	apiKey := &APIKey{
+		ID:         utils.S(object["objectId"]),
+		Name:       utils.S(object["name"]),
+		Classes:    []string{},
+		Operations: []string{},
+	}
+	for _, c := range utils.A(object["classes"]) {
+		apiKey.Classes = append(apiKey.Classes, utils.S(c))
+	}
+	for _, op := range utils.A(object["operations"]) {
+		apiKey.Operations = append(apiKey.Operations, utils.S(op))
+	}
+
+	if lastUsedAt, ok := apiKeyDate(object["lastUsedAt"]); ok == false || now.Sub(lastUsedAt) > apiKeyTouchInterval {
+		lastUsed := types.M{
+			"__type": "Date",
+			"iso":    utils.TimetoString(now),
+		}
+		orm.TomatoDBController.Update("_ApiKey", types.M{"objectId": apiKey.ID}, types.M{"lastUsedAt": lastUsed}, types.M{}, false)
+		object["lastUsedAt"] = lastUsed
+		cache.APIKey.Put(keyHash, object, 0)
+	}
+
+	return &Auth{
+		IsMaster:       false,
+		IsReadOnly:     false,
+		InstallationID: installationID,
+		APIKey:         apiKey,
+		Info:           info,
+	}, nil
+}
+
+// parseCIDR 解析 CIDR ，单个 IP 视为只包含该地址的网段
+func parseCIDR(s string) *net.IPNet {
+	if strings.Contains(s, "/") == false {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil
+		}
+		if ip.To4() != nil {
+			s = s + "/32"
+		} else {
+			s = s + "/128"
+		}
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+func apiKeyDate(v interface{}) (time.Time, bool) {
+	var iso string
+	switch d := v.(type) {
+	case time.Time:
+		return d, true
+	case string:
+		iso = d
+	default:
+		iso = utils.S(utils.M(v)["iso"])
+	}
+	if iso == "" {
+		return time.Time{}, false
+	}
+	t, err := utils.StringtoTime(iso)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,263 @@
+package rest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+func Test_APIKeyAllow(t *testing.T) {
+	var key *APIKey
+	var result bool
+	/********************************************************/
+	key = &APIKey{Classes: []string{"Order"}, Operations: []string{"find", "get"}}
+	result = key.Allow("find", "Order")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+	result = key.Allow("create", "Order")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	result = key.Allow("find", "Invoice")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	result = key.Allow("functions", "")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	/********************************************************/
+	key = &APIKey{Classes: []string{"*", "_Installation", "_ApiKey"}, Operations: []string{"find", "jobs"}}
+	result = key.Allow("find", "Invoice")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+	result = key.Allow("find", "_User")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	result = key.Allow("find", "_Installation")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+	result = key.Allow("find", "_ApiKey")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	result = key.Allow("jobs", "")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+}
+
+func Test_ValidateAPIKey(t *testing.T) {
+	var data types.M
+	var err, expect error
+	/********************************************************/
+	data = types.M{
+		"classes":    types.S{"Order"},
+		"operations": types.S{"find", "functions"},
+		"cidrs":      types.S{"10.0.0.0/8", "192.168.1.1", "::1"},
+	}
+	err = ValidateAPIKey(data)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	/********************************************************/
+	data = types.M{"operations": types.S{"drop"}}
+	err = ValidateAPIKey(data)
+	expect = errs.E(errs.InvalidJSON, "invalid api key operation: drop")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	data = types.M{"cidrs": types.S{"10.0.0.0/33"}}
+	err = ValidateAPIKey(data)
+	expect = errs.E(errs.InvalidJSON, "invalid cidr: 10.0.0.0/33")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	data = types.M{"classes": "Order"}
+	err = ValidateAPIKey(data)
+	expect = errs.E(errs.InvalidJSON, "classes must be an array")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+}
+
+func Test_enforceRoleSecurityWithAPIKey(t *testing.T) {
+	var auth *Auth
+	var err, expect error
+	/********************************************************/
+	auth = &Auth{APIKey: &APIKey{Name: "billing", Classes: []string{"Order"}, Operations: []string{"find"}}}
+	err = enforceRoleSecurity("find", "Order", auth)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	err = enforceRoleSecurity("delete", "Order", auth)
+	expect = errs.E(errs.OperationForbidden, "api key billing isn't allowed to perform the delete operation on the Order collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+}
+
+func Test_AuthSkipACL(t *testing.T) {
+	var auth *Auth
+	var result bool
+	/********************************************************/
+	auth = Master()
+	result = auth.SkipACL("_User")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+	/********************************************************/
+	auth = &Auth{APIKey: &APIKey{Classes: []string{"Order"}, Operations: []string{"find"}}}
+	result = auth.SkipACL("Order")
+	if result != true {
+		t.Error("expect:", true, "result:", result)
+	}
+	result = auth.SkipACL("_User")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+	/********************************************************/
+	auth = Nobody()
+	result = auth.SkipACL("Order")
+	if result != false {
+		t.Error("expect:", false, "result:", result)
+	}
+}
+
+func Test_APIKeyScopeInSubQuery(t *testing.T) {
+	var auth *Auth
+	var q *Query
+	var where types.M
+	var response types.M
+	var err, expect error
+	auth = &Auth{APIKey: &APIKey{Name: "blog", Classes: []string{"Post"}, Operations: []string{"find", "get"}}}
+	/********************************************************/
+	_, err = NewQuery(auth, "_User", types.M{}, types.M{}, nil)
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the find operation on the _User collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	q, err = NewQuery(auth, "Post", types.M{}, types.M{}, nil)
+	if err != nil || q.findOptions["acl"] != nil {
+		t.Error("expect:", nil, "result:", err, q.findOptions)
+	}
+	/********************************************************/
+	where = types.M{
+		"author": types.M{
+			"$inQuery": types.M{
+				"className": "_User",
+				"where":     types.M{"username": "admin"},
+			},
+		},
+	}
+	q, _ = NewQuery(auth, "Post", where, types.M{}, nil)
+	err = q.replaceInQuery()
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	where = types.M{
+		"author": types.M{
+			"$select": types.M{
+				"query": types.M{"className": "_Session"},
+				"key":   "user",
+			},
+		},
+	}
+	q, _ = NewQuery(auth, "Post", where, types.M{}, nil)
+	err = q.replaceSelect()
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the find operation on the _Session collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	response = types.M{
+		"results": types.S{
+			types.M{
+				"objectId": "1001",
+				"author": types.M{
+					"__type":    "Pointer",
+					"className": "_User",
+					"objectId":  "2001",
+				},
+			},
+		},
+	}
+	err = includePath(auth, response, []string{"author"}, types.M{})
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the find operation on the _User collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+}
+
+func Test_APIKeyScopeInWrite(t *testing.T) {
+	var auth *Auth
+	var err, expect error
+	auth = &Auth{APIKey: &APIKey{Name: "blog", Classes: []string{"Post"}, Operations: []string{"create"}}}
+	/********************************************************/
+	_, err = NewWrite(auth, "Post", nil, types.M{"title": "hello"}, nil, nil)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	/********************************************************/
+	_, err = NewWrite(auth, "Post", types.M{"objectId": "1001"}, types.M{"title": "hello"}, nil, nil)
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the update operation on the Post collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	_, err = NewWrite(auth, "_Role", nil, types.M{"name": "admin"}, nil, nil)
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the create operation on the _Role collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+	/********************************************************/
+	err = NewDestroy(auth, "Post", types.M{"objectId": "1001"}, nil).Execute()
+	expect = errs.E(errs.OperationForbidden, "api key blog isn't allowed to perform the delete operation on the Post collection.")
+	if reflect.DeepEqual(expect, err) == false {
+		t.Error("expect:", expect, "result:", err)
+	}
+}
+
+func Test_GetAuthForAPIKeyFromCache(t *testing.T) {
+	var auth *Auth
+	var err error
+	var expect *Auth
+	cache.InitCache()
+	/********************************************************/
+	// 缓存中存在时不查询数据库
+	cache.APIKey.Put(utils.Hash("ak:1024"), types.M{
+		"objectId":   "k01",
+		"name":       "blog",
+		"classes":    types.S{"Post"},
+		"operations": types.S{"find"},
+		"lastUsedAt": types.M{"__type": "Date", "iso": utils.TimetoString(time.Now().UTC())},
+	}, 0)
+	auth, err = GetAuthForAPIKey("ak:1024", "127.0.0.1", "", nil)
+	expect = &Auth{APIKey: &APIKey{ID: "k01", Name: "blog", Classes: []string{"Post"}, Operations: []string{"find"}}}
+	if err != nil || reflect.DeepEqual(expect, auth) == false {
+		t.Error("expect:", expect, "result:", auth, err)
+	}
+	/********************************************************/
+	cache.APIKey.Put(utils.Hash("ak:2048"), types.M{
+		"objectId":  "k02",
+		"cidrs":     types.S{"10.0.0.0/8"},
+		"expiresAt": types.M{"__type": "Date", "iso": utils.TimetoString(time.Now().UTC().Add(time.Hour))},
+	}, 0)
+	_, err = GetAuthForAPIKey("ak:2048", "127.0.0.1", "", nil)
+	if reflect.DeepEqual(errs.E(errs.OperationForbidden, "api key is not allowed from this ip"), err) == false {
+		t.Error("expect:", "api key is not allowed from this ip", "result:", err)
+	}
+}
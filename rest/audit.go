@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"github.com/JuShangEnergy/framework/audit"
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// logRoleAudit 记录 _Role 的修改
+// 在 Write 与 Destroy 中调用，通过 /roles 、 /classes/_Role 、 /batch 以及云代码的修改都会被记录
+func logRoleAudit(auth *Auth, action, objectID string, before, after types.M) {
+	ip := ""
+	if auth.Info != nil {
+		ip = auth.Info.IP
+	}
+	audit.Log(&audit.Entry{
+		Action:    action,
+		Actor:     auth.AuditActor(),
+		IP:        ip,
+		ClassName: "_Role",
+		TargetID:  objectID,
+		Changes:   audit.Diff(before, after),
+	})
+}
+
+// originalRole 获取修改前的 role 数据， keys 不为空时仅返回其中的字段
+func originalRole(objectID string, keys types.M) types.M {
+	results, err := orm.TomatoDBController.Find("_Role", types.M{"objectId": objectID}, types.M{})
+	if err != nil || len(results) == 0 {
+		return types.M{}
+	}
+	role := utils.M(results[0])
+	if role == nil || keys == nil {
+		return role
+	}
+	before := types.M{}
+	for k := range keys {
+		if v, ok := role[k]; ok {
+			before[k] = v
+		}
+	}
+	return before
+}
+
+// roleChanges 返回写入 _Role 的字段，去掉自动生成的字段
+func roleChanges(data types.M) types.M {
+	changes := types.M{}
+	for k, v := range data {
+		if k == "objectId" || k == "createdAt" || k == "updatedAt" {
+			continue
+		}
+		changes[k] = v
+	}
+	return changes
+}
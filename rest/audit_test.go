@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/types"
+)
+
+func Test_roleChanges(t *testing.T) {
+	var data types.M
+	var result types.M
+	var expect types.M
+	/********************************************************/
+	data = types.M{
+		"objectId":  "r01",
+		"createdAt": "2026-01-01T00:00:00.000Z",
+		"updatedAt": "2026-01-01T00:00:00.000Z",
+		"name":      "admin",
+		"users":     types.M{"__op": "AddRelation"},
+	}
+	result = roleChanges(data)
+	expect = types.M{
+		"name":  "admin",
+		"users": types.M{"__op": "AddRelation"},
+	}
+	if reflect.DeepEqual(expect, result) == false {
+		t.Error("expect:", expect, "result:", result)
+	}
+}
+
+func Test_AuditActor(t *testing.T) {
+	var auth *Auth
+	var result string
+	/********************************************************/
+	auth = Master()
+	result = auth.AuditActor()
+	if result != "master" {
+		t.Error("expect:", "master", "result:", result)
+	}
+	/********************************************************/
+	auth = ReadOnly()
+	result = auth.AuditActor()
+	if result != "readOnlyMaster" {
+		t.Error("expect:", "readOnlyMaster", "result:", result)
+	}
+	/********************************************************/
+	auth = &Auth{APIKey: &APIKey{ID: "k01"}}
+	result = auth.AuditActor()
+	if result != "apiKey:k01" {
+		t.Error("expect:", "apiKey:k01", "result:", result)
+	}
+	/********************************************************/
+	auth = &Auth{User: types.M{"objectId": "u01"}}
+	result = auth.AuditActor()
+	if result != "user:u01" {
+		t.Error("expect:", "user:u01", "result:", result)
+	}
+	/********************************************************/
+	auth = Nobody()
+	result = auth.AuditActor()
+	if result != "anonymous" {
+		t.Error("expect:", "anonymous", "result:", result)
+	}
+}
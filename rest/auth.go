@@ -18,6 +18,7 @@ type Auth struct {
 	UserRoles      []string
 	FetchedRoles   bool
 	RolePromise    []string
+	APIKey         *APIKey
 	Info           *types.RequestInfo
 }
 
@@ -47,6 +48,10 @@ func Nobody(info ...*types.RequestInfo) *Auth {
 
 // GetAuthForSessionToken 返回 sessionToken 对应的用户权限信息
 func GetAuthForSessionToken(sessionToken string, installationID string, info *types.RequestInfo) (*Auth, error) {
+	// JWT 格式的 token 在本地校验
+	if JWTSessionEnabled() && utils.IsJWT(sessionToken) {
+		return getAuthForJWTSessionToken(sessionToken, installationID, info)
+	}
 	// 从缓存获取用户信息
 	cachedUser := cache.User.Get(sessionToken)
 	if u := utils.M(cachedUser); u != nil {
@@ -100,6 +105,7 @@ func GetAuthForSessionToken(sessionToken string, installationID string, info *ty
 		return nil, errs.E(errs.InvalidSessionToken, "Session token is expired.")
 	}
 	if expiresAt.UnixNano() < now.UnixNano() {
+		notifySessionRevoked(sessionToken)
 		return nil, errs.E(errs.InvalidSessionToken, "Session token is expired.")
 	}
 
@@ -153,6 +159,31 @@ func GetAuthForLegacySessionToken(sessionToken, installationID string, info *typ
 	}, nil
 }
 
+// SkipACL 是否跳过指定类的 ACL 校验， Master 跳过所有类， API Key 仅跳过授权范围内的类
+func (a *Auth) SkipACL(className string) bool {
+	if a.IsMaster {
+		return true
+	}
+	return a.APIKey != nil && a.APIKey.AllowClass(className)
+}
+
+// AuditActor 返回审计日志中的操作者
+func (a *Auth) AuditActor() string {
+	if a.APIKey != nil {
+		return "apiKey:" + a.APIKey.ID
+	}
+	if a.IsReadOnly {
+		return "readOnlyMaster"
+	}
+	if a.IsMaster {
+		return "master"
+	}
+	if a.User != nil {
+		return "user:" + utils.S(a.User["objectId"])
+	}
+	return "anonymous"
+}
+
 // CouldUpdateUserID Master 与当前用户可进行修改
 func (a *Auth) CouldUpdateUserID(objectID string) bool {
 	if a.IsMaster {
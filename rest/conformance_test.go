@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// Test_FindConformance 使用与 livequery/utils 相同的用例，校验 rest.Find 与 LiveQuery 的匹配结果一致
+// 包括需要 rest 层展开的子查询 $inQuery $notInQuery $select $dontSelect
+func Test_FindConformance(t *testing.T) {
+	data, err := ioutil.ReadFile("../livequery/utils/testdata/conformance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Classes   map[string]types.M   `json:"classes"`
+		Objects   map[string][]types.M `json:"objects"`
+		Relations []struct {
+			ClassName  string   `json:"className"`
+			ObjectID   string   `json:"objectId"`
+			Key        string   `json:"key"`
+			RelatedIDs []string `json:"relatedIds"`
+		} `json:"relations"`
+		Cases []struct {
+			Name      string   `json:"name"`
+			ClassName string   `json:"className"`
+			Where     types.M  `json:"where"`
+			Expect    []string `json:"expect"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	initEnv()
+	for className, schema := range suite.Classes {
+		orm.Adapter.CreateClass(className, schema)
+		for _, object := range suite.Objects[className] {
+			orm.Adapter.CreateObject(className, schema, object)
+		}
+	}
+	for _, r := range suite.Relations {
+		fields := utils.M(suite.Classes[r.ClassName]["fields"])
+		targetClass := utils.S(utils.M(fields[r.Key])["targetClass"])
+		objects := types.S{}
+		for _, id := range r.RelatedIDs {
+			objects = append(objects, types.M{"__type": "Pointer", "className": targetClass, "objectId": id})
+		}
+		update := types.M{r.Key: types.M{"__op": "AddRelation", "objects": objects}}
+		if _, err := Update(Master(), r.ClassName, r.ObjectID, update, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// $text 查询需要全文索引
+	orm.TomatoDBController.CreateIndex("Item", []string{"$text:description"})
+	defer orm.TomatoDBController.DeleteEverything()
+
+	for _, c := range suite.Cases {
+		response, err := Find(Master(), c.ClassName, c.Where, types.M{}, nil)
+		result := []string{}
+		for _, object := range utils.A(response["results"]) {
+			result = append(result, utils.S(utils.M(object)["objectId"]))
+		}
+		sort.Strings(result)
+		if err != nil || reflect.DeepEqual(c.Expect, result) == false {
+			t.Error(c.Name, "expect:", c.Expect, "result:", result, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"github.com/JuShangEnergy/framework/audit"
 	"github.com/JuShangEnergy/framework/cache"
 	"github.com/JuShangEnergy/framework/cloud"
 	"github.com/JuShangEnergy/framework/livequery"
@@ -35,7 +36,11 @@ func NewDestroy(
 
 // Execute 执行删除请求
 func (d *Destroy) Execute() error {
-	err := d.handleSession()
+	err := enforceAPIKey(d.auth, d.className, "delete")
+	if err != nil {
+		return err
+	}
+	err = d.handleSession()
 	if err != nil {
 		return err
 	}
@@ -64,7 +69,7 @@ func (d *Destroy) handleSession() error {
 		return nil
 	}
 	if sessionToken := utils.S(d.originalData["sessionToken"]); sessionToken != "" {
-		cache.User.Del(sessionToken)
+		RevokeSessionToken(sessionToken)
 	}
 
 	return nil
@@ -87,7 +92,7 @@ func (d *Destroy) runBeforeTrigger() error {
 
 // handleUserRoles 获取用户角色信息
 func (d *Destroy) handleUserRoles() error {
-	if d.auth.IsMaster == false {
+	if d.auth.SkipACL(d.className) == false {
 		d.auth.GetUserRoles()
 	}
 
@@ -96,8 +101,15 @@ func (d *Destroy) handleUserRoles() error {
 
 // runDestroy 添加 acl 字段，并执行删除对象操作
 func (d *Destroy) runDestroy() error {
+	if d.className == "_ApiKey" {
+		cache.APIKey.Clear()
+	}
+	var role types.M
+	if d.className == "_Role" {
+		role = originalRole(utils.S(d.query["objectId"]), nil)
+	}
 	options := types.M{}
-	if d.auth.IsMaster == false {
+	if d.auth.SkipACL(d.className) == false {
 		acl := []string{"*"}
 		if d.auth.User != nil {
 			acl = append(acl, utils.S(d.auth.User["objectId"]))
@@ -105,7 +117,14 @@ func (d *Destroy) runDestroy() error {
 		}
 		options["acl"] = acl
 	}
-	return orm.TomatoDBController.Destroy(d.className, d.query, options)
+	err := orm.TomatoDBController.Destroy(d.className, d.query, options)
+	if err != nil {
+		return err
+	}
+	if d.className == "_Role" {
+		logRoleAudit(d.auth, audit.ActionRoleDelete, utils.S(d.query["objectId"]), role, nil)
+	}
+	return nil
 }
 
 // runAfterTrigger 执行删后回调
@@ -63,7 +63,13 @@ func NewQuery(
 		clientSDK:         clientSDK,
 	}
 
-	if auth.IsMaster == false {
+	// 子查询与 include 同样使用当前权限创建，需要校验 API Key 的权限范围
+	err := enforceAPIKey(auth, className, "find", "get")
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.SkipACL(className) == false {
 		// 当前权限为 Master 时，findOptions 中不存在 acl 这个 key
 		if auth.User != nil {
 			query.findOptions["acl"] = []string{utils.S(auth.User["objectId"])}
@@ -282,6 +288,11 @@ func (q *Query) redirectClassNameForKey() error {
 	}
 
 	newClassName := orm.TomatoDBController.RedirectClassNameForKey(q.className, q.redirectKey)
+	// 使用 API Key 时，关联的类同样需要在授权范围内
+	err := enforceAPIKey(q.auth, newClassName, "find", "get")
+	if err != nil {
+		return err
+	}
 	q.className = newClassName
 	q.redirectClassName = newClassName
 
@@ -94,7 +94,7 @@ func Delete(auth *Auth, className, objectID string) error {
 			return errs.E(errs.ObjectNotFound, "Object not found for delete.")
 		}
 		inflatedObject["className"] = className
-		if className == "_Session" && !auth.IsMaster {
+		if className == "_Session" && !auth.SkipACL(className) {
 			objectId, ok := inflatedObject["user"].(types.M)["objectId"].(string)
 			if auth.User == nil || !ok || objectId != auth.User["objectId"].(string) {
 				return errs.E(errs.InvalidSessionToken, "invalid session token")
@@ -167,10 +167,22 @@ func Update(auth *Auth, className, objectID string, object types.M, clientSDK ma
 
 // enforceRoleSecurity 对指定的类与操作进行安全校验
 func enforceRoleSecurity(method string, className string, auth *Auth) error {
-	classesWithMasterOnlyAccess := []string{"_JobStatus", "_PushStatus", "_Hooks", "_GlobalConfig", "_JobSchedule"}
+	classesWithMasterOnlyAccess := []string{"_JobStatus", "_PushStatus", "_Hooks", "_GlobalConfig", "_JobSchedule", "_ApiKey", "_AuditLog", "_PushJob", "_PushDelivery", "_PushBatch"}
+
+	// 审计日志只允许追加，不允许通过接口修改
+	if className == "_AuditLog" && method != "find" && method != "get" {
+		msg := "Clients aren't allowed to perform the " + method + " operation on the " + className + " collection."
+		return errs.E(errs.OperationForbidden, msg)
+	}
+
+	// API Key 只能执行授权范围内的操作
+	err := enforceAPIKey(auth, className, method)
+	if err != nil {
+		return err
+	}
 
 	// 非 Master 不得对 _Installation 进行删除与查找操作操作
-	if className == "_Installation" && auth.IsMaster == false {
+	if className == "_Installation" && auth.SkipACL(className) == false {
 		if method == "delete" || method == "find" {
 			msg := "Clients aren't allowed to perform the " + method + " operation on the installation collection."
 			return errs.E(errs.OperationForbidden, msg)
@@ -178,7 +190,7 @@ func enforceRoleSecurity(method string, className string, auth *Auth) error {
 	}
 
 	//all volatileClasses are masterKey only
-	if utils.StringInSlice(className, classesWithMasterOnlyAccess) && !auth.IsMaster {
+	if utils.StringInSlice(className, classesWithMasterOnlyAccess) && !auth.SkipACL(className) {
 		msg := "Clients aren't allowed to perform the " + method + " operation on the " + className + " collection."
 		return errs.E(errs.OperationForbidden, msg)
 	}
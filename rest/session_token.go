@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/errs"
+	"github.com/JuShangEnergy/framework/livequery"
+	"github.com/JuShangEnergy/framework/types"
+	"github.com/JuShangEnergy/framework/utils"
+)
+
+// revokeAllKey 吊销列表中记录全部吊销时间的 key
+const revokeAllKey = "*"
+
+// JWTSessionEnabled 是否使用 JWT 作为 sessionToken
+func JWTSessionEnabled() bool {
+	return config.TConfig.SessionTokenType == "JWT"
+}
+
+// NewSessionToken 生成新的 sessionToken
+// SessionTokenType=JWT 时签发包含用户 ID 、角色列表与过期时间的 JWT ，否则生成 r: 开头的随机字符串
+// 两种方式生成的 token 都需要写入 _Session ，以便查询与吊销
+func NewSessionToken(userID string, expiresAt time.Time, info *types.RequestInfo) (string, error) {
+	if JWTSessionEnabled() == false {
+		return "r:" + utils.CreateToken(), nil
+	}
+
+	auth := &Auth{User: types.M{"objectId": userID}, Info: info}
+	if info == nil {
+		auth.Info = &types.RequestInfo{}
+	}
+	roles := types.S{}
+	for _, role := range auth.GetUserRoles() {
+		roles = append(roles, role)
+	}
+
+	claims := map[string]interface{}{
+		"sub":   userID,
+		"roles": roles,
+		"iat":   time.Now().Unix(),
+		"exp":   expiresAt.Unix(),
+		"jti":   utils.CreateToken(),
+	}
+	if config.TConfig.AppID != "" {
+		claims["aud"] = config.TConfig.AppID
+	}
+	kid, secret := config.JWTActiveKey()
+	token, err := utils.SignJWT(claims, kid, secret)
+	if err != nil {
+		return "", errs.E(errs.InternalServerError, "Failed to sign session token: "+err.Error())
+	}
+	return token, nil
+}
+
+// getAuthForJWTSessionToken 在本地校验 JWT 格式的 sessionToken ，无需查询 _Session
+// 用户角色取自 token 中签发时的角色列表
+func getAuthForJWTSessionToken(sessionToken, installationID string, info *types.RequestInfo) (*Auth, error) {
+	claims, err := utils.ParseJWT(sessionToken, config.JWTKeys())
+	if err != nil {
+		if err.Error() == "jwt is expired" {
+			notifySessionRevoked(sessionToken)
+			return nil, errs.E(errs.InvalidSessionToken, "Session token is expired.")
+		}
+		return nil, errs.E(errs.InvalidSessionToken, "invalid session token")
+	}
+	userID := utils.S(claims["sub"])
+	jti := utils.S(claims["jti"])
+	if userID == "" || jti == "" {
+		return nil, errs.E(errs.InvalidSessionToken, "invalid session token")
+	}
+	if aud := utils.S(claims["aud"]); aud != "" && aud != config.TConfig.AppID {
+		return nil, errs.E(errs.InvalidSessionToken, "invalid session token")
+	}
+	if cache.Revocation.Get(jti) != nil {
+		return nil, errs.E(errs.InvalidSessionToken, "invalid session token")
+	}
+	// 清空 _Session 之前签发的 token 全部失效
+	if revokedAt, ok := cache.Revocation.Get(revokeAllKey).(float64); ok {
+		if iat, ok := claims["iat"].(float64); ok == false || iat <= revokedAt {
+			return nil, errs.E(errs.InvalidSessionToken, "invalid session token")
+		}
+	}
+
+	// 优先使用缓存中完整的用户信息
+	user := utils.M(cache.User.Get(sessionToken))
+	if user == nil {
+		user = types.M{
+			"objectId":     userID,
+			"className":    "_User",
+			"sessionToken": sessionToken,
+		}
+	}
+	roles := []string{}
+	for _, role := range utils.A(claims["roles"]) {
+		if r := utils.S(role); r != "" {
+			roles = append(roles, r)
+		}
+	}
+
+	return &Auth{
+		IsMaster:       false,
+		IsReadOnly:     false,
+		InstallationID: installationID,
+		User:           user,
+		UserRoles:      roles,
+		FetchedRoles:   true,
+		Info:           info,
+	}, nil
+}
+
+// RevokeSessionToken 吊销 sessionToken ，清除用户缓存
+// JWT 格式的 token 同时写入吊销列表，有效期为 token 的剩余有效期，过期后 token 本身已失效
+func RevokeSessionToken(sessionToken string) {
+	if sessionToken == "" {
+		return
+	}
+	cache.User.Del(sessionToken)
+	notifySessionRevoked(sessionToken)
+	if utils.IsJWT(sessionToken) == false {
+		return
+	}
+	claims, err := utils.ParseJWT(sessionToken, config.JWTKeys())
+	if err != nil {
+		// 签名无效或已过期的 token 无需吊销
+		return
+	}
+	jti := utils.S(claims["jti"])
+	if jti == "" {
+		return
+	}
+	ttl := int64(1)
+	if exp, ok := claims["exp"].(float64); ok {
+		if remaining := int64(exp) - time.Now().Unix(); remaining > 0 {
+			ttl = remaining + 1
+		}
+	}
+	cache.Revocation.Put(jti, true, ttl)
+}
+
+// RevokeAllSessionTokens 吊销当前时间之前签发的全部 JWT ，用于清空 _Session 时
+func RevokeAllSessionTokens() {
+	cache.User.Clear()
+	notifySessionRevoked(revokeAllKey)
+	if JWTSessionEnabled() == false {
+		return
+	}
+	cache.Revocation.Put(revokeAllKey, float64(time.Now().Unix()), int64(config.TConfig.SessionLength))
+}
+
+// notifySessionRevoked 通知 LiveQuery sessionToken 已失效， * 表示全部失效
+func notifySessionRevoked(sessionToken string) {
+	if livequery.TLiveQuery != nil {
+		livequery.TLiveQuery.OnSessionRevoked(sessionToken)
+	}
+}
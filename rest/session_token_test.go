@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JuShangEnergy/framework/cache"
+	"github.com/JuShangEnergy/framework/config"
+	"github.com/JuShangEnergy/framework/errs"
+)
+
+func Test_JWTSessionToken(t *testing.T) {
+	var token string
+	var result *Auth
+	var err error
+	var expectErr error
+	config.TConfig.SessionTokenType = "JWT"
+	config.TConfig.JWTSigningKeys = []string{"k2:secret2", "k1:secret1"}
+	config.TConfig.JWTActiveKeyID = "k2"
+	defer func() {
+		config.TConfig.SessionTokenType = "Random"
+		config.TConfig.JWTSigningKeys = []string{}
+		config.TConfig.JWTActiveKeyID = ""
+	}()
+	/********************************************************/
+	cache.InitCache()
+	cache.Role.Put("1001", []string{"role:admin"}, 0)
+	token, err = NewSessionToken("1001", config.GenerateSessionExpiresAt(), nil)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	result, err = GetAuthForSessionToken(token, "111", nil)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	} else {
+		if reflect.DeepEqual(result.User["objectId"], "1001") == false {
+			t.Error("expect:", "1001", "result:", result.User["objectId"])
+		}
+		if reflect.DeepEqual(result.GetUserRoles(), []string{"role:admin"}) == false {
+			t.Error("expect:", []string{"role:admin"}, "result:", result.GetUserRoles())
+		}
+	}
+	/********************************************************/
+	// 轮换密钥后，旧密钥签发的 token 仍然有效
+	cache.InitCache()
+	cache.Role.Put("1001", []string{}, 0)
+	token, _ = NewSessionToken("1001", config.GenerateSessionExpiresAt(), nil)
+	config.TConfig.JWTActiveKeyID = "k1"
+	_, err = GetAuthForSessionToken(token, "111", nil)
+	if err != nil {
+		t.Error("expect:", nil, "result:", err)
+	}
+	// 移除旧密钥后 token 失效
+	config.TConfig.JWTSigningKeys = []string{"k1:secret1"}
+	_, err = GetAuthForSessionToken(token, "111", nil)
+	expectErr = errs.E(errs.InvalidSessionToken, "invalid session token")
+	if reflect.DeepEqual(expectErr, err) == false {
+		t.Error("expect:", expectErr, "result:", err)
+	}
+	config.TConfig.JWTSigningKeys = []string{"k2:secret2", "k1:secret1"}
+	/********************************************************/
+	cache.InitCache()
+	cache.Role.Put("1001", []string{}, 0)
+	token, _ = NewSessionToken("1001", time.Now().UTC().Add(-time.Minute), nil)
+	_, err = GetAuthForSessionToken(token, "111", nil)
+	expectErr = errs.E(errs.InvalidSessionToken, "Session token is expired.")
+	if reflect.DeepEqual(expectErr, err) == false {
+		t.Error("expect:", expectErr, "result:", err)
+	}
+	/********************************************************/
+	cache.InitCache()
+	cache.Role.Put("1001", []string{}, 0)
+	token, _ = NewSessionToken("1001", config.GenerateSessionExpiresAt(), nil)
+	RevokeSessionToken(token)
+	_, err = GetAuthForSessionToken(token, "111", nil)
+	expectErr = errs.E(errs.InvalidSessionToken, "invalid session token")
+	if reflect.DeepEqual(expectErr, err) == false {
+		t.Error("expect:", expectErr, "result:", err)
+	}
+	/********************************************************/
+	cache.InitCache()
+	cache.Role.Put("1001", []string{}, 0)
+	token, _ = NewSessionToken("1001", config.GenerateSessionExpiresAt(), nil)
+	RevokeAllSessionTokens()
+	_, err = GetAuthForSessionToken(token, "111", nil)
+	expectErr = errs.E(errs.InvalidSessionToken, "invalid session token")
+	if reflect.DeepEqual(expectErr, err) == false {
+		t.Error("expect:", expectErr, "result:", err)
+	}
+	/********************************************************/
+	config.TConfig.SessionTokenType = "Random"
+	token, _ = NewSessionToken("1001", config.GenerateSessionExpiresAt(), nil)
+	if len(token) != 34 || token[:2] != "r:" {
+		t.Error("expect:", "r:...", "result:", token)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"strconv"
 
+	"github.com/JuShangEnergy/framework/audit"
 	am "github.com/JuShangEnergy/framework/auth"
 	"github.com/JuShangEnergy/framework/cache"
 	"github.com/JuShangEnergy/framework/client"
@@ -54,11 +55,19 @@ func NewWrite(
 	if auth.IsReadOnly {
 		return nil, errs.E(errs.OperationForbidden, "Cannot perform a write operation when using readOnlyMasterKey.")
 	}
+	operation := "update"
+	if query == nil {
+		operation = "create"
+	}
+	err := enforceAPIKey(auth, className, operation)
+	if err != nil {
+		return nil, err
+	}
 	if data == nil {
 		data = types.M{}
 	}
 	// 当为 create 请求时，写入数据中不应该包含 objectId
-	if query == nil && data["objectId"] != nil && !auth.IsMaster {
+	if query == nil && data["objectId"] != nil && !auth.SkipACL(className) {
 		return nil, errs.E(errs.InvalidKeyName, "objectId is an invalid field name.")
 	}
 	var queryCopy types.M
@@ -131,6 +140,7 @@ func (w *Write) Execute() (types.M, error) {
 	if err != nil {
 		return nil, err
 	}
+	w.auditRole()
 	err = w.createSessionTokenIfNeeded()
 	if err != nil {
 		return nil, err
@@ -151,7 +161,7 @@ func (w *Write) Execute() (types.M, error) {
 
 // getUserAndRoleACL 获取用户角色信息，写入 acl 字段
 func (w *Write) getUserAndRoleACL() error {
-	if w.auth.IsMaster {
+	if w.auth.SkipACL(w.className) {
 		return nil
 	}
 	acl := []string{"*"}
@@ -424,7 +434,7 @@ func (w *Write) handleSession() error {
 		return nil
 	}
 
-	if w.auth.User == nil && w.auth.IsMaster == false {
+	if w.auth.User == nil && w.auth.SkipACL(w.className) == false {
 		return errs.E(errs.InvalidSessionToken, "Session token required.")
 	}
 
@@ -433,10 +443,13 @@ func (w *Write) handleSession() error {
 	}
 
 	// 当前为 create 请求，并且不是 Master 权限时
-	if w.query == nil && w.auth.IsMaster == false {
+	if w.query == nil && w.auth.SkipACL(w.className) == false {
 		// 生成 token ，过期时间为 1 年
-		token := "r:" + utils.CreateToken()
 		expiresAt := config.GenerateSessionExpiresAt()
+		token, err := NewSessionToken(utils.S(w.auth.User["objectId"]), expiresAt, w.auth.Info)
+		if err != nil {
+			return err
+		}
 		user := types.M{
 			"__type":    "Pointer",
 			"className": "_User",
@@ -790,7 +803,7 @@ func (w *Write) transformUser() error {
 		if w.query != nil {
 			// 如果是 update 请求时，标识出需要清理 Sessions ，并生成新的 Session
 			w.storage["clearSessions"] = true
-			if w.auth.IsMaster == false {
+			if w.auth.SkipACL(w.className) == false {
 				w.storage["generateNewSession"] = true
 			}
 		}
@@ -982,6 +995,22 @@ func (w *Write) expandFilesForExistingObjects() error {
 	return nil
 }
 
+// auditRole 记录 _Role 的创建与更新
+func (w *Write) auditRole() {
+	if w.className != "_Role" || w.storage["roleChanges"] == nil {
+		return
+	}
+	changes := utils.M(w.storage["roleChanges"])
+	delete(w.storage, "roleChanges")
+	if w.query == nil {
+		logRoleAudit(w.auth, audit.ActionRoleCreate, utils.S(w.objectID()), nil, changes)
+		return
+	}
+	before := utils.M(w.storage["originalRole"])
+	delete(w.storage, "originalRole")
+	logRoleAudit(w.auth, audit.ActionRoleUpdate, utils.S(w.objectID()), before, changes)
+}
+
 // runDatabaseOperation 执行数据库操作
 func (w *Write) runDatabaseOperation() error {
 	if w.response != nil {
@@ -990,6 +1019,14 @@ func (w *Write) runDatabaseOperation() error {
 
 	if w.className == "_Role" {
 		cache.Role.Clear()
+		// 记录修改前的数据，用于审计日志
+		w.storage["roleChanges"] = roleChanges(w.data)
+		if w.query != nil {
+			w.storage["originalRole"] = originalRole(utils.S(w.objectID()), roleChanges(w.data))
+		}
+	}
+	if w.className == "_ApiKey" {
+		cache.APIKey.Clear()
 	}
 
 	if w.className == "_User" && w.query != nil &&
@@ -1164,8 +1201,11 @@ func (w *Write) createSessionTokenIfNeeded() error {
 
 // createSessionToken 创建 Token
 func (w *Write) createSessionToken() error {
-	token := "r:" + utils.CreateToken()
 	expiresAt := config.GenerateSessionExpiresAt()
+	token, err := NewSessionToken(utils.S(w.objectID()), expiresAt, w.auth.Info)
+	if err != nil {
+		return err
+	}
 	user := types.M{
 		"__type":    "Pointer",
 		"className": "_User",
@@ -1220,7 +1260,17 @@ func (w *Write) handleFollowup() error {
 			"user": user,
 		}
 		delete(w.storage, "clearSessions")
-		err := orm.TomatoDBController.Destroy("_Session", sessionQuery, types.M{})
+		// JWT 在本地校验，需要将被清除的 token 加入吊销列表，同时通知 LiveQuery 这些 token 已失效
+		sessions, err := orm.TomatoDBController.Find("_Session", sessionQuery, types.M{})
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			if session := utils.M(s); session != nil {
+				RevokeSessionToken(utils.S(session["sessionToken"]))
+			}
+		}
+		err = orm.TomatoDBController.Destroy("_Session", sessionQuery, types.M{})
 		if err != nil {
 			return err
 		}
@@ -7,6 +7,51 @@ import (
 
 func init() {
 
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"],
+		beego.ControllerComments{
+			Method:           "HandleFind",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"],
+		beego.ControllerComments{
+			Method:           "HandleCreate",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"],
+		beego.ControllerComments{
+			Method:           "HandleGet",
+			Router:           `/:objectId`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"],
+		beego.ControllerComments{
+			Method:           "HandleUpdate",
+			Router:           `/:objectId`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:APIKeysController"],
+		beego.ControllerComments{
+			Method:           "HandleDelete",
+			Router:           `/:objectId`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AggregateController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AggregateController"],
 		beego.ControllerComments{
 			Method:           "HandleFind",
@@ -115,6 +160,24 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AudiencesController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AudiencesController"],
+		beego.ControllerComments{
+			Method:           "HandleCount",
+			Router:           `/:objectId/count`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AuditLogsController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:AuditLogsController"],
+		beego.ControllerComments{
+			Method:           "HandleFind",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:BatchController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:BatchController"],
 		beego.ControllerComments{
 			Method:           "HandleBatch",
@@ -979,6 +1042,33 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"],
+		beego.ControllerComments{
+			Method:           "HandleCancel",
+			Router:           `/:objectId`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"],
+		beego.ControllerComments{
+			Method:           "HandlePause",
+			Router:           `/:objectId/pause`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"],
+		beego.ControllerComments{
+			Method:           "HandleResume",
+			Router:           `/:objectId/resume`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"] = append(beego.GlobalControllerRouter["github.com/JuShangEnergy/framework/controllers:PushController"],
 		beego.ControllerComments{
 			Method:           "Get",
@@ -147,6 +147,16 @@ func init() {
 				&controllers.AudiencesController{},
 			),
 		),
+		beego.NSNamespace("/apiKeys",
+			beego.NSInclude(
+				&controllers.APIKeysController{},
+			),
+		),
+		beego.NSNamespace("/auditLogs",
+			beego.NSInclude(
+				&controllers.AuditLogsController{},
+			),
+		),
 	)
 	beego.AddNamespace(ns)
 }
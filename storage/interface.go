@@ -6,12 +6,14 @@ import "github.com/JuShangEnergy/framework/types"
 type Adapter interface {
 	ClassExists(name string) bool
 	SetClassLevelPermissions(className string, CLPs types.M) error
+	SetClassLiveQuery(className string, enabled bool) error
 	CreateClass(className string, schema types.M) (types.M, error)
 	AddFieldIfNotExists(className, fieldName string, fieldType types.M) error
 	DeleteClass(className string) (types.M, error)
 	DeleteAllClasses() error
 	DeleteFields(className string, schema types.M, fieldNames []string) error
 	CreateObject(className string, schema, object types.M) error
+	CreateObjects(className string, schema types.M, objects []types.M) error
 	GetAllClasses() ([]types.M, error)
 	GetClass(className string) (types.M, error)
 	DeleteObjectsByQuery(className string, schema, query types.M) error
@@ -216,6 +216,19 @@ func (m *MongoCollection) insertOne(docs interface{}) error {
 	return nil
 }
 
+// insertMany 插入多个对象
+func (m *MongoCollection) insertMany(docs []interface{}) error {
+	err := m.collection.Insert(docs...)
+	if err != nil {
+		// 键值重复错误单独处理
+		if strings.Index(err.Error(), "duplicate key error") > -1 {
+			return errs.E(errs.DuplicateValue, "A duplicate value for a field with unique values was provided")
+		}
+		return err
+	}
+	return nil
+}
+
 // upsertOne 更新一个对象，如果要更新的对象不存在，则插入该对象
 func (m *MongoCollection) upsertOne(selector interface{}, update interface{}) error {
 	_, err := m.collection.Upsert(selector, update)
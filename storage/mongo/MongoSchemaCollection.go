@@ -285,6 +285,7 @@ func mongoSchemaToParseSchema(schema types.M) types.M {
 	// 复制 schema["_metadata"]["class_permissions"] 到 classLevelPermissions 中
 	var clps types.M
 	clps = utils.CopyMap(defaultCLPS)
+	liveQuery := false
 	if metadata := utils.M(schema["_metadata"]); metadata != nil {
 		if classPermissions := utils.M(metadata["class_permissions"]); classPermissions != nil {
 			// clps = utils.CopyMap(emptyCLPS)
@@ -293,13 +294,20 @@ func mongoSchemaToParseSchema(schema types.M) types.M {
 				clps[k] = v
 			}
 		}
+		if v, ok := metadata["live_query"].(bool); ok {
+			liveQuery = v
+		}
 	}
 
-	return types.M{
+	parseSchema := types.M{
 		"className":             schema["_id"],
 		"fields":                mongoSchemaFieldsToParseSchemaFields(schema),
 		"classLevelPermissions": clps,
 	}
+	if liveQuery {
+		parseSchema["liveQuery"] = true
+	}
+	return parseSchema
 }
 
 // parseFieldTypeToMongoFieldType 返回数据库中存储的字段类型
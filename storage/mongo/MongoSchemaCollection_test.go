@@ -1013,6 +1013,17 @@ func Test_mongoSchemaToParseSchema(t *testing.T) {
 	var result types.M
 	var expect types.M
 	/*****************************************************/
+	schema = types.M{
+		"_id": "post",
+		"_metadata": types.M{
+			"live_query": true,
+		},
+	}
+	result = mongoSchemaToParseSchema(schema)
+	if result["liveQuery"] != true {
+		t.Error("expect:", true, "result:", result["liveQuery"])
+	}
+	/*****************************************************/
 	schema = nil
 	result = mongoSchemaToParseSchema(schema)
 	expect = types.M{}
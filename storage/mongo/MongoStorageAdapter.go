@@ -77,9 +77,18 @@ func (m *MongoAdapter) SetClassLevelPermissions(className string, CLPs types.M)
 	schemaCollection := m.schemaCollection()
 	update := types.M{
 		"$set": types.M{
-			"_metadata": types.M{
-				"class_permissions": CLPs,
-			},
+			"_metadata.class_permissions": CLPs,
+		},
+	}
+	return schemaCollection.updateSchema(className, update)
+}
+
+// SetClassLiveQuery 设置类是否开启 LiveQuery
+func (m *MongoAdapter) SetClassLiveQuery(className string, enabled bool) error {
+	schemaCollection := m.schemaCollection()
+	update := types.M{
+		"$set": types.M{
+			"_metadata.live_query": enabled,
 		},
 	}
 	return schemaCollection.updateSchema(className, update)
@@ -202,6 +211,24 @@ func (m *MongoAdapter) CreateObject(className string, schema, object types.M) er
 	return coll.insertOne(mongoObject)
 }
 
+// CreateObjects 批量创建对象，一次请求写入所有对象
+func (m *MongoAdapter) CreateObjects(className string, schema types.M, objects []types.M) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	schema = convertParseSchemaToMongoSchema(schema)
+	docs := []interface{}{}
+	for _, object := range objects {
+		mongoObject, err := m.transform.parseObjectToMongoObjectForCreate(className, object, schema)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, mongoObject)
+	}
+	coll := m.adaptiveCollection(className)
+	return coll.insertMany(docs)
+}
+
 // GetClass ...
 func (m *MongoAdapter) GetClass(className string) (types.M, error) {
 	return m.schemaCollection().findSchema(className)
@@ -480,9 +480,9 @@ func (t *Transform) transformConstraint(constraint interface{}, inArray bool) (i
 
 		// full text search
 		case "$text":
-			search, ok := utils.M(object[key])["$search"].(types.M)
+			search := utils.M(utils.M(object[key])["$search"])
 			textAnswer := types.M{}
-			if !ok {
+			if search == nil {
 				return nil, errs.E(errs.InvalidJSON, "bad $text: $search, should be object")
 			}
 			term, ok := search["$term"].(string)
@@ -608,6 +608,17 @@ func (t *Transform) transformConstraint(constraint interface{}, inArray bool) (i
 			if geoWithin == nil {
 				return nil, errs.E(errs.InvalidJSON, "bad $geoWithin value")
 			}
+			// $centerSphere: [GeoPoint 或者 [longitude, latitude], 弧度]
+			if centerSphere := utils.A(geoWithin["$centerSphere"]); centerSphere != nil {
+				center, err := transformCenterSphere(centerSphere)
+				if err != nil {
+					return nil, err
+				}
+				answer[key] = types.M{
+					"$centerSphere": center,
+				}
+				break
+			}
 			polygon := utils.A(geoWithin["$polygon"])
 			if polygon == nil || len(polygon) < 3 {
 				return nil, errs.E(errs.InvalidJSON, "bad $geoWithin value; $polygon should contain at least 3 GeoPoints")
@@ -664,6 +675,15 @@ func (t *Transform) transformConstraint(constraint interface{}, inArray bool) (i
 	return answer, nil
 }
 
+// transformCenterSphere 转换 $centerSphere 参数为 [[longitude, latitude], 弧度]
+func transformCenterSphere(centerSphere types.S) (types.S, error) {
+	longitude, latitude, distance, err := utils.ParseCenterSphere(centerSphere)
+	if err != nil {
+		return nil, err
+	}
+	return types.S{types.S{longitude, latitude}, distance}, nil
+}
+
 // transformTopLevelAtom 转换顶层的原子数据
 func (t *Transform) transformTopLevelAtom(atom interface{}) (interface{}, error) {
 	if atom == nil {
@@ -1134,6 +1134,47 @@ func Test_transformConstraint(t *testing.T) {
 		t.Error("expect:", expect, "get result:", result, err)
 	}
 	/*************************************************/
+	constraint = types.M{
+		"$geoWithin": types.M{
+			"$centerSphere": types.S{
+				types.M{
+					"__type":    "GeoPoint",
+					"longitude": 20,
+					"latitude":  30,
+				},
+				0.5,
+			},
+		},
+	}
+	inArray = true
+	result, err = tf.transformConstraint(constraint, inArray)
+	expect = types.M{
+		"$geoWithin": types.M{
+			"$centerSphere": types.S{
+				types.S{20, 30},
+				0.5,
+			},
+		},
+	}
+	if err != nil || reflect.DeepEqual(result, expect) == false {
+		t.Error("expect:", expect, "get result:", result, err)
+	}
+	/*************************************************/
+	constraint = types.M{
+		"$geoWithin": types.M{
+			"$centerSphere": types.S{
+				types.S{20, 30},
+				-1,
+			},
+		},
+	}
+	inArray = true
+	result, err = tf.transformConstraint(constraint, inArray)
+	expect = errs.E(errs.InvalidJSON, "bad $geoWithin value; $centerSphere distance invalid")
+	if reflect.DeepEqual(err, expect) == false || result != nil {
+		t.Error("expect:", expect, "get result:", err)
+	}
+	/*************************************************/
 	constraint = types.M{"$other": "hello"}
 	inArray = true
 	result, err = tf.transformConstraint(constraint, inArray)
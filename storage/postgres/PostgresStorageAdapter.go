@@ -60,6 +60,26 @@ func (p *PostgresAdapter) ensureSchemaCollectionExists() error {
 	return nil
 }
 
+// SetClassLiveQuery 设置类是否开启 LiveQuery
+func (p *PostgresAdapter) SetClassLiveQuery(className string, enabled bool) error {
+	err := p.ensureSchemaCollectionExists()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(enabled)
+	if err != nil {
+		return err
+	}
+
+	qs := `UPDATE "_SCHEMA" SET "schema" = json_object_set_key("schema", $1::text, $2::jsonb) WHERE "className"=$3 `
+	_, err = p.db.Exec(qs, "liveQuery", string(b), className)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ClassExists 检测数据库中是否存在指定类
 func (p *PostgresAdapter) ClassExists(name string) bool {
 	var result bool
@@ -400,7 +420,7 @@ func (p *PostgresAdapter) DeleteAllClasses() error {
 		joins = append(joins, joinTablesForSchema(sch)...)
 	}
 
-	classes := []string{"_SCHEMA", "_PushStatus", "_JobStatus", "_JobSchedule", "_Hooks", "_GlobalConfig", "_Audience"}
+	classes := []string{"_SCHEMA", "_PushStatus", "_JobStatus", "_JobSchedule", "_Hooks", "_GlobalConfig", "_Audience", "_ApiKey", "_AuditLog", "_PushJob", "_PushDelivery", "_PushBatch"}
 	classes = append(classes, classNames...)
 	classes = append(classes, joins...)
 
@@ -479,6 +499,53 @@ func (p *PostgresAdapter) DeleteFields(className string, schema types.M, fieldNa
 
 // CreateObject 创建对象
 func (p *PostgresAdapter) CreateObject(className string, schema, object types.M) error {
+	qs, valuesArray, err := createObjectQuery(className, schema, object)
+	if err != nil || qs == "" {
+		return err
+	}
+	_, err = p.db.Exec(qs, valuesArray...)
+	return createObjectError(err)
+}
+
+// CreateObjects 批量创建对象，在同一个事务中写入所有对象
+func (p *PostgresAdapter) CreateObjects(className string, schema types.M, objects []types.M) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		qs, valuesArray, err := createObjectQuery(className, schema, object)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if qs == "" {
+			continue
+		}
+		_, err = tx.Exec(qs, valuesArray...)
+		if err != nil {
+			tx.Rollback()
+			return createObjectError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// createObjectError 转换唯一索引冲突错误
+func createObjectError(err error) error {
+	if e, ok := err.(*pq.Error); ok {
+		if e.Code == postgresUniqueIndexViolationError {
+			return errs.E(errs.DuplicateValue, "A duplicate value for a field with unique values was provided")
+		}
+	}
+	return err
+}
+
+// createObjectQuery 生成创建对象的 SQL 语句与参数，对象为空时返回空语句
+func createObjectQuery(className string, schema, object types.M) (string, types.S, error) {
 	columnsArray := []string{}
 	valuesArray := types.S{}
 	geoPoints := types.M{}
@@ -486,13 +553,13 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 		schema = types.M{}
 	}
 	if len(object) == 0 {
-		return nil
+		return "", nil, nil
 	}
 	schema = toPostgresSchema(schema)
 	object = handleDotFields(object)
 	err := validateKeys(object)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	// 预处理 authData 字段，避免在遍历 map 并向其添加元素时造成的不稳定性
@@ -528,7 +595,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 			if fieldName == "_password_history" {
 				b, err := json.Marshal(object[fieldName])
 				if err != nil {
-					return err
+					return "", nil, err
 				}
 				valuesArray = append(valuesArray, b)
 			}
@@ -567,7 +634,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 		case "Array":
 			b, err := json.Marshal(object[fieldName])
 			if err != nil {
-				return err
+				return "", nil, err
 			}
 			if fieldName == "_rperm" || fieldName == "_wperm" {
 				// '[' => '{'
@@ -583,7 +650,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 		case "Object":
 			b, err := json.Marshal(object[fieldName])
 			if err != nil {
-				return err
+				return "", nil, err
 			}
 			valuesArray = append(valuesArray, b)
 		case "String", "Number", "Boolean", "Bytes":
@@ -591,7 +658,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 		case "Polygon":
 			value, err := convertPolygonToSQL(utils.A(utils.M(object[fieldName])["coordinates"]))
 			if err != nil {
-				return err
+				return "", nil, err
 			}
 			valuesArray = append(valuesArray, value)
 		case "File":
@@ -604,7 +671,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 			geoPoints[fieldName] = object[fieldName]
 			columnsArray = columnsArray[:len(columnsArray)-1]
 		default:
-			return errs.E(errs.OtherCause, "Type "+utils.S(tp["type"])+" not supported yet")
+			return "", nil, errs.E(errs.OtherCause, "Type "+utils.S(tp["type"])+" not supported yet")
 		}
 
 	}
@@ -656,17 +723,7 @@ func (p *PostgresAdapter) CreateObject(className string, schema, object types.M)
 	valuesPattern := strings.Join(initialValues, ",")
 
 	qs := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, className, columnsPattern, valuesPattern)
-	_, err = p.db.Exec(qs, valuesArray...)
-	if err != nil {
-		if e, ok := err.(*pq.Error); ok {
-			if e.Code == postgresUniqueIndexViolationError {
-				return errs.E(errs.DuplicateValue, "A duplicate value for a field with unique values was provided")
-			}
-		}
-		return err
-	}
-
-	return nil
+	return qs, valuesArray, nil
 }
 
 // GetAllClasses ...
@@ -2019,9 +2076,22 @@ func (p *PostgresAdapter) HandleShutdown() {
 	p.db.Close()
 }
 
-// CreateIndex 创建索引
+// CreateIndex 创建索引，暂不支持 $text 等特殊类型的索引
 func (m *PostgresAdapter) CreateIndex(className string, indexRequest []string) error {
-	return nil
+	if len(indexRequest) == 0 {
+		return nil
+	}
+	columns := []string{}
+	for _, fieldName := range indexRequest {
+		if strings.HasPrefix(fieldName, "$") {
+			return nil
+		}
+		columns = append(columns, `"`+fieldName+`"`)
+	}
+	indexName := className + `_` + strings.Join(indexRequest, "_") + `_index`
+	qs := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON "%s" (%s)`, indexName, className, strings.Join(columns, ","))
+	_, err := m.db.Exec(qs)
+	return err
 }
 
 func postgresObjectToParseObject(object, fields types.M) (types.M, error) {
@@ -2393,11 +2463,15 @@ func toParseSchema(schema types.M) types.M {
 		}
 	}
 
-	return types.M{
+	parseSchema := types.M{
 		"className":             schema["className"],
 		"fields":                fields,
 		"classLevelPermissions": clps,
 	}
+	if liveQuery, ok := schema["liveQuery"].(bool); ok && liveQuery {
+		parseSchema["liveQuery"] = true
+	}
+	return parseSchema
 }
 
 func toPostgresSchema(schema types.M) types.M {
@@ -2844,6 +2918,16 @@ func buildWhereClause(schema, query types.M, index int) (*whereClause, error) {
 						values = append(values, fmt.Sprintf("(%s)", strings.Join(points, ", ")))
 						index = index + 1
 					}
+				} else if centerSphere := utils.A(geoWithin["$centerSphere"]); centerSphere != nil {
+					// $centerSphere: [GeoPoint 或者 [longitude, latitude], 弧度]
+					longitude, latitude, distance, err := utils.ParseCenterSphere(centerSphere)
+					if err != nil {
+						return nil, err
+					}
+					distanceInKM := distance * 6371 * 1000
+					patterns = append(patterns, fmt.Sprintf(`ST_distance_sphere("%s"::geometry, POINT($%d, $%d)::geometry) <= $%d`, fieldName, index, index+1, index+2))
+					values = append(values, longitude, latitude, distanceInKM)
+					index = index + 3
 				} else {
 					return nil, errs.E(errs.InvalidJSON, "bad $geoWithin value")
 				}
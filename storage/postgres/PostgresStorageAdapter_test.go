@@ -6432,3 +6432,71 @@ func TestPostgresAdapter_ContainedAllInNull(t *testing.T) {
 		}
 	}
 }
+
+func Test_createObjectQuery(t *testing.T) {
+	type args struct {
+		className string
+		schema    types.M
+		object    types.M
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantQs     string
+		wantValues types.S
+		wantErr    bool
+	}{
+		{
+			name: "1",
+			args: args{
+				className: "Test",
+				schema:    nil,
+				object:    types.M{},
+			},
+			wantQs:     "",
+			wantValues: nil,
+		},
+		{
+			name: "2",
+			args: args{
+				className: "Test",
+				schema: types.M{
+					"fields": types.M{
+						"key": types.M{"type": "String"},
+					},
+				},
+				object: types.M{"key": "hello"},
+			},
+			wantQs:     `INSERT INTO "Test" ("key") VALUES ($1)`,
+			wantValues: types.S{"hello"},
+		},
+		{
+			name: "3",
+			args: args{
+				className: "Test",
+				schema: types.M{
+					"fields": types.M{
+						"key": types.M{"type": "Unknown"},
+					},
+				},
+				object: types.M{"key": "hello"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, values, err := createObjectQuery(tt.args.className, tt.args.schema, tt.args.object)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createObjectQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if qs != tt.wantQs || reflect.DeepEqual(values, tt.wantValues) == false {
+				t.Errorf("createObjectQuery() = %v %v, want %v %v", qs, values, tt.wantQs, tt.wantValues)
+			}
+		})
+	}
+}
@@ -1,17 +1,26 @@
 package tomato
 
 import (
+	"encoding/json"
+	"log"
+	"strconv"
 	"strings"
 
 	"github.com/JuShangEnergy/framework/config"
 	_ "github.com/JuShangEnergy/framework/routers"
 
 	"github.com/JuShangEnergy/framework/controllers"
+	"github.com/JuShangEnergy/framework/headers"
 	"github.com/JuShangEnergy/framework/livequery"
+	"github.com/JuShangEnergy/framework/livequery/server"
+	"github.com/JuShangEnergy/framework/livequery/t"
 	"github.com/JuShangEnergy/framework/orm"
+	"github.com/JuShangEnergy/framework/push"
+	"github.com/JuShangEnergy/framework/ratelimit"
+	"github.com/JuShangEnergy/framework/rest"
+	"github.com/JuShangEnergy/framework/types"
 	"github.com/beego/beego"
 	"github.com/beego/beego/context"
-	"github.com/beego/beego/plugins/cors"
 )
 
 // Run ...
@@ -22,6 +31,11 @@ func Run() {
 	// 创建必要的索引
 	orm.TomatoDBController.PerformInitialization()
 
+	// 配置了 LiveQuery 或发布者时，类定义中开启了 liveQuery 的类同样发布消息
+	if config.TConfig.LiveQueryClasses != "" || config.TConfig.PublisherType != "" {
+		enableSchemaLiveQuery()
+	}
+
 	if beego.BConfig.RunMode == "dev" {
 		beego.BConfig.WebConfig.DirectoryIndex = true
 		beego.BConfig.WebConfig.StaticDir["/swagger"] = "swagger"
@@ -31,10 +45,24 @@ func Run() {
 
 	allowMethodOverride()
 	allowCrossDomain()
+	allowRateLimit()
 
 	beego.Run()
 }
 
+// RunPushWorker 在单独的进程中处理推送任务，不启动 API 服务
+// PushQueueType 需要设置为多个进程共享的队列，如 RedisStreams 或 Database ， API 进程可设置 PushWorker=false
+func RunPushWorker() {
+	config.Validate()
+
+	orm.TomatoDBController.PerformInitialization()
+
+	if err := push.StartWorker(); err != nil {
+		log.Fatalln(err)
+	}
+	select {}
+}
+
 // RunLiveQueryServer 运行 LiveQuery 服务
 func RunLiveQueryServer(args map[string]string) {
 	// 未设置启动参数时，使用默认参数填充
@@ -48,10 +76,78 @@ func RunLiveQueryServer(args map[string]string) {
 		args["subType"] = config.TConfig.PublisherType
 		args["subURL"] = config.TConfig.PublisherURL
 		args["subConfig"] = config.TConfig.PublisherConfig
+		args["sendQueueSize"] = strconv.Itoa(config.TConfig.LiveQuerySendQueueSize)
+		args["writeTimeout"] = strconv.Itoa(config.TConfig.LiveQueryWriteTimeout)
+		args["overflowPolicy"] = config.TConfig.LiveQueryOverflowPolicy
+		args["pingInterval"] = strconv.Itoa(config.TConfig.LiveQueryPingInterval)
+		args["idleTimeout"] = strconv.Itoa(config.TConfig.LiveQueryIdleTimeout)
+		args["userSensitiveFields"] = strings.Join(config.TConfig.UserSensitiveFields, "|")
+		args["sessionRevokedPolicy"] = config.TConfig.LiveQuerySessionRevokedPolicy
+		args["classNames"] = config.TConfig.LiveQueryClasses
+		args["ssePattern"] = config.TConfig.LiveQuerySSEPattern
+		args["sseReplaySize"] = strconv.Itoa(config.TConfig.LiveQuerySSEReplaySize)
 	}
+	if args["subType"] == "Embedded" {
+		embedLiveQuery()
+	}
+	// 与 API 运行在同一进程时，默认的 EventEmitter 也能接收消息
+	enableSchemaLiveQuery()
 	livequery.Run(args)
 }
 
+// enableSchemaLiveQuery 在 LiveQueryClasses 之外，类定义中开启了 liveQuery 的类同样发布消息
+// 未使用 LiveQuery 时不设置，避免保存对象与注销时的额外开销
+func enableSchemaLiveQuery() {
+	livequery.TLiveQuery.SetSchemaLiveQuery(func(className string) bool {
+		return orm.TomatoDBController.LoadSchema(nil).HasLiveQuery(className)
+	})
+}
+
+// embedLiveQuery 嵌入模式下 LiveQuery 直接通过 rest 查询用户、角色与订阅条件中的子查询，与 API 共用 cache.User 与 cache.Role
+func embedLiveQuery() {
+	server.Embedded = &server.EmbeddedResolver{
+		UserForSessionToken: func(sessionToken string) (t.M, error) {
+			auth, err := rest.GetAuthForSessionToken(sessionToken, "", &types.RequestInfo{})
+			if err != nil {
+				return nil, err
+			}
+			return t.M(auth.User), nil
+		},
+		UserRoles: func(userID string) []string {
+			auth := &rest.Auth{
+				User: types.M{"objectId": userID},
+				Info: &types.RequestInfo{},
+			}
+			return auth.GetUserRoles()
+		},
+		ClassSchema: func(className string) (t.M, error) {
+			schema, err := orm.TomatoDBController.LoadSchema(nil).GetOneSchema(className, false, nil)
+			if err != nil {
+				return nil, err
+			}
+			// 类不存在时返回 nil
+			if len(schema) == 0 {
+				return nil, nil
+			}
+			return t.M(schema), nil
+		},
+		Find: func(className string, where, options t.M) ([]t.M, error) {
+			response, err := rest.Find(rest.Master(), className, types.M(where), types.M(options), nil)
+			if err != nil {
+				return nil, err
+			}
+			// 转换为 JSON 格式，与 HTTP 模式下的查询结果一致
+			data, err := json.Marshal(response["results"])
+			if err != nil {
+				return nil, err
+			}
+			results := []t.M{}
+			err = json.Unmarshal(data, &results)
+			return results, err
+		},
+	}
+}
+
 // HandleShutdown 处理退出
 func HandleShutdown() {
 	if orm.Adapter != nil {
@@ -59,22 +155,22 @@ func HandleShutdown() {
 	}
 }
 
+// allowRateLimit 按 RateLimitRules 配置对请求限流，规则无效时退出
+func allowRateLimit() {
+	rules, err := ratelimit.ParseRules(config.TConfig.RateLimitRules)
+	if err != nil {
+		log.Fatalln("RateLimitRules:", err)
+	}
+	if len(rules) == 0 {
+		return
+	}
+	beego.InsertFilter("*", beego.BeforeRouter, ratelimit.NewLimiter(rules).Filter())
+}
+
+// allowCrossDomain 按配置处理跨域请求，并添加安全相关的响应头
 func allowCrossDomain() {
-	beego.InsertFilter("*", beego.BeforeRouter, cors.Allow(&cors.Options{
-		AllowAllOrigins: true,
-		AllowMethods:    []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Authorization", "Access-Control-Allow-Origin",
-			"Access-Control-Allow-Headers", "X-Parse-Master-Key", "X-Parse-REST-API-Key",
-			"X-Parse-Javascript-Key", "X-Parse-Application-Id", "X-Parse-Client-Version", "X-Parse-Session-Token",
-			"X-Requested-With", "X-Parse-Revocable-Session", "Content-Type"},
-		AllowCredentials: true,
-	}))
-	beego.InsertFilter("*", beego.BeforeRouter, func(ctx *context.Context) {
-		if ctx.Input.Method() == "OPTIONS" {
-			ctx.Output.SetStatus(200)
-			ctx.ResponseWriter.Started = true
-		}
-	})
+	beego.InsertFilter("*", beego.BeforeRouter, headers.SecurityFilter())
+	beego.InsertFilter("*", beego.BeforeRouter, headers.CORSFilter())
 }
 
 func allowMethodOverride() {
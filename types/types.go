@@ -14,9 +14,11 @@ type RequestInfo struct {
 	JavaScriptKey  string
 	DotNetKey      string
 	RestAPIKey     string
+	APIKey         string
 	SessionToken   string
 	InstallationID string
 	ClientVersion  string
+	IP             string
 	ClientSDK      map[string]string
 	Headers        map[string]string
 }
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// jwtHeader JWT 头部
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// IsJWT 判断 token 是否为 JWT 格式，即由 . 分隔的三段
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && strings.HasPrefix(token, "eyJ")
+}
+
+// SignJWT 使用 HS256 对 claims 签名，kid 写入头部用于在多个密钥中选择验签密钥
+func SignJWT(claims map[string]interface{}, kid string, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("jwt secret is required")
+	}
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + hs256(unsigned, secret), nil
+}
+
+// ParseJWT 校验 JWT 签名与过期时间，返回 claims
+// keys 为 kid 与密钥的对应关系，支持同时存在多个有效密钥
+func ParseJWT(token string, keys map[string]string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jwt")
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed jwt header")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.New("malformed jwt header")
+	}
+	if header.Alg != "HS256" {
+		return nil, errors.New("unsupported jwt alg: " + header.Alg)
+	}
+	secret, ok := keys[header.Kid]
+	if ok == false || secret == "" {
+		return nil, errors.New("unknown jwt kid: " + header.Kid)
+	}
+	signature := hs256(parts[0]+"."+parts[1], secret)
+	if hmac.Equal([]byte(signature), []byte(parts[2])) == false {
+		return nil, errors.New("invalid jwt signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed jwt payload")
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed jwt payload")
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		if int64(exp) < time.Now().Unix() {
+			return nil, errors.New("jwt is expired")
+		}
+	}
+	return claims, nil
+}
+
+func hs256(data, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_SignJWT(t *testing.T) {
+	keys := map[string]string{"k1": "secret1", "k2": "secret2"}
+	claims := map[string]interface{}{"sub": "1001", "exp": float64(time.Now().Add(time.Hour).Unix())}
+	token, err := SignJWT(claims, "k2", "secret2")
+	if err != nil || IsJWT(token) == false {
+		t.Error("expect:", "jwt", "result:", token, err)
+	}
+	result, err := ParseJWT(token, keys)
+	if err != nil || reflect.DeepEqual(claims, result) == false {
+		t.Error("expect:", claims, "result:", result, err)
+	}
+	_, err = ParseJWT(token, map[string]string{"k2": "other"})
+	if err == nil || err.Error() != "invalid jwt signature" {
+		t.Error("expect:", "invalid jwt signature", "result:", err)
+	}
+	_, err = ParseJWT(token, map[string]string{"k1": "secret1"})
+	if err == nil || err.Error() != "unknown jwt kid: k2" {
+		t.Error("expect:", "unknown jwt kid: k2", "result:", err)
+	}
+	claims["exp"] = float64(time.Now().Add(-time.Hour).Unix())
+	token, _ = SignJWT(claims, "k1", "secret1")
+	_, err = ParseJWT(token, keys)
+	if err == nil || err.Error() != "jwt is expired" {
+		t.Error("expect:", "jwt is expired", "result:", err)
+	}
+	if IsJWT("r:0123456789ABCDEF0123456789ABCDEF") {
+		t.Error("expect:", false, "result:", true)
+	}
+}
@@ -184,3 +184,32 @@ func ValidatePolygonPoint(latitude, longitude interface{}) error {
 	}
 	return nil
 }
+
+// ParseCenterSphere 解析并校验 $geoWithin 中的 $centerSphere 参数
+// 格式为 [GeoPoint 或者 [longitude, latitude], 弧度]
+func ParseCenterSphere(centerSphere []interface{}) (longitude, latitude interface{}, distance float64, err error) {
+	if len(centerSphere) != 2 {
+		return nil, nil, 0, errs.E(errs.InvalidJSON, "bad $geoWithin value; $centerSphere should be an array of Parse.GeoPoint and distance")
+	}
+	if point := M(centerSphere[0]); point != nil && S(point["__type"]) == "GeoPoint" {
+		longitude, latitude = point["longitude"], point["latitude"]
+	} else if point := A(centerSphere[0]); len(point) == 2 {
+		longitude, latitude = point[0], point[1]
+	} else {
+		return nil, nil, 0, errs.E(errs.InvalidJSON, "bad $geoWithin value; $centerSphere geo point invalid")
+	}
+	if err := ValidatePolygonPoint(latitude, longitude); err != nil {
+		return nil, nil, 0, err
+	}
+	if v, ok := centerSphere[1].(float64); ok {
+		distance = v
+	} else if v, ok := centerSphere[1].(int); ok {
+		distance = float64(v)
+	} else {
+		return nil, nil, 0, errs.E(errs.InvalidJSON, "bad $geoWithin value; $centerSphere distance invalid")
+	}
+	if distance < 0 {
+		return nil, nil, 0, errs.E(errs.InvalidJSON, "bad $geoWithin value; $centerSphere distance invalid")
+	}
+	return longitude, latitude, distance, nil
+}
@@ -49,3 +49,28 @@ func TestDeepCopy(t *testing.T) {
 		t.Error(dst)
 	}
 }
+
+func TestParseCenterSphere(t *testing.T) {
+	longitude, latitude, distance, err := ParseCenterSphere([]interface{}{
+		map[string]interface{}{"__type": "GeoPoint", "longitude": 10.0, "latitude": 20.0}, 0.5,
+	})
+	if err != nil || longitude != 10.0 || latitude != 20.0 || distance != 0.5 {
+		t.Error("expect:", 10.0, 20.0, 0.5, nil, "result:", longitude, latitude, distance, err)
+	}
+	longitude, latitude, distance, err = ParseCenterSphere([]interface{}{[]interface{}{10.0, 20.0}, 1})
+	if err != nil || longitude != 10.0 || latitude != 20.0 || distance != 1.0 {
+		t.Error("expect:", 10.0, 20.0, 1.0, nil, "result:", longitude, latitude, distance, err)
+	}
+	invalid := [][]interface{}{
+		{[]interface{}{10.0, 20.0}},
+		{"point", 1.0},
+		{[]interface{}{10.0, 100.0}, 1.0},
+		{[]interface{}{10.0, 20.0}, "1"},
+		{[]interface{}{10.0, 20.0}, -1.0},
+	}
+	for _, centerSphere := range invalid {
+		if _, _, _, err := ParseCenterSphere(centerSphere); err == nil {
+			t.Error("expect:", "error", "result:", nil, centerSphere)
+		}
+	}
+}